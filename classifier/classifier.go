@@ -0,0 +1,256 @@
+// Package classifier suggests categories for blocks with a multinomial Naive
+// Bayes model trained on the blocks that are already categorized. It runs
+// entirely offline and can be saved to a local file.
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"txeo-gui-library/models"
+)
+
+// Default minimum confidence for SuggestFor
+const DefaultMinConfidence = 0.6
+
+// Upper limits (in whole currency units) of the amount buckets
+var amountBuckets = []int64{5, 20, 50, 100, 250, 500, 1000}
+
+// ClassStats holds the training counts of one category
+type ClassStats struct {
+	Documents int            `json:"documents"`
+	Features  map[string]int `json:"features"`
+	Total     int            `json:"total"`
+}
+
+// Model is a Naive Bayes classifier keyed by category short name. It is safe
+// for concurrent use.
+type Model struct {
+	Classes    map[string]*ClassStats `json:"classes"`
+	Vocabulary map[string]int         `json:"vocabulary"`
+	Documents  int                    `json:"documents"`
+
+	mu sync.RWMutex
+}
+
+// Suggestion is a category proposed for a block
+type Suggestion struct {
+	CategoryShortName string
+	Confidence        float64 // Posterior probability, 0..1
+}
+
+func NewModel() *Model {
+	return &Model{Classes: map[string]*ClassStats{}, Vocabulary: map[string]int{}}
+}
+
+// Train learns from every block with a real category (not empty and not the
+// unknown "?" category).
+func (m *Model) Train(blocks models.Blocks) {
+	for _, block := range blocks {
+		if isUncategorized(block) {
+			continue
+		}
+		m.Learn(block, block.Category.ShortName)
+	}
+}
+
+// Learn adds one example. Call it when the user confirms a suggestion or
+// picks a category, so the model improves without a full retrain.
+func (m *Model) Learn(block models.Block, categoryShortName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	class, ok := m.Classes[categoryShortName]
+	if !ok {
+		class = &ClassStats{Features: map[string]int{}}
+		m.Classes[categoryShortName] = class
+	}
+
+	for _, feature := range Features(block) {
+		class.Features[feature]++
+		class.Total++
+		m.Vocabulary[feature]++
+	}
+	class.Documents++
+	m.Documents++
+}
+
+// Suggest returns every known category ranked by confidence.
+func (m *Model) Suggest(block models.Block) []Suggestion {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.Documents == 0 {
+		return nil
+	}
+
+	features := Features(block)
+	vocabulary := float64(len(m.Vocabulary))
+	scores := map[string]float64{}
+	best := math.Inf(-1)
+	for name, class := range m.Classes {
+		// Log prior plus Laplace smoothed log likelihood of every known feature
+		score := math.Log(float64(class.Documents) / float64(m.Documents))
+		for _, feature := range features {
+			if _, known := m.Vocabulary[feature]; !known {
+				continue
+			}
+			score += math.Log((float64(class.Features[feature]) + 1) / (float64(class.Total) + vocabulary))
+		}
+		scores[name] = score
+		best = math.Max(best, score)
+	}
+
+	// Normalize the log scores into probabilities
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - best)
+	}
+	suggestions := make([]Suggestion, 0, len(scores))
+	for name, score := range scores {
+		suggestions = append(suggestions, Suggestion{CategoryShortName: name, Confidence: math.Exp(score-best) / sum})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		return suggestions[i].CategoryShortName < suggestions[j].CategoryShortName
+	})
+	return suggestions
+}
+
+// SuggestFor proposes a category for a block that has none (or has the
+// unknown category) when the best suggestion reaches minConfidence and the
+// category exists in categories.
+func (m *Model) SuggestFor(block models.Block, categories models.Categories, minConfidence float64) (models.Category, Suggestion, bool) {
+	if !isUncategorized(block) {
+		return models.Category{}, Suggestion{}, false
+	}
+
+	suggestions := m.Suggest(block)
+	if len(suggestions) == 0 || suggestions[0].Confidence < minConfidence {
+		return models.Category{}, Suggestion{}, false
+	}
+
+	for _, category := range categories {
+		if category.ShortName == suggestions[0].CategoryShortName && !category.Deleted {
+			return category, suggestions[0], true
+		}
+	}
+	return models.Category{}, Suggestion{}, false
+}
+
+// Features extracts the tokens the model works with: the words of the
+// normalized concept and Concept2, the sign and size of the amount and the
+// weekday.
+func Features(block models.Block) []string {
+	var features []string
+	for _, word := range strings.Fields(models.NormalizeConcept(block.Concept.Name)) {
+		if len(word) > 1 {
+			features = append(features, "w:"+word)
+		}
+	}
+	for _, word := range strings.Fields(models.NormalizeConcept(block.Concept2)) {
+		if len(word) > 1 {
+			features = append(features, "w2:"+word)
+		}
+	}
+
+	sign := "expense"
+	if block.Amount.IsNegative() {
+		sign = "income"
+	}
+	features = append(features, "sign:"+sign, "amount:"+sign+":"+amountBucket(block))
+
+	if !block.Date.IsZero() {
+		features = append(features, "weekday:"+block.Date.Weekday().String())
+	}
+	return features
+}
+
+func amountBucket(block models.Block) string {
+	units := int64(math.Abs(block.Amount.Float()))
+	for _, limit := range amountBuckets {
+		if units < limit {
+			return fmt.Sprintf("<%d", limit)
+		}
+	}
+	return fmt.Sprintf(">=%d", amountBuckets[len(amountBuckets)-1])
+}
+
+func isUncategorized(block models.Block) bool {
+	return block.Category.ShortName == "" || block.Category.Name == "?"
+}
+
+// Save writes the model as JSON.
+func (m *Model) Save(w io.Writer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return json.NewEncoder(w).Encode(m)
+}
+
+// Load reads a model written by Save.
+func Load(r io.Reader) (*Model, error) {
+	m := NewModel()
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+
+	// Maps written as null, e.g. by hand, would panic in Learn
+	if m.Classes == nil {
+		m.Classes = map[string]*ClassStats{}
+	}
+	if m.Vocabulary == nil {
+		m.Vocabulary = map[string]int{}
+	}
+	for name, class := range m.Classes {
+		if class == nil {
+			class = &ClassStats{}
+			m.Classes[name] = class
+		}
+		if class.Features == nil {
+			class.Features = map[string]int{}
+		}
+	}
+	return m, nil
+}
+
+// SaveFile writes the model to path, replacing it atomically.
+func (m *Model) SaveFile(path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := m.Save(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadFile reads a model from path, returning an empty model when the file
+// does not exist yet.
+func LoadFile(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return NewModel(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
@@ -0,0 +1,155 @@
+package classifier
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+func testBlock(concept string, date string, minor int64, category string) models.Block {
+	day, err := time.Parse(models.DateLayout, date)
+	if err != nil {
+		panic(err)
+	}
+	block := *models.NewBlockWithMoney(concept, day, "", money.New(minor, "EUR"), money.Money{})
+	block.Category = models.Category{Name: category, ShortName: category}
+	return block
+}
+
+func trainedModel() *Model {
+	m := NewModel()
+	m.Train(models.Blocks{
+		testBlock("MERCADONA VALENCIA", "2025-03-01", 4500, "food"),
+		testBlock("MERCADONA ALZIRA", "2025-03-08", 6200, "food"),
+		testBlock("CARREFOUR EXPRESS", "2025-03-10", 1800, "food"),
+		testBlock("BAR PEPE", "2025-03-07", 350, "bars"),
+		testBlock("BAR LA ESQUINA", "2025-03-14", 420, "bars"),
+		testBlock("NOMINA ACME SL", "2025-02-28", -180000, "salary"),
+		testBlock("NOMINA ACME SL", "2025-03-31", -180000, "salary"),
+		testBlock("SIN CATEGORIA", "2025-03-02", 100, ""), // Not learnt
+		testBlock("DESCONOCIDO", "2025-03-02", 100, "?"),
+	})
+	return m
+}
+
+func TestSuggest(t *testing.T) {
+	m := trainedModel()
+	if m.Documents != 7 || len(m.Classes) != 3 {
+		t.Fatalf("%d documents in %d classes, want 7 in 3", m.Documents, len(m.Classes))
+	}
+
+	tests := []struct {
+		block models.Block
+		want  string
+	}{
+		{testBlock("MERCADONA GANDIA", "2025-04-05", 5100, ""), "food"},
+		{testBlock("BAR CENTRAL", "2025-04-04", 300, ""), "bars"},
+		{testBlock("NOMINA", "2025-04-30", -180000, ""), "salary"},
+	}
+	for _, test := range tests {
+		suggestions := m.Suggest(test.block)
+		if len(suggestions) != 3 || suggestions[0].CategoryShortName != test.want {
+			t.Errorf("%s: %v, want %s first", test.block.Concept.Name, suggestions, test.want)
+			continue
+		}
+		sum := 0.0
+		for i, suggestion := range suggestions {
+			sum += suggestion.Confidence
+			if i > 0 && suggestion.Confidence > suggestions[i-1].Confidence {
+				t.Errorf("%s: suggestions not ranked %v", test.block.Concept.Name, suggestions)
+			}
+		}
+		if sum < 0.999 || sum > 1.001 {
+			t.Errorf("%s: confidences add up to %f", test.block.Concept.Name, sum)
+		}
+	}
+
+	if suggestions := NewModel().Suggest(tests[0].block); suggestions != nil {
+		t.Errorf("an empty model suggests %v", suggestions)
+	}
+}
+
+func TestSuggestFor(t *testing.T) {
+	m := trainedModel()
+	categories := models.Categories{{Name: "Comida", ShortName: "food"}, {Name: "Bares", ShortName: "bars", Deleted: true}}
+
+	category, suggestion, ok := m.SuggestFor(testBlock("MERCADONA GANDIA", "2025-04-05", 5100, ""), categories, DefaultMinConfidence)
+	if !ok || category.Name != "Comida" || suggestion.Confidence < DefaultMinConfidence {
+		t.Errorf("SuggestFor = %+v %+v %v, want Comida", category, suggestion, ok)
+	}
+	if _, _, ok := m.SuggestFor(testBlock("MERCADONA GANDIA", "2025-04-05", 5100, "fun"), categories, DefaultMinConfidence); ok {
+		t.Errorf("suggested for a categorized block")
+	}
+	if _, _, ok := m.SuggestFor(testBlock("BAR CENTRAL", "2025-04-04", 300, ""), categories, DefaultMinConfidence); ok {
+		t.Errorf("suggested a deleted category")
+	}
+	if _, _, ok := m.SuggestFor(testBlock("NOMINA", "2025-04-30", -180000, ""), categories, DefaultMinConfidence); ok {
+		t.Errorf("suggested a category missing from categories")
+	}
+	if _, _, ok := m.SuggestFor(testBlock("MERCADONA GANDIA", "2025-04-05", 5100, ""), categories, 1.01); ok {
+		t.Errorf("suggested below the minimum confidence")
+	}
+}
+
+func TestLearn(t *testing.T) {
+	m := trainedModel()
+	block := testBlock("FARMACIA LOPEZ", "2025-04-03", 1200, "")
+	for i := 0; i < 3; i++ {
+		m.Learn(block, "health")
+	}
+	if suggestions := m.Suggest(testBlock("FARMACIA LOPEZ", "2025-04-10", 900, "")); suggestions[0].CategoryShortName != "health" {
+		t.Errorf("after learning: %v", suggestions)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	block := testBlock("Café  Nº 1", "2025-03-03", 2000, "")
+	block.Concept2 = "tarjeta"
+	got := strings.Join(Features(block), " ")
+	want := "w:CAFE w2:TARJETA sign:expense amount:expense:<50 weekday:Monday"
+	if got != want {
+		t.Errorf("features %q, want %q", got, want)
+	}
+	if bucket := amountBucket(testBlock("X", "2025-03-03", -500000, "")); bucket != ">=1000" {
+		t.Errorf("bucket of 5000.00 %q", bucket)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	m := trainedModel()
+	var buf bytes.Buffer
+	if err := m.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := testBlock("MERCADONA GANDIA", "2025-04-05", 5100, "")
+	if got, want := loaded.Suggest(block), m.Suggest(block); got[0] != want[0] {
+		t.Errorf("loaded model suggests %v, want %v", got[0], want[0])
+	}
+
+	// Null maps, e.g. written by hand, can still learn
+	loaded, err = Load(strings.NewReader(`{"classes":{"food":null,"bars":{"documents":1}},"vocabulary":null,"documents":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.Learn(block, "food")
+	loaded.Learn(block, "bars")
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	if missing, err := LoadFile(path); err != nil || missing.Documents != 0 {
+		t.Errorf("LoadFile of a missing file = %+v, %v, want an empty model", missing, err)
+	}
+	if err := m.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if fromFile, err := LoadFile(path); err != nil || fromFile.Documents != m.Documents {
+		t.Errorf("LoadFile = %v, %v", fromFile, err)
+	}
+}
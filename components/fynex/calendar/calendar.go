@@ -39,7 +39,7 @@ func MakeCustomCalendar(selectedDate time.Time, blocks models.Blocks) *fyne.Cont
 	weekdayRow := container.NewGridWithColumns(7, weekdayLabels...)
 
 	// Generate calendar cells
-	cells := createCustomCalendarCells(startingDate, blocks)
+	cells := createCustomCalendarCells(startingDate, blocks, nil)
 
 	// Main calendar grid
 	calendarGrid := container.NewGridWithColumns(7, cells...)
@@ -119,7 +119,7 @@ func MakeCustomCalendarWithCallbackAndSelection(selectedDate time.Time, blocks m
 	weekdayRow := container.NewGridWithColumns(7, weekdayLabels...)
 
 	// Generate calendar cells with callback and selection
-	cells := createCustomCalendarCellsWithCallbackAndSelection(startingDate, blocks, selectedDay, onDaySelected)
+	cells := createCustomCalendarCellsWithCallbackAndSelection(startingDate, blocks, selectedDay, nil, onDaySelected)
 
 	// Main calendar grid
 	calendarGrid := container.NewGridWithColumns(7, cells...)
@@ -134,8 +134,9 @@ func MakeCustomCalendarWithCallbackAndSelection(selectedDate time.Time, blocks m
 	return calendarContainer
 }
 
-// createCustomCalendarCells generates the cells for the custom calendar
-func createCustomCalendarCells(startingDate time.Time, blocks models.Blocks) []fyne.CanvasObject {
+// createCustomCalendarCells generates the cells for the custom calendar.
+// With a forecast, future days show their projected balance.
+func createCustomCalendarCells(startingDate time.Time, blocks models.Blocks, forecast *models.Forecast) []fyne.CanvasObject {
 	today := time.Now() // Current day
 	firstDayOfMonth := time.Date(startingDate.Year(), startingDate.Month(), 1, 0, 0, 0, 0, startingDate.Location())
 	firstWeekday := int(firstDayOfMonth.Weekday()) // Weekday of the first day (Sunday=0)
@@ -163,7 +164,8 @@ func createCustomCalendarCells(startingDate time.Time, blocks models.Blocks) []f
 		amountStyle := styles.GetStyleForAmount(0)
 
 		// If not in the future, make it gray
-		if day <= today.Day()+1 {
+		cellDate := time.Date(startingDate.Year(), startingDate.Month(), day, 0, 0, 0, 0, startingDate.Location())
+		if !cellDate.After(today) {
 			// Tomar el amount del bloque
 			amountForDay := blocks.GetTotalAmountForDay(startingDate, day)
 			amountStyle = styles.GetStyleForAmount(amountForDay)
@@ -179,13 +181,21 @@ func createCustomCalendarCells(startingDate time.Time, blocks models.Blocks) []f
 			text.TextStyle.Bold = true
 		}
 
+		// Future days with a projection show the expected balance below the day
+		var content fyne.CanvasObject = text
+		if forecast != nil && cellDate.After(today) {
+			if projected, ok := forecast.Day(cellDate); ok {
+				bgColor, content = forecastCellContent(text, projected)
+			}
+		}
+
 		// Crear el fondo como un rectángulo
 		bg := canvas.NewRectangle(bgColor)
 
 		// Crear un contenedor para el fondo y el texto
 		cell := container.NewStack(
-			bg,   // Fondo
-			text, // Texto encima
+			bg,      // Fondo
+			content, // Texto encima
 		)
 
 		// // Añadir interacción con clics
@@ -225,7 +235,8 @@ func createCustomCalendarCellsWithCallback(startingDate time.Time, blocks models
 		amountStyle := styles.GetStyleForAmount(0)
 
 		// If not in the future, make it gray
-		if day <= today.Day()+1 {
+		cellDate := time.Date(startingDate.Year(), startingDate.Month(), day, 0, 0, 0, 0, startingDate.Location())
+		if !cellDate.After(today) {
 			// Get the amount for the day
 			amountForDay := blocks.GetTotalAmountForDay(startingDate, day)
 			amountStyle = styles.GetStyleForAmount(amountForDay)
@@ -276,7 +287,7 @@ func createCustomCalendarCellsWithCallback(startingDate time.Time, blocks models
 }
 
 // createCustomCalendarCellsWithCallbackAndSelection generates cells with click handling and selection highlighting
-func createCustomCalendarCellsWithCallbackAndSelection(startingDate time.Time, blocks models.Blocks, selectedDay int, onDaySelected func(int)) []fyne.CanvasObject {
+func createCustomCalendarCellsWithCallbackAndSelection(startingDate time.Time, blocks models.Blocks, selectedDay int, markers []Marker, onDaySelected func(int)) []fyne.CanvasObject {
 	today := time.Now() // Current day
 	firstDayOfMonth := time.Date(startingDate.Year(), startingDate.Month(), 1, 0, 0, 0, 0, startingDate.Location())
 	firstWeekday := int(firstDayOfMonth.Weekday()) // Weekday of the first day (Sunday=0)
@@ -300,7 +311,8 @@ func createCustomCalendarCellsWithCallbackAndSelection(startingDate time.Time, b
 		amountStyle := styles.GetStyleForAmount(0)
 
 		// If not in the future, make it gray
-		if day <= today.Day()+1 {
+		cellDate := time.Date(startingDate.Year(), startingDate.Month(), day, 0, 0, 0, 0, startingDate.Location())
+		if !cellDate.After(today) {
 			// Get the amount for the day
 			amountForDay := blocks.GetTotalAmountForDay(startingDate, day)
 			amountStyle = styles.GetStyleForAmount(amountForDay)
@@ -364,6 +376,11 @@ func createCustomCalendarCellsWithCallbackAndSelection(startingDate time.Time, b
 			container.NewCenter(clickableCell),
 		)
 
+		// Markers go in the top right corner, over the button
+		if dayMarkers := markersForDay(markers, cellDate); len(dayMarkers) > 0 {
+			styledButton.Add(makeMarkerRow(dayMarkers))
+		}
+
 		// Add the interactive cell to the list
 		cells = append(cells, styledButton)
 	}
@@ -373,8 +390,8 @@ func createCustomCalendarCellsWithCallbackAndSelection(startingDate time.Time, b
 
 // daysIn calculates the number of days in a month
 func daysIn(t time.Time) int {
-	nextMonth := t.AddDate(0, 1, 0)
-	return int(nextMonth.Sub(t).Hours() / 24)
+	// Day 0 of the next month is the last day of this one
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
 }
 
 type date struct {
@@ -0,0 +1,78 @@
+package calendar
+
+import (
+	"image/color"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/styles"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Projected days are painted lighter than the real ones
+const forecastAlpha = 110
+
+// MakeCustomCalendarWithForecast is MakeCustomCalendar showing the
+// projected balance of the future days
+func MakeCustomCalendarWithForecast(selectedDate time.Time, blocks models.Blocks, forecast models.Forecast) *fyne.Container {
+
+	monthLabel := widget.NewLabelWithStyle(
+		selectedDate.Format("January 2006"),
+		fyne.TextAlignCenter, fyne.TextStyle{Bold: true},
+	)
+
+	// Days of the week (Monday to Sunday)
+	weekdays := []string{"L", "M", "M", "J", "V", "S", "D"}
+	weekdayLabels := make([]fyne.CanvasObject, len(weekdays))
+	for i, day := range weekdays {
+		label := canvas.NewText(day, color.Black)
+		label.Alignment = fyne.TextAlignCenter
+		weekdayLabels[i] = label
+	}
+
+	cells := createCustomCalendarCells(selectedDate, blocks, &forecast)
+
+	return container.NewVBox(
+		monthLabel,
+		container.NewGridWithColumns(7, weekdayLabels...),
+		container.NewGridWithColumns(7, cells...),
+	)
+}
+
+// ForecastMarkers marks the projected days whose balance may go negative:
+// orange when only the low end of the band does, red when the expected
+// balance does
+func ForecastMarkers(forecast models.Forecast) []Marker {
+	var markers []Marker
+	for _, day := range forecast.Days {
+		switch {
+		case day.Balance.IsNegative():
+			markers = append(markers, Marker{Date: day.Date, Text: "!", Color: expenseMarkerColor, Tip: "balance " + day.Balance.Format(models.Locale)})
+		case day.Low.IsNegative():
+			markers = append(markers, Marker{Date: day.Date, Text: "!", Color: missedMarkerColor, Tip: "balance may reach " + day.Low.Format(models.Locale)})
+		}
+	}
+	return markers
+}
+
+// forecastCellContent returns the background and the day number with the
+// projected balance under it
+func forecastCellContent(dayText *canvas.Text, projected models.ForecastDay) (color.Color, fyne.CanvasObject) {
+	style := styles.GetStyleForBalanceMoney(projected.Balance)
+	bgColor := color.NRGBAModel.Convert(style.BGColor).(color.NRGBA)
+	bgColor.A = forecastAlpha
+
+	balance := canvas.NewText(projected.Balance.Format(models.Locale), color.Black)
+	balance.Alignment = fyne.TextAlignCenter
+	balance.TextSize = theme.CaptionTextSize()
+	if projected.Low.IsNegative() {
+		balance.Color = missedMarkerColor
+	}
+
+	return &bgColor, container.NewVBox(layout.NewSpacer(), dayText, balance, layout.NewSpacer())
+}
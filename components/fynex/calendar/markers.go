@@ -0,0 +1,121 @@
+package calendar
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+	"txeo-gui-library/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Marker is a small sign drawn in the corner of a day cell, e.g. for an
+// expected recurring payment
+type Marker struct {
+	Date  time.Time
+	Text  string
+	Color color.Color
+	Tip   string // What the marker means, e.g. "NETFLIX 12,99 €"
+}
+
+var (
+	expenseMarkerColor = color.NRGBA{R: 183, G: 28, B: 28, A: 255}
+	incomeMarkerColor  = color.NRGBA{R: 46, G: 125, B: 50, A: 255}
+	missedMarkerColor  = color.NRGBA{R: 230, G: 120, B: 0, A: 255}
+)
+
+// MakeCustomCalendarWithMarkers is MakeCustomCalendarWithCallbackAndSelection
+// with markers on their days
+func MakeCustomCalendarWithMarkers(selectedDate time.Time, blocks models.Blocks, selectedDay int, markers []Marker, onDaySelected func(int)) *fyne.Container {
+
+	monthLabel := widget.NewLabelWithStyle(
+		selectedDate.Format("January 2006"),
+		fyne.TextAlignCenter, fyne.TextStyle{Bold: true},
+	)
+
+	// Days of the week (Monday to Sunday)
+	weekdays := []string{"L", "M", "M", "J", "V", "S", "D"}
+	weekdayLabels := make([]fyne.CanvasObject, len(weekdays))
+	for i, day := range weekdays {
+		label := canvas.NewText(day, color.Black)
+		label.Alignment = fyne.TextAlignCenter
+		weekdayLabels[i] = label
+	}
+
+	cells := createCustomCalendarCellsWithCallbackAndSelection(selectedDate, blocks, selectedDay, markers, onDaySelected)
+
+	return container.NewVBox(
+		monthLabel,
+		container.NewGridWithColumns(7, weekdayLabels...),
+		container.NewGridWithColumns(7, cells...),
+	)
+}
+
+// RecurringMarkers marks the expected dates of the series in the month of
+// monthDate, in red for payments and green for income, and the missed
+// occurrences in orange.
+func RecurringMarkers(series models.RecurringSeriesList, monthDate time.Time) []Marker {
+	from := time.Date(monthDate.Year(), monthDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	var markers []Marker
+	for _, s := range series {
+		tip := fmt.Sprintf("%s %s", s.Merchant, s.AverageAmount.Abs().Format(models.Locale))
+		markerColor := expenseMarkerColor
+		if s.LastAmount.IsNegative() {
+			markerColor = incomeMarkerColor
+		}
+
+		for _, expected := range s.ExpectedBetween(from, to) {
+			markers = append(markers, Marker{Date: expected, Text: "•", Color: markerColor, Tip: tip})
+		}
+		for _, missed := range s.Missed {
+			if !missed.Before(from) && missed.Before(to) {
+				markers = append(markers, Marker{Date: missed, Text: "!", Color: missedMarkerColor, Tip: tip + " (missed)"})
+			}
+		}
+	}
+	return markers
+}
+
+func markersForDay(markers []Marker, day time.Time) []Marker {
+	var found []Marker
+	for _, marker := range markers {
+		if marker.Date.Year() == day.Year() && marker.Date.YearDay() == day.YearDay() {
+			found = append(found, marker)
+		}
+	}
+	return found
+}
+
+func makeMarkerRow(markers []Marker) fyne.CanvasObject {
+	row := container.NewHBox(layout.NewSpacer())
+	for _, marker := range markers {
+		text := canvas.NewText(marker.Text, marker.Color)
+		text.TextStyle.Bold = true
+		row.Add(text)
+	}
+	return container.NewVBox(row, layout.NewSpacer())
+}
+
+// BudgetMarkers marks the days of the month of monthDate with spending in a
+// category whose budget was in warning or over that day. Budgets count the
+// spending of the descendants of their category in categories.
+func BudgetMarkers(budgets models.Budgets, blocks models.Blocks, categories models.Categories, monthDate time.Time) []Marker {
+	from := time.Date(monthDate.Year(), monthDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	var markers []Marker
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		status := budgets.StatusForDay(blocks, categories, day)
+		if status == models.BudgetOK {
+			continue
+		}
+		markers = append(markers, Marker{Date: day, Text: "●", Color: status.Color(), Tip: "budget " + string(status)})
+	}
+	return markers
+}
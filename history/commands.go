@@ -0,0 +1,261 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"txeo-gui-library/models"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// edit is a command that snapshots the categories and the categorization of
+// the blocks it touches before applying a change, and restores them on undo
+type edit struct {
+	description string
+	categories  *models.Categories
+	blocks      *models.Blocks        // A pointer, so appends by the caller are seen
+	rows        func() ([]int, error) // Blocks the change touches, found before every Do
+	apply       func(rows []int) error
+
+	savedCategories models.Categories
+	savedRows       []int
+	savedBlocks     []models.Block
+}
+
+func (e *edit) Description() string {
+	return e.description
+}
+
+func (e *edit) Do() error {
+	var rows []int
+	if e.rows != nil {
+		var err error
+		if rows, err = e.rows(); err != nil {
+			return err
+		}
+	}
+
+	e.savedCategories = copyCategories(*e.categories)
+	e.savedRows = rows
+	e.savedBlocks = make([]models.Block, len(rows))
+	for i, row := range rows {
+		e.savedBlocks[i] = (*e.blocks)[row]
+		e.savedBlocks[i].Splits = append(models.Splits(nil), (*e.blocks)[row].Splits...)
+	}
+
+	if err := e.apply(rows); err != nil {
+		e.Undo()
+		return err
+	}
+	return nil
+}
+
+func (e *edit) Undo() error {
+	if e.savedCategories == nil {
+		return nil
+	}
+	*e.categories = e.savedCategories
+	for i, row := range e.savedRows {
+		(*e.blocks)[row] = e.savedBlocks[i]
+	}
+	e.savedCategories, e.savedRows, e.savedBlocks = nil, nil, nil
+	return nil
+}
+
+// copyCategories copies the categories and their concepts, so the edits
+// can append to the concepts without touching the snapshot
+func copyCategories(categories models.Categories) models.Categories {
+	copied := make(models.Categories, len(categories))
+	for i, category := range categories {
+		copied[i] = category
+		copied[i].Concepts = append(models.Concepts(nil), category.Concepts...)
+		copied[i].Tags = append(models.Tags(nil), category.Tags...)
+	}
+	return copied
+}
+
+// AssignCategory gives the block at row a category, removing its splits,
+// and stores its concept in that category so later imports get it too (like
+// Categories.AssignCategoryToSelectedConcept). A concept stored in another
+// category is moved.
+func AssignCategory(categories *models.Categories, blocks *models.Blocks, row int, category models.Category) Command {
+	return &edit{
+		description: fmt.Sprintf("assign category %s", category.Name),
+		categories:  categories,
+		blocks:      blocks,
+		rows: func() ([]int, error) {
+			if row < 0 || row >= len(*blocks) {
+				return nil, fmt.Errorf("%w: row %d", ErrNotFound, row)
+			}
+			return []int{row}, nil
+		},
+		apply: func(rows []int) error {
+			i, err := findCategory(*categories, category.ShortName)
+			if err != nil {
+				return err
+			}
+			block := &(*blocks)[row]
+
+			// Built from the name so fuzzy matching gets its canonical
+			// name and merchant
+			concept := models.NewConceptFromString(block.Concept.Name)
+			concept.Icon = (*categories)[i].Icon
+			concept.ShortName = block.Concept.Name
+			concept.Tags = block.Concept.Tags
+			concept.CategoryShortName = category.ShortName
+			removeConcept(*categories, concept.Name)
+			(*categories)[i].Concepts = append((*categories)[i].Concepts, concept)
+
+			block.Category = (*categories)[i]
+			block.Category.Concepts = nil
+			block.Concept.CategoryShortName = category.ShortName
+			block.ClearSplits()
+			return nil
+		},
+	}
+}
+
+// EditConcept replaces the stored concept called name. When the new
+// concept has another CategoryShortName it moves to that category.
+func EditConcept(categories *models.Categories, name string, concept models.Concept) Command {
+	return &edit{
+		description: fmt.Sprintf("edit concept %s", name),
+		categories:  categories,
+		apply: func([]int) error {
+			if !removeConcept(*categories, name) {
+				return fmt.Errorf("%w: concept %q", ErrNotFound, name)
+			}
+			i, err := findCategory(*categories, concept.CategoryShortName)
+			if err != nil {
+				return err
+			}
+			(*categories)[i].Concepts = append((*categories)[i].Concepts, concept)
+			return nil
+		},
+	}
+}
+
+// DeleteConcept removes a stored concept so it is no longer auto-categorized
+func DeleteConcept(categories *models.Categories, name string) Command {
+	return &edit{
+		description: fmt.Sprintf("delete concept %s", name),
+		categories:  categories,
+		apply: func([]int) error {
+			if !removeConcept(*categories, name) {
+				return fmt.Errorf("%w: concept %q", ErrNotFound, name)
+			}
+			return nil
+		},
+	}
+}
+
+// DeleteCategory marks a category as deleted, like the repository does;
+// its concepts and the blocks that use it are kept
+func DeleteCategory(categories *models.Categories, shortName string) Command {
+	return &edit{
+		description: fmt.Sprintf("delete category %s", shortName),
+		categories:  categories,
+		apply: func([]int) error {
+			i, err := findCategory(*categories, shortName)
+			if err != nil {
+				return err
+			}
+			(*categories)[i].Deleted = true
+			return nil
+		},
+	}
+}
+
+// MergeCategories moves the concepts, children and blocks (split lines
+// included) of the category from into the category into, and deletes from
+func MergeCategories(categories *models.Categories, blocks *models.Blocks, from string, into string) Command {
+	return &edit{
+		description: fmt.Sprintf("merge category %s into %s", from, into),
+		categories:  categories,
+		blocks:      blocks,
+		rows: func() ([]int, error) {
+			var rows []int
+			for i := range *blocks {
+				for _, part := range (*blocks)[i].Parts() {
+					if part.Category.ShortName == from {
+						rows = append(rows, i)
+						break
+					}
+				}
+			}
+			return rows, nil
+		},
+		apply: func(rows []int) error {
+			if from == into {
+				return fmt.Errorf("cannot merge category %q into itself", from)
+			}
+			source, err := findCategory(*categories, from)
+			if err != nil {
+				return err
+			}
+			target, err := findCategory(*categories, into)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(categories.Ancestors(into), from) {
+				return fmt.Errorf("%w: %q is below %q", models.ErrCategoryCycle, into, from)
+			}
+
+			for _, concept := range (*categories)[source].Concepts {
+				concept.CategoryShortName = into
+				(*categories)[target].Concepts = append((*categories)[target].Concepts, concept)
+			}
+			(*categories)[source].Concepts = nil
+			(*categories)[source].Deleted = true
+			for i := range *categories {
+				if (*categories)[i].ParentShortName == from {
+					(*categories)[i].ParentShortName = into
+				}
+			}
+
+			category := (*categories)[target]
+			category.Concepts = nil
+			for _, row := range rows {
+				block := &(*blocks)[row]
+				if block.Category.ShortName == from {
+					block.Category = category
+					block.Concept.CategoryShortName = into
+				}
+				for i := range block.Splits {
+					if block.Splits[i].Category.ShortName == from {
+						block.Splits[i].Category = category
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func findCategory(categories models.Categories, shortName string) (int, error) {
+	for i := range categories {
+		if categories[i].ShortName == shortName && !categories[i].Deleted {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: category %q", ErrNotFound, shortName)
+}
+
+// removeConcept removes the concept called name from whichever category
+// has it, reporting whether one did
+func removeConcept(categories models.Categories, name string) bool {
+	removed := false
+	for i := range categories {
+		concepts := categories[i].Concepts[:0]
+		for _, concept := range categories[i].Concepts {
+			if concept.Name == name {
+				removed = true
+				continue
+			}
+			concepts = append(concepts, concept)
+		}
+		categories[i].Concepts = concepts
+	}
+	return removed
+}
@@ -0,0 +1,129 @@
+package history
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+func testCategories() models.Categories {
+	return models.Categories{
+		{Name: "Comida", ShortName: "food", Concepts: models.Concepts{{Name: "MERCADONA", CategoryShortName: "food"}}},
+		{Name: "Ocio", ShortName: "fun"},
+		{Name: "Bares", ShortName: "bars", ParentShortName: "fun"},
+	}
+}
+
+func testBlock(concept string, minor int64, category string) models.Block {
+	block := *models.NewBlockWithMoney(concept, time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), "", money.New(minor, "EUR"), money.Money{})
+	block.Category = models.Category{ShortName: category}
+	return block
+}
+
+func conceptCategory(categories models.Categories, name string) string {
+	for _, category := range categories {
+		for _, concept := range category.Concepts {
+			if concept.Name == name {
+				return category.ShortName
+			}
+		}
+	}
+	return ""
+}
+
+func TestAssignCategory(t *testing.T) {
+	categories := testCategories()
+	blocks := models.Blocks{testBlock("MERCADONA", 9000, "food")}
+	if err := blocks[0].AddSplit(models.Category{ShortName: "fun"}, money.New(1000, "EUR"), ""); err != nil {
+		t.Fatal(err)
+	}
+	h := New(0)
+
+	if err := h.Execute(AssignCategory(&categories, &blocks, 0, models.Category{Name: "Ocio", ShortName: "fun"})); err != nil {
+		t.Fatal(err)
+	}
+	if blocks[0].Category.ShortName != "fun" || blocks[0].IsSplit() || conceptCategory(categories, "MERCADONA") != "fun" {
+		t.Errorf("assigned block %q split %v, concept in %q", blocks[0].Category.ShortName, blocks[0].IsSplit(), conceptCategory(categories, "MERCADONA"))
+	}
+
+	// Blocks appended after the edit, e.g. by an import, do not break undo
+	blocks = append(blocks, testBlock("BAR", 300, ""))
+	if err := h.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if blocks[0].Category.ShortName != "food" || len(blocks[0].Splits) != 2 || conceptCategory(categories, "MERCADONA") != "food" || len(blocks) != 2 {
+		t.Errorf("undone block %q splits %d, concept in %q", blocks[0].Category.ShortName, len(blocks[0].Splits), conceptCategory(categories, "MERCADONA"))
+	}
+	if err := h.Redo(); err != nil || blocks[0].Category.ShortName != "fun" {
+		t.Errorf("redo: %v, category %q", err, blocks[0].Category.ShortName)
+	}
+
+	if err := h.Execute(AssignCategory(&categories, &blocks, 5, models.Category{ShortName: "fun"})); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing row: %v", err)
+	}
+	if err := h.Execute(AssignCategory(&categories, &blocks, 1, models.Category{ShortName: "nope"})); !errors.Is(err, ErrNotFound) || blocks[1].Category.ShortName != "" {
+		t.Errorf("missing category: %v, block %q", err, blocks[1].Category.ShortName)
+	}
+}
+
+func TestConceptCommands(t *testing.T) {
+	categories := testCategories()
+	h := New(0)
+
+	if err := h.Execute(EditConcept(&categories, "MERCADONA", models.Concept{Name: "MERCADONA SA", CategoryShortName: "fun"})); err != nil {
+		t.Fatal(err)
+	}
+	if conceptCategory(categories, "MERCADONA") != "" || conceptCategory(categories, "MERCADONA SA") != "fun" {
+		t.Errorf("edited concept not moved")
+	}
+	if err := h.Execute(DeleteConcept(&categories, "MERCADONA SA")); err != nil {
+		t.Fatal(err)
+	}
+	if conceptCategory(categories, "MERCADONA SA") != "" {
+		t.Errorf("deleted concept still stored")
+	}
+
+	h.Undo()
+	h.Undo()
+	if conceptCategory(categories, "MERCADONA") != "food" || conceptCategory(categories, "MERCADONA SA") != "" {
+		t.Errorf("undo did not restore the concept")
+	}
+	if err := h.Execute(DeleteConcept(&categories, "NOPE")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a missing concept: %v", err)
+	}
+}
+
+func TestCategoryCommands(t *testing.T) {
+	categories := testCategories()
+	market := testBlock("MERCADONA", 9000, "food")
+	if err := market.AddSplit(models.Category{ShortName: "bars"}, money.New(1000, "EUR"), ""); err != nil {
+		t.Fatal(err)
+	}
+	blocks := models.Blocks{testBlock("BAR", 300, "bars"), market, testBlock("CINE", 800, "fun")}
+	h := New(0)
+
+	if err := h.Execute(MergeCategories(&categories, &blocks, "fun", "bars")); !errors.Is(err, models.ErrCategoryCycle) {
+		t.Errorf("merging a parent into its child: %v", err)
+	}
+	if err := h.Execute(MergeCategories(&categories, &blocks, "bars", "food")); err != nil {
+		t.Fatal(err)
+	}
+	if blocks[0].Category.ShortName != "food" || blocks[1].Splits[1].Category.ShortName != "food" || !categories[2].Deleted || blocks[2].Category.ShortName != "fun" {
+		t.Errorf("after merging bars into food: %q, split %q, bars deleted %v", blocks[0].Category.ShortName, blocks[1].Splits[1].Category.ShortName, categories[2].Deleted)
+	}
+
+	if err := h.Execute(DeleteCategory(&categories, "fun")); err != nil || !categories[1].Deleted {
+		t.Errorf("delete: %v", err)
+	}
+	if err := h.Execute(DeleteCategory(&categories, "fun")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a deleted category: %v", err)
+	}
+
+	h.Undo()
+	h.Undo()
+	if categories[1].Deleted || categories[2].Deleted || blocks[0].Category.ShortName != "bars" || blocks[1].Splits[1].Category.ShortName != "bars" {
+		t.Errorf("undo left fun deleted %v, bars deleted %v, blocks %q %q", categories[1].Deleted, categories[2].Deleted, blocks[0].Category.ShortName, blocks[1].Splits[1].Category.ShortName)
+	}
+}
@@ -0,0 +1,240 @@
+// Package history keeps an undo/redo stack of categorization edits: assigning
+// categories, editing and deleting concepts, and merging and deleting
+// categories. Edits work on the in-memory models; saving them is up to the
+// OnChange callback. A History is meant to be used from the GUI goroutine.
+package history
+
+import (
+	"errors"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	log "github.com/sirupsen/logrus"
+)
+
+// Steps kept by New when the limit is not positive
+const DefaultLimit = 100
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	ErrGroupOpen     = errors.New("a group of edits is still open")
+	ErrNoGroup       = errors.New("no group of edits is open")
+)
+
+// Ctrl+Z and Ctrl+Shift+Z (Cmd on macOS)
+var (
+	ShortcutUndo = &desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault}
+	ShortcutRedo = &desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+)
+
+// Command is an edit that can be reverted. Do is called again to redo it.
+type Command interface {
+	Do() error
+	Undo() error
+	Description() string // For menu items such as "Undo assign category Food"
+}
+
+// History is a bounded undo/redo stack of commands
+type History struct {
+	// OnChange is called when a step is added, undone or redone, e.g. to
+	// save, refresh the table and enable the menu items
+	OnChange func()
+
+	limit int
+	undo  []Command
+	redo  []Command
+	open  []*Group // Groups started with Begin, innermost last
+}
+
+// New returns a history that keeps the last limit steps
+func New(limit int) *History {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &History{limit: limit}
+}
+
+// Execute does the command and makes it the next step to undo, dropping
+// the steps that could be redone. Inside Begin and End it is added to the
+// open group instead.
+func (h *History) Execute(command Command) error {
+	if err := command.Do(); err != nil {
+		return err
+	}
+	if len(h.open) > 0 {
+		group := h.open[len(h.open)-1]
+		group.Commands = append(group.Commands, command)
+		return nil
+	}
+	h.push(command)
+	return nil
+}
+
+// Begin opens a group: the commands executed until the matching End are
+// undone and redone as a single step, e.g. assigning a category to every
+// selected row. Groups can be nested.
+func (h *History) Begin(description string) {
+	h.open = append(h.open, &Group{Name: description})
+}
+
+// End closes the innermost group. Empty groups leave no step.
+func (h *History) End() error {
+	if len(h.open) == 0 {
+		return ErrNoGroup
+	}
+	group := h.open[len(h.open)-1]
+	h.open = h.open[:len(h.open)-1]
+	if len(group.Commands) == 0 {
+		return nil
+	}
+	if len(h.open) > 0 {
+		parent := h.open[len(h.open)-1]
+		parent.Commands = append(parent.Commands, group)
+		return nil
+	}
+	h.push(group)
+	return nil
+}
+
+// Cancel closes the innermost group undoing what it did
+func (h *History) Cancel() error {
+	if len(h.open) == 0 {
+		return ErrNoGroup
+	}
+	group := h.open[len(h.open)-1]
+	h.open = h.open[:len(h.open)-1]
+	err := group.Undo()
+	h.changed()
+	return err
+}
+
+func (h *History) push(command Command) {
+	h.undo = append(h.undo, command)
+	if len(h.undo) > h.limit {
+		h.undo = h.undo[len(h.undo)-h.limit:]
+	}
+	h.redo = nil
+	h.changed()
+}
+
+// Undo reverts the last step. A step that fails to undo stays in place.
+func (h *History) Undo() error {
+	if len(h.open) > 0 {
+		return ErrGroupOpen
+	}
+	if len(h.undo) == 0 {
+		return ErrNothingToUndo
+	}
+	command := h.undo[len(h.undo)-1]
+	if err := command.Undo(); err != nil {
+		return err
+	}
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, command)
+	h.changed()
+	return nil
+}
+
+// Redo does again the last undone step
+func (h *History) Redo() error {
+	if len(h.open) > 0 {
+		return ErrGroupOpen
+	}
+	if len(h.redo) == 0 {
+		return ErrNothingToRedo
+	}
+	command := h.redo[len(h.redo)-1]
+	if err := command.Do(); err != nil {
+		return err
+	}
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, command)
+	h.changed()
+	return nil
+}
+
+func (h *History) CanUndo() bool { return len(h.open) == 0 && len(h.undo) > 0 }
+func (h *History) CanRedo() bool { return len(h.open) == 0 && len(h.redo) > 0 }
+
+// UndoDescription describes the step Undo would revert, or is empty
+func (h *History) UndoDescription() string {
+	if len(h.undo) == 0 {
+		return ""
+	}
+	return h.undo[len(h.undo)-1].Description()
+}
+
+// RedoDescription describes the step Redo would do, or is empty
+func (h *History) RedoDescription() string {
+	if len(h.redo) == 0 {
+		return ""
+	}
+	return h.redo[len(h.redo)-1].Description()
+}
+
+// Clear forgets every step, e.g. after loading another file
+func (h *History) Clear() {
+	h.undo, h.redo, h.open = nil, nil, nil
+	h.changed()
+}
+
+func (h *History) changed() {
+	if h.OnChange != nil {
+		h.OnChange()
+	}
+}
+
+// AddShortcuts binds ShortcutUndo and ShortcutRedo on a canvas. Failures
+// are logged.
+func (h *History) AddShortcuts(canvas fyne.Canvas) {
+	canvas.AddShortcut(ShortcutUndo, func(fyne.Shortcut) {
+		if err := h.Undo(); err != nil && !errors.Is(err, ErrNothingToUndo) {
+			log.Errorf("Undo %q: %v", h.UndoDescription(), err)
+		}
+	})
+	canvas.AddShortcut(ShortcutRedo, func(fyne.Shortcut) {
+		if err := h.Redo(); err != nil && !errors.Is(err, ErrNothingToRedo) {
+			log.Errorf("Redo %q: %v", h.RedoDescription(), err)
+		}
+	})
+}
+
+// Group is several commands done and undone as one step
+type Group struct {
+	Name     string
+	Commands []Command
+}
+
+// NewGroup returns the commands as one step, for bulk edits built up front
+func NewGroup(description string, commands ...Command) *Group {
+	return &Group{Name: description, Commands: commands}
+}
+
+// Do runs the commands in order. When one fails the ones already done are
+// undone.
+func (g *Group) Do() error {
+	for i, command := range g.Commands {
+		if err := command.Do(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				g.Commands[j].Undo()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Undo reverts the commands in reverse order
+func (g *Group) Undo() error {
+	for i := len(g.Commands) - 1; i >= 0; i-- {
+		if err := g.Commands[i].Undo(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Group) Description() string {
+	return g.Name
+}
@@ -0,0 +1,150 @@
+package history
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// appendCommand appends its text to a log on Do and removes it on Undo
+type appendCommand struct {
+	log  *[]string
+	text string
+	fail error
+}
+
+func (c *appendCommand) Do() error {
+	if c.fail != nil {
+		return c.fail
+	}
+	*c.log = append(*c.log, c.text)
+	return nil
+}
+
+func (c *appendCommand) Undo() error {
+	*c.log = (*c.log)[:len(*c.log)-1]
+	return nil
+}
+
+func (c *appendCommand) Description() string {
+	return "append " + c.text
+}
+
+func TestHistoryUndoRedo(t *testing.T) {
+	var log []string
+	changes := 0
+	h := New(0)
+	h.OnChange = func() { changes++ }
+
+	if err := h.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Undo on a new history: %v", err)
+	}
+	for _, text := range []string{"a", "b", "c"} {
+		if err := h.Execute(&appendCommand{log: &log, text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if h.UndoDescription() != "append c" || !h.CanUndo() || h.CanRedo() {
+		t.Errorf("undo %q, can undo %v, can redo %v", h.UndoDescription(), h.CanUndo(), h.CanRedo())
+	}
+
+	h.Undo()
+	h.Undo()
+	if strings.Join(log, "") != "a" || h.RedoDescription() != "append b" {
+		t.Errorf("after two undos log %v, redo %q", log, h.RedoDescription())
+	}
+	h.Redo()
+	if strings.Join(log, "") != "ab" {
+		t.Errorf("after redo log %v", log)
+	}
+
+	// A new step drops the steps that could be redone
+	h.Execute(&appendCommand{log: &log, text: "d"})
+	if err := h.Redo(); !errors.Is(err, ErrNothingToRedo) || strings.Join(log, "") != "abd" {
+		t.Errorf("Redo after a new step: %v, log %v", err, log)
+	}
+	if changes != 7 {
+		t.Errorf("OnChange called %d times, want 7", changes)
+	}
+
+	// A failing command is not recorded
+	failure := errors.New("boom")
+	if err := h.Execute(&appendCommand{log: &log, text: "x", fail: failure}); !errors.Is(err, failure) || h.UndoDescription() != "append d" {
+		t.Errorf("failed Execute: %v, undo %q", err, h.UndoDescription())
+	}
+
+	h.Clear()
+	if h.CanUndo() || h.CanRedo() {
+		t.Errorf("steps left after Clear")
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	var log []string
+	h := New(2)
+	for _, text := range []string{"a", "b", "c"} {
+		h.Execute(&appendCommand{log: &log, text: text})
+	}
+	h.Undo()
+	h.Undo()
+	if err := h.Undo(); !errors.Is(err, ErrNothingToUndo) || strings.Join(log, "") != "a" {
+		t.Errorf("third undo with a limit of 2: %v, log %v", err, log)
+	}
+}
+
+func TestHistoryGroups(t *testing.T) {
+	var log []string
+	h := New(0)
+
+	h.Begin("bulk")
+	h.Execute(&appendCommand{log: &log, text: "a"})
+	h.Begin("inner")
+	h.Execute(&appendCommand{log: &log, text: "b"})
+	if err := h.Undo(); !errors.Is(err, ErrGroupOpen) {
+		t.Errorf("Undo with an open group: %v", err)
+	}
+	h.End()
+	h.Execute(&appendCommand{log: &log, text: "c"})
+	if err := h.End(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.End(); !errors.Is(err, ErrNoGroup) {
+		t.Errorf("End without a group: %v", err)
+	}
+
+	// The whole group is one step
+	if h.UndoDescription() != "bulk" {
+		t.Errorf("undo %q", h.UndoDescription())
+	}
+	h.Undo()
+	if len(log) != 0 || h.CanUndo() {
+		t.Errorf("after undoing the group log %v", log)
+	}
+	h.Redo()
+	if strings.Join(log, "") != "abc" {
+		t.Errorf("after redoing the group log %v", log)
+	}
+
+	// Empty groups leave no step, cancelled ones undo what they did
+	h.Begin("empty")
+	h.End()
+	h.Begin("cancelled")
+	h.Execute(&appendCommand{log: &log, text: "x"})
+	if err := h.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(log, "") != "abc" || h.UndoDescription() != "bulk" {
+		t.Errorf("after an empty and a cancelled group log %v, undo %q", log, h.UndoDescription())
+	}
+}
+
+func TestGroupRollsBackOnFailure(t *testing.T) {
+	var log []string
+	failure := errors.New("boom")
+	group := NewGroup("bulk", &appendCommand{log: &log, text: "a"}, &appendCommand{log: &log, text: "b"}, &appendCommand{log: &log, text: "c", fail: failure})
+
+	h := New(0)
+	if err := h.Execute(group); !errors.Is(err, failure) || len(log) != 0 || h.CanUndo() {
+		t.Errorf("failed group: %v, log %v", err, log)
+	}
+}
@@ -0,0 +1,263 @@
+package importers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+
+	"golang.org/x/net/html"
+)
+
+// Column indexes of a CaixaBank movement export, resolved from its header row
+type caixaBankColumns struct {
+	date      int
+	valueDate int
+	concept   int
+	concept2  int
+	amount    int
+	balance   int
+}
+
+// Header names used by the different CaixaBank exports, already normalized
+var caixaBankHeaders = map[string][]string{
+	"date":      {"fecha", "fecha operacion", "f operacion"},
+	"valueDate": {"fecha valor", "f valor"},
+	"concept":   {"concepto", "movimiento"},
+	"concept2":  {"mas datos", "concepto complementario", "observaciones"},
+	"amount":    {"importe", "cantidad"},
+	"balance":   {"saldo"},
+}
+
+var ErrNoCaixaBankHeader = errors.New("no CaixaBank header row found")
+
+// ImportCaixaBankFile reads a CaixaBank movement export from disk.
+func ImportCaixaBankFile(path string) (models.Blocks, RowErrors, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	return ImportCaixaBank(f)
+}
+
+// ImportCaixaBank reads a CaixaBank movement export, either the CSV download
+// or the legacy ".xls" file (which is really an HTML table). Rows that cannot
+// be parsed are reported in RowErrors and skipped; the returned error is only
+// set when the file as a whole is unreadable.
+func ImportCaixaBank(r io.Reader) (models.Blocks, RowErrors, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows [][]string
+	var lines []int
+	if strings.Contains(strings.ToLower(text), "<table") {
+		rows, err = readHTMLRows(text)
+		for i := range rows {
+			lines = append(lines, i+1)
+		}
+	} else {
+		rows, lines, err = readCSVRows(text)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return blocksFromCaixaBankRows(rows, lines)
+}
+
+func blocksFromCaixaBankRows(rows [][]string, lines []int) (models.Blocks, RowErrors, error) {
+
+	// Skip the account summary lines that precede the header
+	headerIndex := -1
+	var columns caixaBankColumns
+	for i, row := range rows {
+		if c, ok := findCaixaBankColumns(row); ok {
+			headerIndex = i
+			columns = c
+			break
+		}
+	}
+	if headerIndex < 0 {
+		return nil, nil, ErrNoCaixaBankHeader
+	}
+
+	var blocks models.Blocks
+	var rowErrors RowErrors
+	for i := headerIndex + 1; i < len(rows); i++ {
+		row := rows[i]
+		if isBlankRow(row) {
+			continue
+		}
+
+		block, err := caixaBankRowToBlock(row, columns)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: lines[i], Raw: strings.Join(row, ";"), Err: err})
+			continue
+		}
+		blocks.AddBlock(*block)
+	}
+
+	return blocks, rowErrors, nil
+}
+
+func findCaixaBankColumns(row []string) (caixaBankColumns, bool) {
+	columns := caixaBankColumns{date: -1, valueDate: -1, concept: -1, concept2: -1, amount: -1, balance: -1}
+	targets := map[string]*int{
+		"date":      &columns.date,
+		"valueDate": &columns.valueDate,
+		"concept":   &columns.concept,
+		"concept2":  &columns.concept2,
+		"amount":    &columns.amount,
+		"balance":   &columns.balance,
+	}
+
+	for i, cell := range row {
+		header := normalizeHeader(cell)
+		for field, aliases := range caixaBankHeaders {
+			for _, alias := range aliases {
+				if header == alias && *targets[field] < 0 {
+					*targets[field] = i
+				}
+			}
+		}
+	}
+
+	ok := columns.date >= 0 && columns.concept >= 0 && columns.amount >= 0
+	return columns, ok
+}
+
+func caixaBankRowToBlock(row []string, columns caixaBankColumns) (*models.Block, error) {
+	cell := func(index int) string {
+		if index < 0 || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+
+	if columns.amount >= len(row) || columns.date >= len(row) {
+		return nil, fmt.Errorf("expected at least %d columns, got %d", max(columns.amount, columns.date)+1, len(row))
+	}
+
+	block, err := models.ParseBlock(cell(columns.concept), cell(columns.date), cell(columns.concept2), cell(columns.amount), cell(columns.balance), money.EsES)
+	if err != nil {
+		return nil, err
+	}
+	// Bank exports sign debits as negative; Blocks store expenses as positive
+	block.Amount = block.Amount.Neg()
+
+	var valueDate time.Time
+	if cell(columns.valueDate) != "" {
+		if valueDate, err = models.ParseDateOrder(cell(columns.valueDate), models.DayFirst); err != nil {
+			return nil, err
+		}
+	}
+	block.ValueDate = valueDate
+	return block, nil
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// readCSVRows splits a CSV export, guessing the delimiter from the content.
+// It also returns the source line of every record for error reporting.
+func readCSVRows(text string) ([][]string, []int, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = guessDelimiter(text)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, record)
+		lines = append(lines, line)
+	}
+	return rows, lines, nil
+}
+
+func guessDelimiter(text string) rune {
+	best, bestCount := ';', 0
+	for _, delimiter := range []rune{';', '\t', ','} {
+		count := 0
+		for _, line := range strings.SplitN(text, "\n", 20) {
+			count += strings.Count(line, string(delimiter))
+		}
+		if count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
+
+// readHTMLRows extracts the cell text of every <tr> in an HTML document.
+func readHTMLRows(text string) ([][]string, error) {
+	doc, err := html.Parse(strings.NewReader(text))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var row []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+					row = append(row, nodeText(c))
+				}
+			}
+			rows = append(rows, row)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	return rows, nil
+}
+
+func nodeText(n *html.Node) string {
+	var buf bytes.Buffer
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
@@ -0,0 +1,119 @@
+package importers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Account summary lines come before the header in the CSV download
+const caixaBankCSV = `Cuenta;ES00 2100 0000 0000 0000 0000
+Saldo disponible;1.482,66
+
+Fecha;Fecha valor;Movimiento;Más datos;Importe;Saldo
+02/03/2025;03/03/2025;MERCADONA;COMPRA TARJETA;-12,34;1.487,66
+01/03/2025;01/03/2025;NÓMINA;MARZO;1.500,00;1.500,00
+;;;;;
+03/03/2025;;CAFÉ;;-5;1.482,66
+04/03/2025;;ROTA;;doce;
+31/02/2025;;ROTA;;-1;
+`
+
+const caixaBankHTML = `<html><body><table>
+<tr><th>F. Operación</th><th>F. Valor</th><th>Concepto</th><th>Importe</th><th>Saldo</th></tr>
+<tr><td>02/03/2025</td><td>03/03/2025</td><td> MERCADONA
+  SUPER </td><td>-12,34</td><td>1.487,66</td></tr>
+<tr><td>01/03/2025</td><td></td><td>NÓMINA</td><td>1.500,00</td><td></td></tr>
+</table></body></html>`
+
+func TestImportCaixaBankCSV(t *testing.T) {
+	blocks, rowErrors, err := ImportCaixaBank(strings.NewReader(caixaBankCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		date      string
+		valueDate string
+		concept   string
+		concept2  string
+		amount    int64
+		balance   int64
+	}{
+		{"2025-03-02", "2025-03-03", "MERCADONA", "COMPRA TARJETA", 1234, 148766},
+		{"2025-03-01", "2025-03-01", "NÓMINA", "MARZO", -150000, 150000},
+		{"2025-03-03", "", "CAFÉ", "", 500, 148266},
+	}
+	if len(blocks) != len(tests) {
+		t.Fatalf("%d blocks, want %d", len(blocks), len(tests))
+	}
+	for i, test := range tests {
+		block := blocks[i]
+		valueDate := ""
+		if !block.ValueDate.IsZero() {
+			valueDate = block.ValueDate.Format("2006-01-02")
+		}
+		if block.FormatDate() != test.date || valueDate != test.valueDate || block.Concept.Name != test.concept || block.Concept2 != test.concept2 {
+			t.Errorf("block %d: %s %s %q %q, want %+v", i, block.FormatDate(), valueDate, block.Concept.Name, block.Concept2, test)
+		}
+		if block.Amount.Minor() != test.amount || block.Balance.Minor() != test.balance || block.Amount.Currency() != "EUR" {
+			t.Errorf("block %d: amount %s balance %s, want %d and %d", i, block.Amount, block.Balance, test.amount, test.balance)
+		}
+	}
+
+	// Bad rows are reported with their source line and skipped
+	if len(rowErrors) != 2 || rowErrors[0].Line != 9 || rowErrors[1].Line != 10 {
+		t.Fatalf("row errors %v, want lines 9 and 10", rowErrors)
+	}
+	if !strings.HasPrefix(rowErrors[0].Raw, "04/03/2025;") || errors.Unwrap(rowErrors[0]) == nil {
+		t.Errorf("row error %+v does not keep the row or the cause", rowErrors[0])
+	}
+}
+
+func TestImportCaixaBankWindows1252(t *testing.T) {
+	encoded, err := charmap.Windows1252.NewEncoder().String(caixaBankCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, _, err := ImportCaixaBank(strings.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 3 || blocks[1].Concept.Name != "NÓMINA" || blocks[0].Concept2 != "COMPRA TARJETA" {
+		t.Errorf("%d blocks, concept %q, want the accents decoded", len(blocks), blocks[1].Concept.Name)
+	}
+}
+
+func TestImportCaixaBankHTML(t *testing.T) {
+	blocks, rowErrors, err := ImportCaixaBank(strings.NewReader(caixaBankHTML))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatal(err, rowErrors)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("%d blocks, want 2", len(blocks))
+	}
+	if blocks[0].Concept.Name != "MERCADONA SUPER" || blocks[0].Amount.Minor() != 1234 || blocks[0].ValueDate.Format("2006-01-02") != "2025-03-03" {
+		t.Errorf("first block %q %s value date %s", blocks[0].Concept.Name, blocks[0].Amount, blocks[0].ValueDate)
+	}
+	if blocks[1].Amount.Minor() != -150000 || blocks[1].HasBalance() || !blocks[1].ValueDate.IsZero() {
+		t.Errorf("second block %s balance %s value date %s", blocks[1].Amount, blocks[1].Balance, blocks[1].ValueDate)
+	}
+}
+
+func TestImportCaixaBankDelimiters(t *testing.T) {
+	for _, delimiter := range []string{"\t", ","} {
+		text := "Fecha" + delimiter + "Concepto" + delimiter + "Importe\n02/03/2025" + delimiter + "BAR" + delimiter + "\"-2,50\"\n"
+		blocks, rowErrors, err := ImportCaixaBank(strings.NewReader(text))
+		if err != nil || len(rowErrors) != 0 || len(blocks) != 1 || blocks[0].Amount.Minor() != 250 {
+			t.Errorf("delimiter %q: %d blocks, %v, %v", delimiter, len(blocks), rowErrors, err)
+		}
+	}
+}
+
+func TestImportCaixaBankNoHeader(t *testing.T) {
+	if _, _, err := ImportCaixaBank(strings.NewReader("02/03/2025;BAR;-2,50\n")); !errors.Is(err, ErrNoCaixaBankHeader) {
+		t.Errorf("error = %v, want ErrNoCaixaBankHeader", err)
+	}
+}
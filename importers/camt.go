@@ -0,0 +1,280 @@
+package importers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+var ErrNoCamtStatement = errors.New("no camt statement or report found")
+
+// CamtBalanceError reports a statement whose opening balance plus its
+// entries does not give the closing balance.
+type CamtBalanceError struct {
+	StatementID string
+	Opening     money.Money
+	Closing     money.Money
+	Computed    money.Money
+}
+
+func (e CamtBalanceError) Error() string {
+	return fmt.Sprintf("camt statement %s: closing balance %s, entries give %s (missing %s)",
+		e.StatementID, e.Closing, e.Computed, e.Closing.Sub(e.Computed))
+}
+
+// XML layout shared by camt.053 (BkToCstmrStmt/Stmt) and camt.052
+// (BkToCstmrAcctRpt/Rpt). Namespaces are ignored so every version matches.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	Reports    []camtStatement `xml:"BkToCstmrAcctRpt>Rpt"`
+}
+
+type camtStatement struct {
+	ID       string        `xml:"Id"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtEntry struct {
+	Reference      string          `xml:"NtryRef"`
+	Amount         camtAmount      `xml:"Amt"`
+	CdtDbtInd      string          `xml:"CdtDbtInd"`
+	Status         camtStatus      `xml:"Sts"`
+	BookingDate    camtDate        `xml:"BookgDt"`
+	ValueDate      camtDate        `xml:"ValDt"`
+	ServicerRef    string          `xml:"AcctSvcrRef"`
+	AdditionalInfo string          `xml:"AddtlNtryInf"`
+	Transactions   []camtTxDetails `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTxDetails struct {
+	Unstructured []string `xml:"RmtInf>Ustrd"`
+	CreditorName string   `xml:"RltdPties>Cdtr>Nm"`
+	DebtorName   string   `xml:"RltdPties>Dbtr>Nm"`
+	// camt.053.001.08 and later wrap the party in a Pty element
+	CreditorPartyName string `xml:"RltdPties>Cdtr>Pty>Nm"`
+	DebtorPartyName   string `xml:"RltdPties>Dbtr>Pty>Nm"`
+}
+
+// ImportCamtFile reads a camt.053 or camt.052 document from disk.
+func ImportCamtFile(path string) (models.Blocks, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ImportCamt(f)
+}
+
+// ImportCamt maps the booked Ntry elements of an ISO 20022 camt.053 statement
+// or camt.052 report to Blocks, keeping the booking date as Date and the value
+// date as ValueDate. When a statement carries opening (OPBD/PRCD) and closing
+// (CLBD) balances, the running Balance is filled in and the entries are
+// checked against them; mismatches are returned as CamtBalanceError together
+// with the blocks.
+func ImportCamt(r io.Reader) (models.Blocks, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	statements := append(doc.Statements, doc.Reports...)
+	if len(statements) == 0 {
+		return nil, ErrNoCamtStatement
+	}
+
+	var blocks models.Blocks
+	var errs []error
+	for _, statement := range statements {
+		statementBlocks, err := statement.toBlocks()
+		if err != nil {
+			return nil, fmt.Errorf("camt statement %s: %w", statement.ID, err)
+		}
+		blocks = append(blocks, statementBlocks...)
+
+		if err := statement.checkBalances(statementBlocks); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return blocks, errors.Join(errs...)
+}
+
+func (s camtStatement) toBlocks() (models.Blocks, error) {
+	opening, hasOpening, err := s.balance("OPBD", "PRCD")
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks models.Blocks
+	running := opening
+	for _, entry := range s.Entries {
+		if !entry.isBooked() {
+			continue
+		}
+
+		amount, err := entry.Amount.money()
+		if err != nil {
+			return nil, fmt.Errorf("entry %s amount: %w", entry.Reference, err)
+		}
+		// Blocks store expenses (DBIT) as positive amounts
+		if entry.CdtDbtInd == "CRDT" {
+			amount = amount.Neg()
+		}
+		running = running.Sub(amount)
+
+		var balance money.Money
+		if hasOpening {
+			balance = running
+		}
+
+		bookingDate, err := entry.BookingDate.time()
+		if err != nil {
+			return nil, fmt.Errorf("entry %s booking date: %w", entry.Reference, err)
+		}
+		valueDate, err := entry.ValueDate.time()
+		if err != nil {
+			return nil, fmt.Errorf("entry %s value date: %w", entry.Reference, err)
+		}
+
+		concept, concept2 := entry.concepts()
+		block := models.NewBlockWithMoney(concept, bookingDate, concept2, amount, balance)
+		block.ValueDate = valueDate
+		block.ExternalID = entry.ServicerRef
+		if block.ExternalID == "" {
+			block.ExternalID = entry.Reference
+		}
+		blocks.AddBlock(*block)
+	}
+
+	return blocks, nil
+}
+
+func (s camtStatement) checkBalances(blocks models.Blocks) error {
+	opening, hasOpening, err := s.balance("OPBD", "PRCD")
+	if err != nil {
+		return err
+	}
+	closing, hasClosing, err := s.balance("CLBD")
+	if err != nil {
+		return err
+	}
+	if !hasOpening || !hasClosing {
+		return nil
+	}
+
+	computed := opening
+	for _, block := range blocks {
+		computed = computed.Sub(block.Amount)
+	}
+	if !computed.Equal(closing) {
+		return CamtBalanceError{StatementID: s.ID, Opening: opening, Closing: closing, Computed: computed}
+	}
+	return nil
+}
+
+// balance returns the first balance with one of the given type codes.
+func (s camtStatement) balance(codes ...string) (money.Money, bool, error) {
+	for _, code := range codes {
+		for _, balance := range s.Balances {
+			if balance.Code != code {
+				continue
+			}
+			amount, err := balance.Amount.money()
+			if err != nil {
+				return money.Money{}, false, fmt.Errorf("%s balance: %w", code, err)
+			}
+			if balance.CdtDbtInd == "DBIT" {
+				amount = amount.Neg()
+			}
+			return amount, true, nil
+		}
+	}
+	return money.Money{}, false, nil
+}
+
+func (a camtAmount) money() (money.Money, error) {
+	currency := a.Currency
+	if currency == "" {
+		currency = "EUR"
+	}
+	return money.ParseDecimal(a.Value, currency)
+}
+
+func (e camtEntry) isBooked() bool {
+	status := strings.TrimSpace(e.Status.Code)
+	if status == "" {
+		status = strings.TrimSpace(e.Status.Text)
+	}
+	return status == "" || status == "BOOK"
+}
+
+// concepts picks the counterparty name (or the additional entry info) as the
+// Concept and the unstructured remittance information as the Concept2.
+func (e camtEntry) concepts() (string, string) {
+	var counterparty string
+	var remittance []string
+	for _, tx := range e.Transactions {
+		name := tx.CreditorName + tx.CreditorPartyName
+		if e.CdtDbtInd == "CRDT" {
+			name = tx.DebtorName + tx.DebtorPartyName
+		}
+		if counterparty == "" {
+			counterparty = strings.TrimSpace(name)
+		}
+		for _, line := range tx.Unstructured {
+			if line = strings.TrimSpace(line); line != "" {
+				remittance = append(remittance, line)
+			}
+		}
+	}
+
+	concept := counterparty
+	if concept == "" {
+		concept = strings.TrimSpace(e.AdditionalInfo)
+	}
+	if concept == "" && len(remittance) > 0 {
+		concept, remittance = remittance[0], remittance[1:]
+	}
+	return concept, strings.Join(remittance, " ")
+}
+
+// time returns the Dt or DtTm of the element, or the zero time when absent.
+func (d camtDate) time() (time.Time, error) {
+	value := strings.TrimSpace(d.Date)
+	if value == "" {
+		value = strings.TrimSpace(d.DateTime)
+	}
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return models.ParseDate(value)
+}
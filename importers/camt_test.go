@@ -0,0 +1,101 @@
+package importers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func camtEntryXML(reference string, cents string, indicator string, status string, booking string, parties string, remittance string) string {
+	return fmt.Sprintf(`<Ntry><NtryRef>%s</NtryRef><Amt Ccy="EUR">%s</Amt><CdtDbtInd>%s</CdtDbtInd><Sts>%s</Sts>`+
+		`<BookgDt><Dt>%s</Dt></BookgDt><ValDt><DtTm>%sT10:00:00</DtTm></ValDt>`+
+		`<NtryDtls><TxDtls><RltdPties>%s</RltdPties><RmtInf>%s</RmtInf></TxDtls></NtryDtls></Ntry>`,
+		reference, cents, indicator, status, booking, booking, parties, remittance)
+}
+
+func camtStatementXML(closing string, entries ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"><BkToCstmrStmt><Stmt><Id>S1</Id>
+<Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd></Bal>
+<Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">` + closing + `</Amt><CdtDbtInd>CRDT</CdtDbtInd></Bal>
+` + strings.Join(entries, "\n") + `
+</Stmt></BkToCstmrStmt></Document>`
+}
+
+func TestImportCamt(t *testing.T) {
+	document := camtStatementXML("1087.66",
+		camtEntryXML("E1", "12.34", "DBIT", "BOOK", "2025-03-02", "<Cdtr><Nm>MERCADONA</Nm></Cdtr>", "<Ustrd>TICKET 1</Ustrd><Ustrd> TIENDA 12 </Ustrd>"),
+		camtEntryXML("E2", "1000.00", "CRDT", "<Cd>BOOK</Cd>", "2025-03-03", "<Dbtr><Pty><Nm>EMPRESA SL</Nm></Pty></Dbtr>", ""),
+		camtEntryXML("E3", "50.00", "DBIT", "PDNG", "2025-03-04", "", "<Ustrd>PENDIENTE</Ustrd>"),
+	)
+	blocks, err := ImportCamt(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("%d blocks, want 2 without the pending entry", len(blocks))
+	}
+
+	first, second := blocks[0], blocks[1]
+	if first.Concept.Name != "MERCADONA" || first.Concept2 != "TICKET 1 TIENDA 12" || first.ExternalID != "E1" {
+		t.Errorf("first %q %q %q", first.Concept.Name, first.Concept2, first.ExternalID)
+	}
+	if first.Amount.Minor() != 1234 || first.Balance.Minor() != 8766 {
+		t.Errorf("first amount %s balance %s, want 12.34 and 87.66", first.Amount, first.Balance)
+	}
+	if first.FormatDate() != "2025-03-02" || first.ValueDate.Format("2006-01-02") != "2025-03-02" {
+		t.Errorf("first dates %s / %s", first.FormatDate(), first.ValueDate)
+	}
+	// The debtor is the counterparty of a credit
+	if second.Concept.Name != "EMPRESA SL" || second.Amount.Minor() != -100000 || second.Balance.Minor() != 108766 {
+		t.Errorf("second %q amount %s balance %s", second.Concept.Name, second.Amount, second.Balance)
+	}
+}
+
+func TestImportCamtBalanceMismatch(t *testing.T) {
+	document := camtStatementXML("90.00", camtEntryXML("E1", "12.34", "DBIT", "BOOK", "2025-03-02", "", "<Ustrd>PAGO</Ustrd>"))
+	blocks, err := ImportCamt(strings.NewReader(document))
+	if len(blocks) != 1 || blocks[0].Concept.Name != "PAGO" {
+		t.Errorf("blocks %v, want the entry even when the balances fail", blocks)
+	}
+
+	var balanceError CamtBalanceError
+	if !errors.As(err, &balanceError) {
+		t.Fatalf("error = %v, want a CamtBalanceError", err)
+	}
+	if balanceError.StatementID != "S1" || balanceError.Closing.Minor() != 9000 || balanceError.Computed.Minor() != 8766 {
+		t.Errorf("error %v", balanceError)
+	}
+}
+
+func TestImportCamtReport(t *testing.T) {
+	// A camt.052 report without balances has no running Balance
+	document := `<Document><BkToCstmrAcctRpt><Rpt><Id>R1</Id>` +
+		camtEntryXML("", "3.00", "DBIT", "", "2025-03-02", "", "") +
+		`</Rpt></BkToCstmrAcctRpt></Document>`
+	document = strings.Replace(document, "<Ntry>", "<Ntry><AcctSvcrRef>REF-9</AcctSvcrRef><AddtlNtryInf>COMISION</AddtlNtryInf>", 1)
+
+	blocks, err := ImportCamt(strings.NewReader(document))
+	if err != nil || len(blocks) != 1 {
+		t.Fatalf("blocks %v, %v, want one", blocks, err)
+	}
+	if blocks[0].Concept.Name != "COMISION" || blocks[0].ExternalID != "REF-9" || blocks[0].HasBalance() {
+		t.Errorf("block %q %q balance %s", blocks[0].Concept.Name, blocks[0].ExternalID, blocks[0].Balance)
+	}
+}
+
+func TestImportCamtErrors(t *testing.T) {
+	if _, err := ImportCamt(strings.NewReader("<Document></Document>")); !errors.Is(err, ErrNoCamtStatement) {
+		t.Errorf("empty document error = %v, want ErrNoCamtStatement", err)
+	}
+	for name, document := range map[string]string{
+		"amount": camtStatementXML("100.00", camtEntryXML("E1", "1,5", "DBIT", "BOOK", "2025-03-02", "", "")),
+		"date":   camtStatementXML("100.00", camtEntryXML("E1", "1.50", "DBIT", "BOOK", "2025-02-30", "", "")),
+		"xml":    "<Document><BkToCstmrStmt>",
+	} {
+		if _, err := ImportCamt(strings.NewReader(document)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Package importers turns bank statement exports into models.Blocks.
+//
+// Amounts follow the sign convention used by models.Block and the styles
+// package: money leaving the account (expenses) is positive and money coming
+// in is negative. Bank files use the opposite sign, so every importer negates
+// the amount it reads.
+package importers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// RowError describes a row of the input that could not be turned into a Block.
+type RowError struct {
+	Line int    // 1-based line (or table row) in the source file
+	Raw  string // The offending row, joined for display
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+type RowErrors []RowError
+
+func (errs RowErrors) Error() string {
+	lines := make([]string, 0, len(errs))
+	for _, e := range errs {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// decodeText returns the input as UTF-8, stripping a BOM and falling back to
+// Windows-1252 (the encoding used by CaixaBank exports) when it is not UTF-8.
+func decodeText(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// normalizeHeader lowercases a column header and removes accents and
+// punctuation so "Más datos" and "MAS DATOS." compare equal.
+func normalizeHeader(s string) string {
+	replacer := strings.NewReplacer(
+		"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+		".", "", ":", "", " ", " ",
+	)
+	s = replacer.Replace(strings.ToLower(strings.TrimSpace(s)))
+	return strings.Join(strings.Fields(s), " ")
+}
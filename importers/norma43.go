@@ -0,0 +1,362 @@
+package importers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Norma 43 (AEB 43) record codes
+const (
+	norma43Header        = "11"
+	norma43Movement      = "22"
+	norma43Complementary = "23"
+	norma43Equivalence   = "24"
+	norma43Footer        = "33"
+	norma43EndOfFile     = "88"
+)
+
+const norma43RecordLength = 80
+
+var (
+	ErrNorma43Malformed        = errors.New("malformed record")
+	ErrNorma43UnexpectedRecord = errors.New("unexpected record")
+	ErrNorma43DebitCount       = errors.New("debit count does not match footer")
+	ErrNorma43DebitTotal       = errors.New("debit total does not match footer")
+	ErrNorma43CreditCount      = errors.New("credit count does not match footer")
+	ErrNorma43CreditTotal      = errors.New("credit total does not match footer")
+	ErrNorma43ClosingBalance   = errors.New("closing balance does not match footer")
+	ErrNorma43MissingFooter    = errors.New("account without footer record")
+)
+
+// Norma43Error is a parsing or validation failure tied to a line of the file.
+// Err is one of the ErrNorma43 sentinels, so callers can use errors.Is.
+type Norma43Error struct {
+	Line   int
+	Detail string
+	Err    error
+}
+
+func (e Norma43Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("norma 43 line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("norma 43 line %d: %v (%s)", e.Line, e.Err, e.Detail)
+}
+
+func (e Norma43Error) Unwrap() error {
+	return e.Err
+}
+
+type Norma43Errors []Norma43Error
+
+func (errs Norma43Errors) Error() string {
+	lines := make([]string, 0, len(errs))
+	for _, e := range errs {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Common concept codes defined by the AEB, used when a movement has no text
+var norma43CommonConcepts = map[string]string{
+	"01": "TALONES - REINTEGROS",
+	"02": "ABONARES - ENTREGAS - INGRESOS",
+	"03": "DOMICILIADOS - RECIBOS - LETRAS - PAGOS POR SU CTA.",
+	"04": "GIROS - TRANSFERENCIAS - TRASPASOS - CHEQUES",
+	"05": "AMORTIZACIONES PRESTAMOS, CREDITOS, ETC.",
+	"06": "REMESAS EFECTOS",
+	"07": "SUSCRIPCIONES - DIV. PASIVOS - CANJES",
+	"08": "DIV. CUPONES - PRIMA JUNTA - AMORTIZACIONES",
+	"09": "OPERACIONES DE BOLSA Y/O COMPRA/VENTA VALORES",
+	"10": "CHEQUES GASOLINA",
+	"11": "CAJERO AUTOMATICO",
+	"12": "TARJETAS DE CREDITO - TARJETAS DE DEBITO",
+	"13": "OPERACIONES EXTRANJERO",
+	"14": "DEVOLUCIONES E IMPAGADOS",
+	"15": "NOMINAS - SEGUROS SOCIALES",
+	"16": "TIMBRES - CORRETAJE - POLIZA",
+	"17": "INTERESES - COMISIONES - CUSTODIA - GASTOS E IMPUESTOS",
+	"98": "ANULACIONES - CORRECCIONES ASIENTO",
+	"99": "VARIOS",
+}
+
+// norma43Account accumulates the movements of one 11..33 group
+type norma43Account struct {
+	headerLine   int
+	currency     string
+	balanceCents int64
+	debitCount   int
+	debitCents   int64
+	creditCount  int
+	creditCents  int64
+	blocks       models.Blocks
+	texts        [][]string // 23 record texts for every block
+}
+
+// ImportNorma43File reads a Norma 43 statement from disk.
+func ImportNorma43File(path string) (models.Blocks, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ImportNorma43(f)
+}
+
+// ImportNorma43 parses an AEB Norma 43 statement. Every movement (22) becomes
+// a Block whose Concept is the description of its AEB common concept, whose
+// Concept2 is built from its complementary concept records (23), or is its
+// Referencia 2 when it has none, and whose Balance is computed from the
+// opening balance of the header (11).
+// The footer (33) totals are checked against the movements; any problem is
+// returned as Norma43Errors together with the blocks that could be read.
+func ImportNorma43(r io.Reader) (models.Blocks, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	// The fields are at fixed byte offsets of Windows-1252 records, so a file
+	// saved as UTF-8 is taken back to one byte per character
+	isUTF8 := utf8.Valid(data)
+
+	var blocks models.Blocks
+	var errs Norma43Errors
+	var account *norma43Account
+
+	fail := func(line int, sentinel error, format string, args ...interface{}) {
+		errs = append(errs, Norma43Error{Line: line, Detail: fmt.Sprintf(format, args...), Err: sentinel})
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		record := newNorma43Record(scanner.Bytes(), isUTF8)
+		if record == nil {
+			continue
+		}
+
+		switch record.field(0, 2) {
+		case norma43Header:
+			if account != nil {
+				fail(account.headerLine, ErrNorma43MissingFooter, "")
+				blocks = append(blocks, account.finish()...)
+			}
+			balance, err := norma43SignedAmount(record.field(32, 33), record.field(33, 47))
+			if err != nil {
+				fail(line, ErrNorma43Malformed, "opening balance: %v", err)
+			}
+			account = &norma43Account{headerLine: line, currency: norma43Currency(record.field(47, 50)), balanceCents: balance}
+
+		case norma43Movement:
+			if account == nil {
+				fail(line, ErrNorma43UnexpectedRecord, "movement before header")
+				continue
+			}
+			block, err := account.addMovement(record)
+			if err != nil {
+				fail(line, ErrNorma43Malformed, "%v", err)
+				continue
+			}
+			account.blocks = append(account.blocks, *block)
+			account.texts = append(account.texts, nil)
+
+		case norma43Complementary:
+			if account == nil || len(account.blocks) == 0 {
+				fail(line, ErrNorma43UnexpectedRecord, "complementary concept without movement")
+				continue
+			}
+			last := len(account.texts) - 1
+			for _, field := range []string{record.field(4, 42), record.field(42, 80)} {
+				if field = strings.TrimSpace(field); field != "" {
+					account.texts[last] = append(account.texts[last], field)
+				}
+			}
+
+		case norma43Equivalence:
+			// Currency equivalence, not used by Blocks
+
+		case norma43Footer:
+			if account == nil {
+				fail(line, ErrNorma43UnexpectedRecord, "footer without header")
+				continue
+			}
+			errs = append(errs, account.validateFooter(record, line)...)
+			blocks = append(blocks, account.finish()...)
+			account = nil
+
+		case norma43EndOfFile:
+			// Record count only
+
+		default:
+			fail(line, ErrNorma43UnexpectedRecord, "record code %q", record.field(0, 2))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return blocks, err
+	}
+
+	if account != nil {
+		fail(account.headerLine, ErrNorma43MissingFooter, "")
+		blocks = append(blocks, account.finish()...)
+	}
+
+	if len(errs) > 0 {
+		return blocks, errs
+	}
+	return blocks, nil
+}
+
+// norma43Record is a line of the file in Windows-1252, padded with spaces to
+// the record length
+type norma43Record []byte
+
+// newNorma43Record returns the record of a line, or nil for a blank line.
+// Lines of a UTF-8 file are encoded to Windows-1252 when they can be.
+func newNorma43Record(line []byte, isUTF8 bool) norma43Record {
+	line = bytes.TrimRight(line, "\r")
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil
+	}
+	record := append(norma43Record(nil), line...)
+	if isUTF8 {
+		if encoded, err := charmap.Windows1252.NewEncoder().Bytes(line); err == nil {
+			record = encoded
+		}
+	}
+	if len(record) < norma43RecordLength {
+		record = append(record, bytes.Repeat([]byte(" "), norma43RecordLength-len(record))...)
+	}
+	return record
+}
+
+// field decodes the bytes from..to of the record to UTF-8
+func (r norma43Record) field(from, to int) string {
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(r[from:to])
+	if err != nil {
+		return string(r[from:to])
+	}
+	return string(decoded)
+}
+
+func (a *norma43Account) addMovement(record norma43Record) (*models.Block, error) {
+	date, err := time.Parse("060102", record.field(10, 16))
+	if err != nil {
+		return nil, fmt.Errorf("operation date %q", record.field(10, 16))
+	}
+	valueDate, err := time.Parse("060102", record.field(16, 22))
+	if err != nil {
+		return nil, fmt.Errorf("value date %q", record.field(16, 22))
+	}
+
+	cents, err := norma43Amount(record.field(28, 42))
+	if err != nil {
+		return nil, fmt.Errorf("amount: %v", err)
+	}
+
+	switch code := record.field(27, 28); code {
+	case "1":
+		a.debitCount++
+		a.debitCents += cents
+		a.balanceCents -= cents
+	case "2":
+		a.creditCount++
+		a.creditCents += cents
+		a.balanceCents += cents
+		cents = -cents
+	default:
+		return nil, fmt.Errorf("debit/credit code %q", code)
+	}
+
+	// Referencia 2 is a bank reference, not a description, so it is only the
+	// Concept2 of movements without 23 records, see finish
+	concept := norma43CommonConcepts[record.field(22, 24)]
+	reference := strings.TrimSpace(record.field(64, 80))
+
+	block := models.NewBlockWithMoney(concept, date, reference, money.New(cents, a.currency), money.New(a.balanceCents, a.currency))
+	block.ValueDate = valueDate
+	return block, nil
+}
+
+// finish moves the complementary concept texts into the Concept2 of the
+// blocks that have them.
+func (a *norma43Account) finish() models.Blocks {
+	for i := range a.blocks {
+		if texts := a.texts[i]; len(texts) > 0 {
+			a.blocks[i].Concept2 = strings.Join(texts, " ")
+		}
+	}
+	return a.blocks
+}
+
+func (a *norma43Account) validateFooter(record norma43Record, line int) Norma43Errors {
+	var errs Norma43Errors
+	check := func(field string, sentinel error, expected int64, parse func(string) (int64, error)) {
+		value, err := parse(field)
+		if err != nil {
+			errs = append(errs, Norma43Error{Line: line, Detail: err.Error(), Err: ErrNorma43Malformed})
+			return
+		}
+		if value != expected {
+			errs = append(errs, Norma43Error{Line: line, Detail: fmt.Sprintf("footer %d, movements %d", value, expected), Err: sentinel})
+		}
+	}
+	count := func(s string) (int64, error) {
+		return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	}
+	closing := func(s string) (int64, error) {
+		return norma43SignedAmount(record.field(58, 59), s)
+	}
+
+	check(record.field(20, 25), ErrNorma43DebitCount, int64(a.debitCount), count)
+	check(record.field(25, 39), ErrNorma43DebitTotal, a.debitCents, norma43Amount)
+	check(record.field(39, 44), ErrNorma43CreditCount, int64(a.creditCount), count)
+	check(record.field(44, 58), ErrNorma43CreditTotal, a.creditCents, norma43Amount)
+	check(record.field(59, 73), ErrNorma43ClosingBalance, a.balanceCents, closing)
+	return errs
+}
+
+// norma43Amount parses a 14 digit amount with two implied decimals into cents.
+func norma43Amount(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+}
+
+// norma43SignedAmount applies a debit (1) / credit (2) code to an amount.
+func norma43SignedAmount(code string, s string) (int64, error) {
+	cents, err := norma43Amount(s)
+	if err != nil {
+		return 0, err
+	}
+	if code == "1" {
+		return -cents, nil
+	}
+	return cents, nil
+}
+
+// norma43Currency maps the ISO 4217 numeric code of the header to its
+// alphabetic code.
+func norma43Currency(code string) string {
+	switch code {
+	case "840":
+		return "USD"
+	case "826":
+		return "GBP"
+	case "756":
+		return "CHF"
+	}
+	return "EUR"
+}
@@ -0,0 +1,174 @@
+package importers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// The record builders count widths in characters, which are bytes once the
+// file is in Windows-1252
+func headerRecord(balance int64) string {
+	code := "2"
+	if balance < 0 {
+		code, balance = "1", -balance
+	}
+	return fmt.Sprintf("11%4s%4s%10s%6s%6s%s%014d%3s%-30s", "2100", "0418", "0200051332", "250301", "250331", code, balance, "978", "3NOMBRE TITULAR")
+}
+
+func movementRecord(date string, debit bool, cents int64, reference string) string {
+	code := "2"
+	if debit {
+		code = "1"
+	}
+	return fmt.Sprintf("22%4s%4s%6s%6s%2s%3s%s%014d%010d%12s%-16s", "", "0418", date, date, "12", "345", code, cents, 0, "", reference)
+}
+
+func complementaryRecord(first string, second string) string {
+	return fmt.Sprintf("23%2s%-38s%-38s", "01", first, second)
+}
+
+func footerRecord(debits int, debitCents int64, credits int, creditCents int64, closing int64) string {
+	code := "2"
+	if closing < 0 {
+		code, closing = "1", -closing
+	}
+	return fmt.Sprintf("33%4s%4s%10s%05d%014d%05d%014d%s%014d%3s    ", "2100", "0418", "0200051332", debits, debitCents, credits, creditCents, code, closing, "978")
+}
+
+func norma43File(records ...string) string {
+	return strings.Join(records, "\r\n") + "\r\n"
+}
+
+func TestImportNorma43(t *testing.T) {
+	file := norma43File(
+		headerRecord(100000),
+		movementRecord("250302", true, 1234, "ESPAÑA REF 12"),
+		// 38 characters, so a two byte Ñ would push the rest into the next field
+		complementaryRecord("PANADERÍA MUÑOZ SL CALLE MAYOR 12 MADR", "SEGUNDA LÍNEA"),
+		complementaryRecord("", "TERCERA"),
+		movementRecord("250305", false, 10000, ""),
+		footerRecord(1, 1234, 1, 10000, 108766),
+		"88999999999999999999000005",
+	)
+	windows1252, err := charmap.Windows1252.NewEncoder().String(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string]string{"utf-8": file, "windows-1252": windows1252, "bom": "\xef\xbb\xbf" + file} {
+		blocks, err := ImportNorma43(strings.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(blocks) != 2 {
+			t.Fatalf("%s: %d blocks, want 2", name, len(blocks))
+		}
+
+		// Every 23 text goes to Concept2, in order, and the Referencia 2 is
+		// left out when there are 23 records
+		first, second := blocks[0], blocks[1]
+		if first.Concept.Name != norma43CommonConcepts["12"] || first.Concept2 != "PANADERÍA MUÑOZ SL CALLE MAYOR 12 MADR SEGUNDA LÍNEA TERCERA" {
+			t.Errorf("%s: concepts %q / %q", name, first.Concept.Name, first.Concept2)
+		}
+		if first.Amount.Minor() != 1234 || first.Amount.Currency() != "EUR" || first.Balance.Minor() != 98766 {
+			t.Errorf("%s: first amount %s balance %s", name, first.Amount, first.Balance)
+		}
+		if first.FormatDate() != "2025-03-02" || first.ValueDate.Format("2006-01-02") != "2025-03-02" {
+			t.Errorf("%s: first dates %s / %s", name, first.FormatDate(), first.ValueDate)
+		}
+		if second.Concept.Name != norma43CommonConcepts["12"] || second.Concept2 != "" || second.Amount.Minor() != -10000 || second.Balance.Minor() != 108766 {
+			t.Errorf("%s: second %q / %q amount %s balance %s", name, second.Concept.Name, second.Concept2, second.Amount, second.Balance)
+		}
+	}
+}
+
+func TestImportNorma43Reference(t *testing.T) {
+	file := norma43File(
+		headerRecord(0),
+		movementRecord("250302", true, 500, "REF ÑANDÚ"),
+		footerRecord(1, 500, 0, 0, -500),
+	)
+	windows1252, _ := charmap.Windows1252.NewEncoder().String(file)
+	for _, data := range []string{file, windows1252} {
+		blocks, err := ImportNorma43(strings.NewReader(data))
+		// Without 23 records the Referencia 2 is the Concept2, never the Concept
+		if err != nil || len(blocks) != 1 || blocks[0].Concept.Name != norma43CommonConcepts["12"] || blocks[0].Concept2 != "REF ÑANDÚ" {
+			t.Errorf("blocks %v, %v, want the reference as Concept2", blocks, err)
+		}
+	}
+}
+
+func TestImportNorma43Footer(t *testing.T) {
+	header := headerRecord(100000)
+	movements := []string{movementRecord("250302", true, 1234, ""), movementRecord("250305", false, 10000, "")}
+
+	tests := []struct {
+		name   string
+		footer string
+		errs   []error
+	}{
+		{"valid", footerRecord(1, 1234, 1, 10000, 108766), nil},
+		{"debit count", footerRecord(2, 1234, 1, 10000, 108766), []error{ErrNorma43DebitCount}},
+		{"debit total", footerRecord(1, 1235, 1, 10000, 108766), []error{ErrNorma43DebitTotal}},
+		{"credit count", footerRecord(1, 1234, 0, 10000, 108766), []error{ErrNorma43CreditCount}},
+		{"credit total", footerRecord(1, 1234, 1, 9999, 108766), []error{ErrNorma43CreditTotal}},
+		{"closing balance", footerRecord(1, 1234, 1, 10000, -108766), []error{ErrNorma43ClosingBalance}},
+		{"several", footerRecord(0, 0, 1, 10000, 108766), []error{ErrNorma43DebitCount, ErrNorma43DebitTotal}},
+		{"malformed", strings.Replace(footerRecord(1, 1234, 1, 10000, 108766), "00001", "0000X", 1), []error{ErrNorma43Malformed}},
+	}
+	for _, test := range tests {
+		blocks, err := ImportNorma43(strings.NewReader(norma43File(append(append([]string{header}, movements...), test.footer)...)))
+		if len(blocks) != 2 {
+			t.Errorf("%s: %d blocks, want 2 even when the footer fails", test.name, len(blocks))
+		}
+		if test.errs == nil {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			continue
+		}
+
+		var errs Norma43Errors
+		if !errors.As(err, &errs) || len(errs) != len(test.errs) {
+			t.Errorf("%s: error %v, want %v", test.name, err, test.errs)
+			continue
+		}
+		for i, want := range test.errs {
+			if !errors.Is(errs[i], want) || errs[i].Line != 4 {
+				t.Errorf("%s: error %d is %v, want %v on line 4", test.name, i, errs[i], want)
+			}
+		}
+	}
+}
+
+func TestImportNorma43Structure(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  []string
+		blocks int
+		err    error
+		line   int
+	}{
+		{"missing footer", []string{headerRecord(0), movementRecord("250302", true, 100, "")}, 1, ErrNorma43MissingFooter, 1},
+		{"movement before header", []string{movementRecord("250302", true, 100, "")}, 0, ErrNorma43UnexpectedRecord, 1},
+		{"complementary without movement", []string{headerRecord(0), complementaryRecord("X", ""), footerRecord(0, 0, 0, 0, 0)}, 0, ErrNorma43UnexpectedRecord, 2},
+		{"footer without header", []string{footerRecord(0, 0, 0, 0, 0)}, 0, ErrNorma43UnexpectedRecord, 1},
+		{"unknown record", []string{headerRecord(0), "99", footerRecord(0, 0, 0, 0, 0)}, 0, ErrNorma43UnexpectedRecord, 2},
+		{"bad date", []string{headerRecord(0), movementRecord("251302", true, 100, ""), footerRecord(0, 0, 0, 0, 0)}, 0, ErrNorma43Malformed, 2},
+	}
+	for _, test := range tests {
+		blocks, err := ImportNorma43(strings.NewReader(norma43File(test.lines...)))
+		if len(blocks) != test.blocks {
+			t.Errorf("%s: %d blocks, want %d", test.name, len(blocks), test.blocks)
+		}
+		var errs Norma43Errors
+		if !errors.As(err, &errs) || len(errs) != 1 || !errors.Is(errs[0], test.err) || errs[0].Line != test.line {
+			t.Errorf("%s: error %v, want %v on line %d", test.name, err, test.err, test.line)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package importers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// OFXAccount identifies the account written in an exported statement
+type OFXAccount struct {
+	BankID    string
+	AccountID string
+	Currency  string // ISO 4217, EUR when empty
+}
+
+var ErrNoOFXTransactions = errors.New("no STMTTRN entries found")
+
+// OFXTransactionError describes a STMTTRN entry that could not be turned into
+// a Block.
+type OFXTransactionError struct {
+	FITID string
+	Err   error
+}
+
+func (e OFXTransactionError) Error() string {
+	return fmt.Sprintf("ofx transaction %q: %v", e.FITID, e.Err)
+}
+
+func (e OFXTransactionError) Unwrap() error {
+	return e.Err
+}
+
+type OFXTransactionErrors []OFXTransactionError
+
+func (errs OFXTransactionErrors) Error() string {
+	lines := make([]string, 0, len(errs))
+	for _, e := range errs {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	ofxTagPattern      = regexp.MustCompile(`<(/?)([A-Za-z0-9.]+)>([^<]*)`)
+	ofxCategoryPattern = regexp.MustCompile(`\s*\[cat:([^\]]+)\]\s*$`)
+)
+
+// ofxTransaction holds the leaf values of a STMTTRN aggregate
+type ofxTransaction map[string]string
+
+// ofxStatement holds the transactions of a STMTRS/CCSTMTRS and its ledger balance
+type ofxStatement struct {
+	currency     string
+	transactions []ofxTransaction
+	ledger       map[string]string
+}
+
+// ImportOFXFile reads an OFX or QFX file from disk.
+func ImportOFXFile(path string, categories models.Categories) (models.Blocks, OFXTransactionErrors, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	return ImportOFX(f, categories)
+}
+
+// ImportOFX reads the STMTTRN entries of an OFX 1.x (SGML) or 2.x (XML)
+// document. NAME becomes the Concept, MEMO the Concept2 and FITID the
+// ExternalID. A category written by ExportOFX at the end of the MEMO is
+// resolved against categories by ShortName; an unknown one is logged and
+// left in the MEMO, with the block uncategorized. When the statement has a
+// LEDGERBAL, the running Balance of every block is rebuilt from it.
+//
+// Entries that cannot be parsed are reported by FITID in
+// OFXTransactionErrors and skipped. The balances of a statement with skipped
+// entries are not rebuilt, since the ledger counts the missing amounts. The
+// returned error is only set when the file as a whole is unreadable.
+func ImportOFX(r io.Reader, categories models.Categories) (models.Blocks, OFXTransactionErrors, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statements := parseOFXStatements(text)
+
+	var blocks models.Blocks
+	var trnErrors OFXTransactionErrors
+	found := false
+	for _, statement := range statements {
+		statementBlocks := models.Blocks{}
+		skipped := false
+		for _, trn := range statement.transactions {
+			found = true
+			block, err := ofxTransactionToBlock(trn, statement.currency, categories)
+			if err != nil {
+				trnErrors = append(trnErrors, OFXTransactionError{FITID: trn["FITID"], Err: err})
+				skipped = true
+				continue
+			}
+			statementBlocks.AddBlock(*block)
+		}
+
+		sort.Stable(&statementBlocks)
+		if balance, ok := statement.ledger["BALAMT"]; ok && !skipped {
+			if err := fillBalancesFromLedger(statementBlocks, balance, statement.currency); err != nil {
+				return nil, nil, err
+			}
+		}
+		blocks = append(blocks, statementBlocks...)
+	}
+
+	if !found {
+		return nil, nil, ErrNoOFXTransactions
+	}
+	return blocks, trnErrors, nil
+}
+
+// parseOFXStatements walks the tags of the document. OFX 1.x leaves have no
+// closing tag, but aggregates always do, so the same scan handles both versions.
+func parseOFXStatements(text string) []ofxStatement {
+	var statements []ofxStatement
+	var current *ofxStatement
+	var trn ofxTransaction
+	inLedger := false
+
+	for _, match := range ofxTagPattern.FindAllStringSubmatch(text, -1) {
+		closing, tag, value := match[1] == "/", strings.ToUpper(match[2]), strings.TrimSpace(html.UnescapeString(match[3]))
+
+		switch {
+		case !closing && (tag == "STMTRS" || tag == "CCSTMTRS"):
+			statements = append(statements, ofxStatement{currency: "EUR", ledger: map[string]string{}})
+			current = &statements[len(statements)-1]
+		case !closing && tag == "CURDEF" && current != nil:
+			current.currency = value
+		case !closing && tag == "STMTTRN":
+			trn = ofxTransaction{}
+		case closing && tag == "STMTTRN" && trn != nil:
+			if current == nil {
+				statements = append(statements, ofxStatement{currency: "EUR", ledger: map[string]string{}})
+				current = &statements[len(statements)-1]
+			}
+			current.transactions = append(current.transactions, trn)
+			trn = nil
+		case tag == "LEDGERBAL":
+			inLedger = !closing
+		case !closing && value != "":
+			if trn != nil {
+				trn[tag] = value
+			} else if inLedger && current != nil {
+				current.ledger[tag] = value
+			}
+		}
+	}
+
+	return statements
+}
+
+func ofxTransactionToBlock(trn ofxTransaction, currency string, categories models.Categories) (*models.Block, error) {
+	date, err := parseOFXDate(trn["DTPOSTED"])
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseOFXAmount(trn["TRNAMT"], currency)
+	if err != nil {
+		return nil, err
+	}
+
+	name := trn["NAME"]
+	if name == "" {
+		name = trn["PAYEE"]
+	}
+	memo := trn["MEMO"]
+	if name == "" {
+		name, memo = memo, ""
+	}
+
+	// An unknown category is left in the MEMO instead of inventing one
+	var category models.Category
+	if match := ofxCategoryPattern.FindStringSubmatch(memo); match != nil {
+		if found, ok := categories.Find(match[1]); ok {
+			category = found
+			memo = ofxCategoryPattern.ReplaceAllString(memo, "")
+		} else {
+			log.Warnf("OFX transaction %q: unknown category %q", trn["FITID"], match[1])
+		}
+	}
+
+	// OFX signs debits as negative; Blocks store expenses as positive
+	block := models.NewBlockWithMoney(name, date, memo, amount.Neg(), money.Money{})
+	block.ExternalID = trn["FITID"]
+	block.Category = category
+	return block, nil
+}
+
+// fillBalancesFromLedger walks the sorted blocks backwards from the closing
+// ledger balance.
+func fillBalancesFromLedger(blocks models.Blocks, ledger string, currency string) error {
+	balance, err := parseOFXAmount(ledger, currency)
+	if err != nil {
+		return fmt.Errorf("ofx ledger balance: %w", err)
+	}
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		blocks[i].Balance = balance
+		// Amount is positive for expenses, so undoing it adds it back
+		balance = balance.Add(blocks[i].Amount)
+	}
+	return nil
+}
+
+// parseOFXDate reads the YYYYMMDD prefix of an OFX datetime such as
+// "20250302120000.000[-5:EST]".
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid OFX date %q", s)
+	}
+	t, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid OFX date %q", s)
+	}
+	return t, nil
+}
+
+func parseOFXAmount(s string, currency string) (money.Money, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	if s == "" {
+		return money.Money{}, errors.New("missing amount")
+	}
+	return money.ParseDecimal(s, currency)
+}
+
+// ExportOFX writes blocks as an OFX 2.x bank statement. Blocks without an
+// ExternalID get a FITID derived from their date, amount, concept and position
+// among identical same-day movements, so exporting twice gives the same ids.
+// The category ShortName is appended to the MEMO so ImportOFX can restore it.
+func ExportOFX(w io.Writer, blocks models.Blocks, account OFXAccount) error {
+	currency := account.Currency
+	if currency == "" {
+		currency = "EUR"
+	}
+
+	sorted := make(models.Blocks, len(blocks))
+	copy(sorted, blocks)
+	sort.Stable(&sorted)
+
+	var out strings.Builder
+	now := time.Now().Format("20060102150405")
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n")
+	out.WriteString(`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n")
+	out.WriteString("<OFX>\n<SIGNONMSGSRSV1><SONRS>\n")
+	out.WriteString("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	fmt.Fprintf(&out, "<DTSERVER>%s</DTSERVER><LANGUAGE>SPA</LANGUAGE>\n", now)
+	out.WriteString("</SONRS></SIGNONMSGSRSV1>\n<BANKMSGSRSV1><STMTTRNRS>\n<TRNUID>0</TRNUID>\n")
+	out.WriteString("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n<STMTRS>\n")
+	fmt.Fprintf(&out, "<CURDEF>%s</CURDEF>\n", ofxEscape(currency))
+	fmt.Fprintf(&out, "<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n", ofxEscape(account.BankID), ofxEscape(account.AccountID))
+
+	out.WriteString("<BANKTRANLIST>\n")
+	if len(sorted) > 0 {
+		fmt.Fprintf(&out, "<DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", ofxDate(sorted[0].Date), ofxDate(sorted[len(sorted)-1].Date))
+	}
+
+	seen := map[string]int{}
+	for _, block := range sorted {
+		// Bank sign: credits positive, debits negative
+		amount := block.Amount.Neg()
+		trnType := "DEBIT"
+		if amount.IsPositive() {
+			trnType = "CREDIT"
+		}
+
+		fitid := block.ExternalID
+		if fitid == "" {
+			key := fmt.Sprintf("%s|%s|%s", block.FormatDate(), block.Amount.Decimal(), block.Concept.Name)
+			fitid = ofxFITID(key, seen[key])
+			seen[key]++
+		}
+
+		memo := block.Concept2
+		if block.Category.ShortName != "" {
+			memo = strings.TrimSpace(memo + " [cat:" + block.Category.ShortName + "]")
+		}
+
+		out.WriteString("<STMTTRN>\n")
+		fmt.Fprintf(&out, "<TRNTYPE>%s</TRNTYPE>\n", trnType)
+		fmt.Fprintf(&out, "<DTPOSTED>%s</DTPOSTED>\n", ofxDate(block.Date))
+		fmt.Fprintf(&out, "<TRNAMT>%s</TRNAMT>\n", amount.Decimal())
+		fmt.Fprintf(&out, "<FITID>%s</FITID>\n", ofxEscape(fitid))
+		fmt.Fprintf(&out, "<NAME>%s</NAME>\n", ofxEscape(block.Concept.Name))
+		if memo != "" {
+			fmt.Fprintf(&out, "<MEMO>%s</MEMO>\n", ofxEscape(memo))
+		}
+		out.WriteString("</STMTTRN>\n")
+	}
+	out.WriteString("</BANKTRANLIST>\n")
+
+	if len(sorted) > 0 {
+		last := sorted[len(sorted)-1]
+		if last.HasBalance() {
+			fmt.Fprintf(&out, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", last.Balance.Decimal(), ofxDate(last.Date))
+		}
+	}
+	out.WriteString("</STMTRS>\n</STMTTRNRS></BANKMSGSRSV1>\n</OFX>\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func ofxDate(date time.Time) string {
+	return date.Format("20060102")
+}
+
+func ofxFITID(key string, ordinal int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%d", key, ordinal)))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func ofxEscape(s string) string {
+	return html.EscapeString(s)
+}
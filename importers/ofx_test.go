@@ -0,0 +1,151 @@
+package importers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+// OFX 1.x leaves have no closing tag
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>EUR
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20250302120000.000[-5:EST]<TRNAMT>-12,34<FITID>A1<NAME>MERCADONA &amp; CO<MEMO>COMPRA [cat:food]</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20250301<TRNAMT>1500.00<FITID>A2<MEMO>NOMINA</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20250303<TRNAMT>-5<FITID>A3<NAME>BAR<MEMO>CAFE [cat:nope]</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1482.66<DTASOF>20250303</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>
+`
+
+func TestImportOFX(t *testing.T) {
+	categories := models.Categories{{ShortName: "food"}}
+	blocks, trnErrors, err := ImportOFX(strings.NewReader(sgmlStatement), categories)
+	if err != nil || len(trnErrors) != 0 {
+		t.Fatal(err, trnErrors)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("%d blocks, want 3", len(blocks))
+	}
+
+	// Sorted by date, with the balance rebuilt backwards from LEDGERBAL
+	tests := []struct {
+		id       string
+		concept  string
+		concept2 string
+		category string
+		amount   int64
+		balance  int64
+	}{
+		{"A2", "NOMINA", "", "", -150000, 150000}, // Without NAME the MEMO is the concept
+		{"A1", "MERCADONA & CO", "COMPRA", "food", 1234, 148766},
+		{"A3", "BAR", "CAFE [cat:nope]", "", 500, 148266}, // An unknown category stays in the MEMO
+	}
+	for i, test := range tests {
+		block := blocks[i]
+		if block.ExternalID != test.id || block.Concept.Name != test.concept || block.Concept2 != test.concept2 || block.Category.ShortName != test.category {
+			t.Errorf("block %d: %q %q %q [%s], want %q %q %q [%s]", i, block.ExternalID, block.Concept.Name, block.Concept2, block.Category.ShortName, test.id, test.concept, test.concept2, test.category)
+		}
+		if block.Amount.Minor() != test.amount || block.Balance.Minor() != test.balance || block.Amount.Currency() != "EUR" {
+			t.Errorf("block %d: amount %s balance %s, want %d and %d", i, block.Amount, block.Balance, test.amount, test.balance)
+		}
+	}
+	if blocks[1].FormatDate() != "2025-03-02" {
+		t.Errorf("date %s, want 2025-03-02", blocks[1].FormatDate())
+	}
+}
+
+func TestImportOFXErrors(t *testing.T) {
+	if _, _, err := ImportOFX(strings.NewReader("<OFX></OFX>"), nil); !errors.Is(err, ErrNoOFXTransactions) {
+		t.Errorf("empty statement error = %v, want ErrNoOFXTransactions", err)
+	}
+	for _, trn := range []string{
+		"<STMTTRN><DTPOSTED>2025<TRNAMT>1<FITID>X</STMTTRN>",
+		"<STMTTRN><DTPOSTED>20251302<TRNAMT>1<FITID>X</STMTTRN>",
+		"<STMTTRN><DTPOSTED>20250302<FITID>X</STMTTRN>",
+		"<STMTTRN><DTPOSTED>20250302<TRNAMT>1.234<FITID>X</STMTTRN>",
+	} {
+		blocks, trnErrors, err := ImportOFX(strings.NewReader("<OFX>"+trn+"</OFX>"), nil)
+		if err != nil || len(blocks) != 0 || len(trnErrors) != 1 || trnErrors[0].FITID != "X" {
+			t.Errorf("%s: %d blocks, errors %v, %v, want the entry reported by FITID", trn, len(blocks), trnErrors, err)
+		}
+	}
+}
+
+func TestImportOFXSkipsBadTransactions(t *testing.T) {
+	statement := strings.Replace(sgmlStatement, "<TRNAMT>-5<FITID>A3", "<TRNAMT>-5,5,5<FITID>A3", 1)
+	statement = strings.Replace(statement, "<DTPOSTED>20250301<", "<DTPOSTED>2025-03-01<", 1)
+	blocks, trnErrors, err := ImportOFX(strings.NewReader(statement), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].ExternalID != "A1" {
+		t.Fatalf("%d blocks, want A1 alone", len(blocks))
+	}
+	if len(trnErrors) != 2 || trnErrors[0].FITID != "A2" || trnErrors[1].FITID != "A3" {
+		t.Fatalf("errors %v, want A2 and A3", trnErrors)
+	}
+	if !strings.Contains(trnErrors.Error(), `"A2"`) || errors.Unwrap(trnErrors[0]) == nil {
+		t.Errorf("error %q does not name the FITID or wrap the cause", trnErrors.Error())
+	}
+	// The ledger counts the skipped amounts, so no balance is rebuilt
+	if blocks[0].HasBalance() {
+		t.Errorf("balance %s rebuilt from an incomplete statement", blocks[0].Balance)
+	}
+}
+
+func TestExportOFXRoundTrip(t *testing.T) {
+	day := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+	food := models.Category{ShortName: "food"}
+	blocks := models.Blocks{
+		*models.NewBlockWithMoney("CAFE <&>", day, "", money.New(250, "USD"), money.Money{}),
+		*models.NewBlockWithMoney("CAFE <&>", day, "", money.New(250, "USD"), money.Money{}),
+		*models.NewBlockWithMoney("NOMINA", day.AddDate(0, 0, -1), "MARZO", money.New(-100000, "USD"), money.New(101000, "USD")),
+		*models.NewBlockWithMoney("TIENDA", day, "", money.New(1000, "USD"), money.New(99500, "USD")),
+	}
+	blocks[0].Category = food
+	blocks[3].ExternalID = "BANK-1"
+
+	export := func() models.Blocks {
+		var out strings.Builder
+		if err := ExportOFX(&out, blocks, OFXAccount{BankID: "2100", AccountID: "0200051332", Currency: "USD"}); err != nil {
+			t.Fatal(err)
+		}
+		imported, trnErrors, err := ImportOFX(strings.NewReader(out.String()), models.Categories{food})
+		if err != nil || len(trnErrors) != 0 {
+			t.Fatal(err, trnErrors)
+		}
+		return imported
+	}
+	first, second := export(), export()
+	if len(first) != len(blocks) {
+		t.Fatalf("%d blocks, want %d", len(first), len(blocks))
+	}
+
+	want := models.Blocks{blocks[2], blocks[0], blocks[1], blocks[3]}
+	for i, block := range first {
+		if block.Concept.Name != want[i].Concept.Name || block.Concept2 != want[i].Concept2 || !block.Amount.Equal(want[i].Amount) {
+			t.Errorf("block %d: %q %q %s, want %q %q %s", i, block.Concept.Name, block.Concept2, block.Amount, want[i].Concept.Name, want[i].Concept2, want[i].Amount)
+		}
+		if block.Category.ShortName != want[i].Category.ShortName || block.FormatDate() != want[i].FormatDate() {
+			t.Errorf("block %d: category %q date %s, want %q %s", i, block.Category.ShortName, block.FormatDate(), want[i].Category.ShortName, want[i].FormatDate())
+		}
+		if block.ExternalID != second[i].ExternalID {
+			t.Errorf("block %d: FITID %q then %q, want the same on every export", i, block.ExternalID, second[i].ExternalID)
+		}
+	}
+	// Identical movements of the same day get different FITIDs
+	if first[1].ExternalID == first[2].ExternalID {
+		t.Errorf("identical movements share FITID %q", first[1].ExternalID)
+	}
+	if first[3].ExternalID != "BANK-1" || first[3].Balance.Minor() != 99500 || first[0].Balance.Minor() != 101000 {
+		t.Errorf("last block %q balance %s, first balance %s", first[3].ExternalID, first[3].Balance, first[0].Balance)
+	}
+}
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"txeo-gui-library/money"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+var ErrInvalidIBAN = errors.New("invalid IBAN")
+
+// AccountType is the kind of account a Block belongs to
+type AccountType string
+
+const (
+	AccountChecking AccountType = "checking"
+	AccountCard     AccountType = "card"
+	AccountSavings  AccountType = "savings" // e.g. the hucha
+	AccountCash     AccountType = "cash"
+)
+
+// Account is a bank account, card or savings account. Its ID is what
+// Block.Account holds.
+type Account struct {
+	ID             string
+	Name           string
+	IBAN           string // Without spaces, empty for cards and cash
+	Type           AccountType
+	Currency       string
+	OpeningBalance money.Money
+	OpeningDate    time.Time // Day the opening balance refers to
+}
+type Accounts []Account
+
+// NewAccount validates the IBAN (when given) and derives the ID from it, or
+// from the name when there is no IBAN.
+func NewAccount(name string, iban string, accountType AccountType, openingBalance money.Money, openingDate time.Time) (Account, error) {
+	iban = strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+	if iban != "" {
+		if err := ValidateIBAN(iban); err != nil {
+			return Account{}, err
+		}
+	}
+
+	account := Account{
+		ID:             iban,
+		Name:           name,
+		IBAN:           iban,
+		Type:           accountType,
+		Currency:       openingBalance.Currency(),
+		OpeningBalance: openingBalance,
+		OpeningDate:    Day(openingDate),
+	}
+	if account.ID == "" {
+		account.ID = strings.ReplaceAll(strings.ToLower(NormalizeConcept(name)), " ", "-")
+	}
+	if account.Currency == "" {
+		account.Currency = Locale.Currency
+	}
+	return account, nil
+}
+
+// ValidateIBAN checks the country code, length and the ISO 7064 mod 97
+// check digits of an IBAN without spaces.
+func ValidateIBAN(iban string) error {
+	if len(iban) < 15 || len(iban) > 34 {
+		return fmt.Errorf("%w: %q has %d characters", ErrInvalidIBAN, iban, len(iban))
+	}
+
+	// Move the country and check digits to the end and turn letters into numbers
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&digits, "%d", r-'A'+10)
+		default:
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidIBAN, iban, r)
+		}
+	}
+
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	if new(big.Int).Mod(n, big.NewInt(97)).Int64() != 1 {
+		return fmt.Errorf("%w: %q has wrong check digits", ErrInvalidIBAN, iban)
+	}
+	return nil
+}
+
+func (accounts Accounts) Find(id string) (Account, bool) {
+	for _, account := range accounts {
+		if account.ID == id {
+			return account, true
+		}
+	}
+	return Account{}, false
+}
+
+// Reconcile reconciles the blocks of this account. When the blocks start on
+// or after OpeningDate the first bank balance is checked against the
+// opening balance too.
+func (a Account) Reconcile(blocks Blocks) Reconciliation {
+	own := blocks.ForAccount(a.ID).chronological()
+	if a.OpeningBalance.Currency() == "" || len(own) == 0 || own[0].Date.Before(a.OpeningDate) {
+		return own.Reconcile()
+	}
+
+	opening := Block{Account: a.ID, Concept: Concept{Name: "opening balance"}, Date: a.OpeningDate, Balance: a.OpeningBalance, Amount: money.New(0, a.Currency)}
+	return append(Blocks{opening}, own...).Reconcile()
+}
+
+// GetBalanceStyle styles the balance of a block of this account. Savings
+// accounts always get the savings green; the rest use the balance gradient.
+func (a Account) GetBalanceStyle(b Block) *widget.CustomTextGridStyle {
+	if a.Type == AccountSavings {
+		return savingsBalanceStyle()
+	}
+	return b.GetBalanceStyle()
+}
+
+// ReconcileAccounts reconciles every account on its own, since balances of
+// different accounts cannot follow each other.
+func (accounts Accounts) ReconcileAccounts(blocks Blocks) map[string]Reconciliation {
+	results := map[string]Reconciliation{}
+	for _, account := range accounts {
+		results[account.ID] = account.Reconcile(blocks)
+	}
+	for id, own := range blocks.ByAccount() {
+		if _, known := results[id]; !known {
+			results[id] = own.Reconcile()
+		}
+	}
+	return results
+}
+
+// GetBalanceStyle styles a block balance with the rules of its account
+func (accounts Accounts) GetBalanceStyle(b Block) *widget.CustomTextGridStyle {
+	if account, ok := accounts.Find(b.Account); ok {
+		return account.GetBalanceStyle(b)
+	}
+	return b.GetBalanceStyle()
+}
+
+// IsInternalTransfer reports whether a block moves money between two of
+// these accounts: a leg marked by PairTransfers, or a transfer whose concept
+// names another account or mentions its IBAN.
+func (accounts Accounts) IsInternalTransfer(b Block) bool {
+	if b.IsTransfer() {
+		return true
+	}
+	if b.Concept.Merchant.Operation != OperationTransfer {
+		return false
+	}
+
+	for _, account := range accounts {
+		if account.ID != b.Account && accounts.names(b, account) {
+			return true
+		}
+	}
+	return false
+}
+
+// Consolidate returns the blocks of every account without the internal
+// transfers, for totals and views across accounts.
+func (accounts Accounts) Consolidate(blocks Blocks) Blocks {
+	var consolidated Blocks
+	for _, block := range blocks {
+		if !accounts.IsInternalTransfer(block) {
+			consolidated = append(consolidated, block)
+		}
+	}
+	return consolidated
+}
+
+// ForAccount returns the blocks of one account
+func (b Blocks) ForAccount(id string) Blocks {
+	var own Blocks
+	for i := 0; i < len(b); i++ {
+		if b[i].Account == id {
+			own = append(own, b[i])
+		}
+	}
+	return own
+}
+
+// ByAccount groups the blocks by Block.Account
+func (b Blocks) ByAccount() map[string]Blocks {
+	accounts := map[string]Blocks{}
+	for i := 0; i < len(b); i++ {
+		accounts[b[i].Account] = append(accounts[b[i].Account], b[i])
+	}
+	return accounts
+}
+
+// SetAccount ties every block without an account to the given one, e.g.
+// right after importing a statement
+func (b Blocks) SetAccount(id string) {
+	for i := 0; i < len(b); i++ {
+		if b[i].Account == "" {
+			b[i].Account = id
+		}
+	}
+}
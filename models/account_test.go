@@ -0,0 +1,129 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"txeo-gui-library/money"
+)
+
+func TestNewAccount(t *testing.T) {
+	account, err := NewAccount("Cuenta nómina", "es91 2100 0418 4502 0005 1332", AccountChecking, money.New(100000, "EUR"), mustDay("2025-01-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.ID != "ES9121000418450200051332" || account.IBAN != account.ID || account.Currency != "EUR" {
+		t.Errorf("account %+v", account)
+	}
+
+	cash, err := NewAccount("Caja fuerte", "", AccountCash, money.Money{}, mustDay("2025-01-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cash.ID != "caja-fuerte" || cash.Currency != Locale.Currency {
+		t.Errorf("cash account ID %q currency %q", cash.ID, cash.Currency)
+	}
+
+	for _, iban := range []string{"ES9121000418450200051333", "ES91 2100", "ES91-2100-0418-4502-0005-1332"} {
+		if _, err := NewAccount("Mala", iban, AccountChecking, money.Money{}, mustDay("2025-01-01")); !errors.Is(err, ErrInvalidIBAN) {
+			t.Errorf("IBAN %q error = %v, want ErrInvalidIBAN", iban, err)
+		}
+	}
+	if err := ValidateIBAN("GB82WEST12345698765432"); err != nil {
+		t.Errorf("GB IBAN: %v", err)
+	}
+}
+
+func TestBlocksTiedToAccounts(t *testing.T) {
+	blocks := Blocks{testBlock("SUPER", "2025-03-01", 1000), testBlock("BAR", "2025-03-02", 300)}
+	blocks[1].Account = "card"
+	blocks.SetAccount("main")
+	if blocks[0].Account != "main" || blocks[1].Account != "card" {
+		t.Errorf("SetAccount gave %q and %q, want the card block kept", blocks[0].Account, blocks[1].Account)
+	}
+
+	byAccount := blocks.ByAccount()
+	if len(byAccount) != 2 || len(byAccount["main"]) != 1 || len(blocks.ForAccount("card")) != 1 || len(blocks.ForAccount("other")) != 0 {
+		t.Errorf("ByAccount %v", byAccount)
+	}
+}
+
+func TestAccountReconcileOpeningBalance(t *testing.T) {
+	account := Account{ID: "main", Currency: "EUR", OpeningBalance: money.New(100000, "EUR"), OpeningDate: mustDay("2025-03-01")}
+	good := Blocks{withBalance("SUPER", "2025-03-02", 5000, 95000)}
+	bad := Blocks{withBalance("SUPER", "2025-03-02", 5000, 90000)}
+	good.SetAccount("main")
+	bad.SetAccount("main")
+
+	if result := account.Reconcile(good); !result.OK() || result.Checked != 1 {
+		t.Errorf("checked %d, breaks %v", result.Checked, result.Breaks)
+	}
+	if result := account.Reconcile(bad); len(result.Breaks) != 1 || result.Breaks[0].Missing.Minor() != 5000 {
+		t.Errorf("opening balance not checked: %v", result.Breaks)
+	}
+
+	// Blocks from before the opening date are not checked against it
+	account.OpeningDate = mustDay("2025-03-05")
+	if result := account.Reconcile(bad); !result.OK() || result.Checked != 0 {
+		t.Errorf("checked %d, breaks %v", result.Checked, result.Breaks)
+	}
+}
+
+func TestReconcileAccounts(t *testing.T) {
+	accounts := Accounts{{ID: "main"}, {ID: "savings"}}
+	blocks := Blocks{
+		withBalance("NOMINA", "2025-03-01", -100000, 100000),
+		withBalance("HUCHA", "2025-03-01", -20000, 20000),
+		withBalance("SUPER", "2025-03-02", 5000, 95000),
+		withBalance("BAR", "2025-03-02", 300, 600),
+	}
+	blocks[0].Account, blocks[1].Account, blocks[2].Account, blocks[3].Account = "main", "savings", "main", "card"
+
+	// Mixed together the balances of both accounts would break each other
+	results := accounts.ReconcileAccounts(blocks)
+	if len(results) != 3 {
+		t.Fatalf("%d results, want main, savings and the unknown card", len(results))
+	}
+	for id, result := range results {
+		if !result.OK() {
+			t.Errorf("%s: breaks %v", id, result.Breaks)
+		}
+	}
+	if results["main"].Checked != 1 {
+		t.Errorf("main checked %d", results["main"].Checked)
+	}
+}
+
+func TestInternalTransfers(t *testing.T) {
+	accounts := Accounts{
+		{ID: "main", Name: "Nomina"},
+		{ID: "ES9121000418450200051332", Name: "Hucha", IBAN: "ES9121000418450200051332"},
+	}
+	byName := testBlock("TRANSFERENCIA A HUCHA", "2025-03-01", 5000)
+	byIBAN := testBlock("TRANSFERENCIA", "2025-03-01", 5000)
+	byIBAN.Concept2 = "ES91 2100 0418 4502 0005 1332"
+	external := testBlock("TRANSFERENCIA A JUAN", "2025-03-01", 5000)
+	notTransfer := testBlock("HUCHA DE CERAMICA", "2025-03-01", 1500)
+	for _, b := range []*Block{&byName, &byIBAN, &external, &notTransfer} {
+		b.Account = "main"
+	}
+	paired := testBlock("BIZUM", "2025-03-01", 100)
+	paired.TransferID = "T1"
+
+	if !accounts.IsInternalTransfer(byName) || !accounts.IsInternalTransfer(byIBAN) || !accounts.IsInternalTransfer(paired) {
+		t.Errorf("transfers to own accounts not found")
+	}
+	if accounts.IsInternalTransfer(external) || accounts.IsInternalTransfer(notTransfer) {
+		t.Errorf("external transfer or purchase taken as internal")
+	}
+
+	// A transfer that names its own account is not internal
+	self := byName
+	self.Account = "ES9121000418450200051332"
+	if accounts.IsInternalTransfer(self) {
+		t.Errorf("a transfer naming its own account is internal")
+	}
+
+	if consolidated := accounts.Consolidate(Blocks{byName, external, notTransfer, paired}); len(consolidated) != 2 {
+		t.Errorf("consolidated %d blocks, want the external transfer and the purchase", len(consolidated))
+	}
+}
@@ -1,11 +1,13 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"image/color"
-	"strconv"
 	"strings"
 	"time"
+	"txeo-gui-library/money"
 	"txeo-gui-library/styles"
 
 	"fyne.io/fyne/v2/widget"
@@ -14,24 +16,71 @@ import (
 )
 
 type Block struct {
+	ID              string // Fingerprint, see Blocks.AssignIDs
+	Account         string // Account.ID
+	TransferID      string // Shared by both legs of an internal transfer, see Accounts.PairTransfers
 	Concept         Concept
 	ConceptAsString string
-	Date            string
+	Date            time.Time // Operation (booking) date
+	ValueDate       time.Time // Zero when the bank does not give it
 	Concept2        string
-	Amount          float64
-	Balance         string
+	Amount          money.Money // Positive for expenses, negative for income
+	Balance         money.Money // Zero value (no currency) when the bank gave none
 	Category        Category
+	Splits          Splits // Lines sharing Amount among several categories, see SetSplits
+	ExternalID      string // Identifier given by the bank, e.g. the OFX FITID
+	Notes           string
 }
 type Blocks []Block
 
+// Locale used to read Balance strings and to print amounts
+var Locale = money.EsES
+
+// NewBlock leaves unparseable balances empty and unparseable dates zero,
+// logging a warning. Use ParseBlock to get the error instead.
 func (b *Block) NewBlock(conceptAsString string, date string, concept2 string, amount float64, balance string) *Block {
 
+	// Unparseable balances are left empty, as before
+	parsedBalance, _ := money.ParseMoney(balance, Locale)
+
+	parsedDate, err := ParseDateOrder(date, DayFirst)
+	if err != nil {
+		log.Warnf("NewBlock %q: %v", conceptAsString, err)
+	}
+	return NewBlockWithMoney(conceptAsString, parsedDate, concept2, money.FromFloat(amount, Locale.Currency), parsedBalance)
+}
+
+// ParseBlock builds a block from the text of a bank export. The date is read
+// day first with ParseDateOrder and the amount and balance with money.ParseMoney in locale,
+// keeping their sign. An empty balance is left empty; any other field that
+// cannot be read is an error.
+func ParseBlock(conceptAsString string, date string, concept2 string, amount string, balance string, locale money.Locale) (*Block, error) {
+	parsedDate, err := ParseDateOrder(date, DayFirst)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedAmount, err := money.ParseMoney(amount, locale)
+	if err != nil {
+		return nil, fmt.Errorf("amount: %w", err)
+	}
+
+	var parsedBalance money.Money
+	if strings.TrimSpace(balance) != "" {
+		if parsedBalance, err = money.ParseMoney(balance, locale); err != nil {
+			return nil, fmt.Errorf("balance: %w", err)
+		}
+	}
+	return NewBlockWithMoney(conceptAsString, parsedDate, concept2, parsedAmount, parsedBalance), nil
+}
+func NewBlockWithMoney(conceptAsString string, date time.Time, concept2 string, amount money.Money, balance money.Money) *Block {
+
 	concept := NewConceptFromString(conceptAsString)
 	return &Block{Concept: concept, Date: date, Concept2: concept2, Amount: amount, Balance: balance}
 }
 func (b *Blocks) Len() int           { return len(*b) }
 func (b *Blocks) Swap(i, j int)      { (*b)[i], (*b)[j] = (*b)[j], (*b)[i] }
-func (b *Blocks) Less(i, j int) bool { return (*b)[i].Date < (*b)[j].Date }
+func (b *Blocks) Less(i, j int) bool { return (*b)[i].Date.Before((*b)[j].Date) }
 
 func (b Block) PrintInfo() {
 	log.Infof("Object: %#v", b)
@@ -43,32 +92,35 @@ func (b Block) GetBackgroundGlobalStyle() *widget.CustomTextGridStyle {
 	withdrawalStyle := &widget.CustomTextGridStyle{BGColor: &color.NRGBA{R: 183, G: 28, B: 28, A: 128}} // Dark red for withdrawals
 	incomeStyle := &widget.CustomTextGridStyle{BGColor: &color.NRGBA{R: 76, G: 175, B: 80, A: 128}}     // Light green for income
 
-	amountStyle := styles.GetStyleForAmount(b.Amount)
+	amountStyle := styles.GetStyleForMoney(b.Amount)
 
-	// Check if this is a savings category (including HUCHA transfers)
-	if b.Category.Subcategory == "savings" || b.Category.Subcategory == "HUCHA_SAVE" {
+	// Split blocks are styled by their largest line
+	category := b.PrimaryCategory()
+
+	// Check if this is a savings category or money sent to another own account
+	if category.Semantics() == CategorySavings || (b.IsTransfer() && b.Amount.IsPositive()) {
 		return savingsStyle
 	}
 
-	// Check if this is a withdrawal category (retiro de hucha or HUCHA_TAKE)
-	if b.Category.Subcategory == "withdrawal" || b.Category.Subcategory == "HUCHA_TAKE" {
+	// Check if this is a withdrawal category or money taken from another own account
+	if category.Semantics() == CategoryWithdrawal || (b.IsTransfer() && b.Amount.IsNegative()) {
 		return withdrawalStyle
 	}
 
 	// Check if this is an income category
-	if b.Category.Subcategory == "income" {
+	if category.Semantics() == CategoryIncome {
 		return incomeStyle
 	}
 
 	var returnedStyle *widget.CustomTextGridStyle
-	if b.Amount > 0 {
+	if b.Amount.IsPositive() {
 		returnedStyle = amountStyle
 	} else {
 		returnedStyle = greenStyle
 	}
 
 	// If category is nil, return redStyle
-	if b.Category.Name == "" {
+	if category.Name == "" {
 		return redStyle
 	}
 
@@ -76,35 +128,42 @@ func (b Block) GetBackgroundGlobalStyle() *widget.CustomTextGridStyle {
 }
 func (b Block) GetDateStyle(blocks Blocks) *widget.CustomTextGridStyle {
 
-	// Calculate net spending for the day (expenses minus income)
-	dayExpenses := 0.0
-	dayIncome := 0.0
+	// Calculate net spending for the day (expenses minus income), in the
+	// currency of this block since other currencies cannot be added
+	dayExpenses := money.New(0, b.Amount.Currency())
+	dayIncome := money.New(0, b.Amount.Currency())
 
 	for i := 0; i < len(blocks); i++ {
-		if blocks[i].Date == b.Date {
-			// Check if this is income, savings, or expense
-			subcategory := blocks[i].Category.Subcategory
-			switch subcategory {
-			case "income":
-				dayIncome += blocks[i].Amount
-			case "savings", "HUCHA_SAVE":
-				// Savings are treated as neutral or slightly positive
-				// Don't add to expenses
-			case "HUCHA_TAKE":
-				// Taking from savings is an expense
-				dayExpenses += blocks[i].Amount
-			default:
-				// Regular expenses
-				dayExpenses += blocks[i].Amount
+		if SameDay(blocks[i].Date, b.Date) {
+			// Internal transfers are neither expense nor income
+			if blocks[i].IsTransfer() {
+				continue
+			}
+
+			// Check if every line is income, savings, or expense
+			for _, part := range blocks[i].Parts() {
+				if !part.Amount.SameCurrency(dayExpenses) {
+					continue
+				}
+				switch part.Category.Semantics() {
+				case CategoryIncome:
+					dayIncome = dayIncome.Add(part.Amount)
+				case CategorySavings, CategoryTransfer:
+					// Savings and transfers are treated as neutral
+					// Don't add to expenses
+				default:
+					// Regular expenses
+					dayExpenses = dayExpenses.Add(part.Amount)
+				}
 			}
 		}
 	}
 
 	// Calculate net amount (negative means more income than expenses = good)
-	netAmount := dayExpenses - dayIncome
+	netAmount := dayExpenses.Sub(dayIncome)
 
 	// If we have more income than expenses, use green colors
-	if dayIncome > dayExpenses {
+	if dayIncome.GreaterThan(dayExpenses) {
 		return &widget.CustomTextGridStyle{
 			FGColor: &color.NRGBA{R: 255, G: 255, B: 255, A: 255}, // White text
 			BGColor: &color.NRGBA{R: 0, G: 150, B: 0, A: 255},     // Green background for net positive days
@@ -112,69 +171,94 @@ func (b Block) GetDateStyle(blocks Blocks) *widget.CustomTextGridStyle {
 	}
 
 	// Otherwise use the gradient based on net spending
-	oneDateStyle := styles.GetStyleForAmount(netAmount)
+	oneDateStyle := styles.GetStyleForMoney(netAmount)
 	return oneDateStyle
 }
 func (b Block) GetAmountStyle() *widget.CustomTextGridStyle {
+	category := b.PrimaryCategory()
+
 	// For income transactions, use green color
-	if b.Category.Subcategory == "income" {
+	if category.Semantics() == CategoryIncome {
 		return &widget.CustomTextGridStyle{
 			FGColor: &color.NRGBA{R: 0, G: 150, B: 0, A: 255}, // Dark green for income amounts
 		}
 	}
 
-	// For savings/HUCHA transactions, use a different green
-	if b.Category.Subcategory == "savings" || b.Category.Subcategory == "HUCHA_SAVE" {
+	// For savings and transfers to other own accounts, use a different green
+	if category.Semantics() == CategorySavings || (b.IsTransfer() && b.Amount.IsPositive()) {
 		return &widget.CustomTextGridStyle{
 			FGColor: &color.NRGBA{R: 46, G: 125, B: 50, A: 255}, // Forest green for savings
 		}
 	}
 
 	// For withdrawals, use the standard gradient (will be red for high amounts)
-	return styles.GetStyleForAmount(b.Amount)
+	return styles.GetStyleForMoney(b.Amount)
 }
 func (b Block) GetBalanceStyle() *widget.CustomTextGridStyle {
+	category := b.PrimaryCategory()
+
 	// For income transactions, always use green for balance
-	if b.Category.Subcategory == "income" {
+	if category.Semantics() == CategoryIncome {
 		return &widget.CustomTextGridStyle{
 			FGColor: &color.NRGBA{R: 0, G: 150, B: 0, A: 255}, // Dark green for income balance
 		}
 	}
 
-	// For savings/HUCHA transactions, use green for balance
-	if b.Category.Subcategory == "savings" || b.Category.Subcategory == "HUCHA_SAVE" {
-		return &widget.CustomTextGridStyle{
-			FGColor: &color.NRGBA{R: 46, G: 125, B: 50, A: 255}, // Forest green for savings balance
-		}
+	// For savings and transfers to other own accounts, use green for balance
+	if category.Semantics() == CategorySavings || (b.IsTransfer() && b.Amount.IsPositive()) {
+		return savingsBalanceStyle()
 	}
 
 	// For regular transactions, use the standard gradient based on balance
-	return styles.GetStyleForBalance(b.GetBalanceAsFloat())
+	return styles.GetStyleForBalanceMoney(b.Balance)
+}
+func savingsBalanceStyle() *widget.CustomTextGridStyle {
+	return &widget.CustomTextGridStyle{
+		FGColor: &color.NRGBA{R: 46, G: 125, B: 50, A: 255}, // Forest green for savings balance
+	}
 }
 func (b Block) GetConceptStyle() *widget.CustomTextGridStyle {
 	return styles.GetStyleForAmount(0)
 }
 func (b Block) GetBalanceAsFloat() float64 {
-	balance, err := ParseBalanceString(b.Balance)
-	if err != nil {
-		return 0
-	}
-	return balance
+	return b.Balance.Float()
+}
+func (b Block) HasBalance() bool {
+	return b.Balance.Currency() != ""
 }
 func (b Block) Println() {
 
 	fmt.Print("\n--------------------------------------------------------------------------------------------------------------------\n")
-	log.Infof("  📅  %-12s %s %-15s ✏️ [%20s  ] 💵 Amount: %4.2f 💵 Balance: %9s", aurora.BrightWhite(b.Date), b.Category.Icon, aurora.BrightYellow(b.Category.ShortName), aurora.BrightWhite(b.Concept.Name), aurora.BrightRed(b.Amount), aurora.BrightGreen(b.Balance))
+	log.Infof("  📅  %-12s %s %-15s ✏️ [%20s  ] 💵 Amount: %12s 💵 Balance: %12s", aurora.BrightWhite(b.FormatDate()), b.Category.Icon, aurora.BrightYellow(b.Category.ShortName), aurora.BrightWhite(b.Concept.Name), aurora.BrightRed(b.FormatAmount()), aurora.BrightGreen(b.FormatBalance()))
+	b.printSplits()
 	fmt.Print("--------------------------------------------------------------------------------------------------------------------\n")
 }
 func (b Block) PrintlnForClick(row int, direction string) {
 
 	fmt.Print("\n---------------------------------------------------------------------------------------------------------------------------------------------\n")
-	log.Infof("   🮰 %5s-clicked row: %d -->  📅  %-12s %s %-15s ✏️ [%20s  ] 💵 Amount: %4.2f 💵 Balance: %9s", strings.ToTitle(direction), row, aurora.BrightWhite(b.Date), b.Category.Icon, aurora.BrightYellow(b.Category.ShortName), aurora.BrightWhite(b.Concept.Name), aurora.BrightRed(b.Amount), aurora.BrightGreen(b.Balance))
+	log.Infof("   🮰 %5s-clicked row: %d -->  📅  %-12s %s %-15s ✏️ [%20s  ] 💵 Amount: %12s 💵 Balance: %12s", strings.ToTitle(direction), row, aurora.BrightWhite(b.FormatDate()), b.Category.Icon, aurora.BrightYellow(b.Category.ShortName), aurora.BrightWhite(b.Concept.Name), aurora.BrightRed(b.FormatAmount()), aurora.BrightGreen(b.FormatBalance()))
+	b.printSplits()
 	fmt.Print("---------------------------------------------------------------------------------------------------------------------------------------------\n")
 }
+func (b Block) printSplits() {
+	for _, split := range b.Splits {
+		log.Infof("      ↳ %s %-15s %-24s 💵 Amount: %12s", split.Category.Icon, aurora.BrightYellow(split.Category.ShortName), split.Note, aurora.BrightRed(split.Amount.Format(Locale)))
+	}
+}
+func (b Block) FormatDate() string {
+	return b.Date.Format(DateLayout)
+}
+func (b Block) FormatAmount() string {
+	return b.Amount.Format(Locale)
+}
+func (b Block) FormatBalance() string {
+	if !b.HasBalance() {
+		return ""
+	}
+	return b.Balance.Format(Locale)
+}
 func (b Block) GetAmountAsFloat() float64 {
-	return b.Amount
+	return b.Amount.Float()
 }
 func (b *Blocks) AddBlock(block Block) {
 
@@ -185,13 +269,8 @@ func (b *Block) AssignCategoryForBlock(categories Categories) {
 	// Get Unknown Category
 	var category Category
 
-	// Special case: TRANSFER.HUCHA DIGI should never auto-categorize
-	if b.Concept.Name == "TRANSFER.HUCHA DIGI" {
-		// Leave category empty to force manual categorization
-		b.Category = category
-		return
-	}
-
+	// Rules in DefaultRules may leave the category empty to force manual
+	// categorization (e.g. TRANSFER.HUCHA DIGI)
 	category = category.TryToAssignCategory(*b, categories)
 
 	// Try to assign a category to the block
@@ -207,51 +286,98 @@ func (b *Block) GetCategory() Category {
 /* │                  BLOCKS                  │ */
 /* ╰──────────────────────────────────────────╯ */
 func (b Blocks) GetAmountAsFloat() float64 {
+	return b.GetTotalAmount().Float()
+}
 
-	totalAmount := 0.0
-	for i := 0; i < len(b); i++ {
-		totalAmount += b[i].Amount
+// GetTotalAmount sums the amounts, skipping internal transfers. Amounts in
+// different currencies cannot be added: when the blocks have several, only
+// the ones in the currency of Locale are. Use Total or TotalsByCurrency to
+// see the others.
+func (b Blocks) GetTotalAmount() money.Money {
+
+	totals := b.TotalsByCurrency()
+	if total, err := totals.Single(); err == nil {
+		return total
 	}
-	return totalAmount
+	return totals.In(Locale.Currency)
 }
-func ParseBalanceString(balanceStr string) (float64, error) {
-	// Quitar espacios al principio y al final
-	s := strings.TrimSpace(balanceStr)
-
-	// Eliminar el símbolo de euro si existe al final
-	s = strings.TrimSuffix(s, "€")
-	s = strings.TrimSpace(s) // Quitar espacios que pudieran quedar
-
-	// Revisar el signo
-	sign := 1.0
-	if strings.HasPrefix(s, "-") {
-		sign = -1.0
-		s = s[1:] // Quitar el signo
-	} else if strings.HasPrefix(s, "+") {
-		s = s[1:] // Quitar el signo '+'
-	}
 
-	s = strings.TrimSpace(s)
+// Total sums the amounts, skipping internal transfers, or returns
+// money.ErrCurrencyMismatch when they have different currencies
+func (b Blocks) Total() (money.Money, error) {
+	return b.TotalsByCurrency().Single()
+}
 
-	// Reemplazar coma por punto (asumiendo formato español)
-	s = strings.ReplaceAll(s, ",", ".")
+// TotalsByCurrency sums the amounts of every currency, skipping internal
+// transfers
+func (b Blocks) TotalsByCurrency() money.Totals {
 
-	// Ahora s debería ser un número válido en formato inglés, ej. "100.96" o "20.15"
-	value, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return 0, err
+	totals := money.Totals{}
+	for i := 0; i < len(b); i++ {
+		if b[i].IsTransfer() {
+			continue
+		}
+		totals.Add(b[i].Amount)
 	}
+	return totals
+}
+
+// AssignIDs gives every block without an ID a fingerprint derived from its
+// account, date, amount and concept. Identical movements on the same day
+// (twins) are told apart by their order in the slice, so importing the same
+// statement twice yields the same IDs.
+func (b Blocks) AssignIDs() {
 
-	return sign * value, nil
+	seen := map[string]int{}
+	for i := 0; i < len(b); i++ {
+		key := b[i].fingerprintKey()
+		ordinal := seen[key]
+		seen[key]++
+		if b[i].ID == "" {
+			b[i].ID = Fingerprint(key, ordinal)
+		}
+	}
+}
+func (b Block) fingerprintKey() string {
+	concept := strings.ToUpper(strings.Join(strings.Fields(b.Concept.Name), " "))
+	return strings.Join([]string{b.Account, b.FormatDate(), b.Amount.Decimal(), b.Amount.Currency(), concept}, "|")
+}
+func Fingerprint(key string, ordinal int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, ordinal)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ParseBalanceString reads a CaixaBank balance such as "1.234,56 €".
+//
+// Deprecated: use money.Parse with the locale of the source.
+func ParseBalanceString(balanceStr string) (float64, error) {
+	return money.Parse(balanceStr, money.EsES)
 }
 
+// GetTotalAmountForDay sums the blocks of the given day of startingDate's month
 func (b Blocks) GetTotalAmountForDay(startingDate time.Time, day int) float64 {
 
-	totalAmount := 0.0
+	date := time.Date(startingDate.Year(), startingDate.Month(), day, 0, 0, 0, 0, time.UTC)
+	return b.GetBlocksForDay(date).GetTotalAmount().Float()
+}
+func (b Blocks) GetBlocksForDay(date time.Time) Blocks {
+
+	var dayBlocks Blocks
 	for i := 0; i < len(b); i++ {
-		if b[i].Date == startingDate.Format("2006-01-02") {
-			totalAmount += b[i].Amount
+		if SameDay(b[i].Date, date) {
+			dayBlocks = append(dayBlocks, b[i])
 		}
 	}
-	return totalAmount
+	return dayBlocks
+}
+
+// GroupByDay returns the blocks of every day, keyed by Day(date)
+func (b Blocks) GroupByDay() map[time.Time]Blocks {
+
+	days := map[time.Time]Blocks{}
+	for i := 0; i < len(b); i++ {
+		day := Day(b[i].Date)
+		days[day] = append(days[day], b[i])
+	}
+	return days
 }
@@ -0,0 +1,257 @@
+package models
+
+import (
+	"image/color"
+	"time"
+	"txeo-gui-library/money"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// BudgetPeriod is the span a budget limit applies to
+type BudgetPeriod string
+
+const (
+	BudgetWeekly    BudgetPeriod = "weekly"
+	BudgetMonthly   BudgetPeriod = "monthly"
+	BudgetQuarterly BudgetPeriod = "quarterly"
+	BudgetYearly    BudgetPeriod = "yearly"
+)
+
+// RolloverPolicy tells what happens with the difference between the limit
+// and the spending of a period
+type RolloverPolicy string
+
+const (
+	RolloverNone       RolloverPolicy = "none"       // Every period starts from the limit
+	RolloverUnderspend RolloverPolicy = "underspend" // Unused money adds to the next period
+	RolloverOverspend  RolloverPolicy = "overspend"  // Overspending is taken from the next period
+	RolloverBoth       RolloverPolicy = "both"
+)
+
+// BudgetStatus is what widgets colour category rows and calendar days with
+type BudgetStatus string
+
+const (
+	BudgetOK      BudgetStatus = "ok"
+	BudgetWarning BudgetStatus = "warning" // Spent most of the limit, or on track to exceed it
+	BudgetOver    BudgetStatus = "over"
+)
+
+// Share of the limit from which a budget is in warning
+const budgetWarningShare = 0.8
+
+// Budget limits the spending of a category per period
+type Budget struct {
+	ID                int
+	CategoryShortName string
+	Period            BudgetPeriod
+	Limit             money.Money
+	Rollover          RolloverPolicy
+	Start             time.Time // First period counted for rollover; zero starts with the first block
+}
+type Budgets []Budget
+
+// BudgetEvaluation is a budget applied to the period containing a day
+type BudgetEvaluation struct {
+	Budget    Budget
+	From      time.Time   // First day of the period
+	To        time.Time   // First day of the next period
+	Carried   money.Money // Rollover from the previous periods, negative for overspending
+	Limit     money.Money // Budget limit plus Carried
+	Spent     money.Money // Expenses minus refunds, without internal transfers
+	Remaining money.Money
+	Projected money.Money // Spending at the end of the period at the current pace
+	Status    BudgetStatus
+}
+
+// PeriodBounds returns the period containing day, weeks starting on Monday
+func (budget Budget) PeriodBounds(day time.Time) (time.Time, time.Time) {
+	day = Day(day)
+	switch budget.Period {
+	case BudgetWeekly:
+		from := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return from, from.AddDate(0, 0, 7)
+	case BudgetQuarterly:
+		from := time.Date(day.Year(), (day.Month()-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 3, 0)
+	case BudgetYearly:
+		from := time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, 0)
+	default:
+		from := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 1, 0)
+	}
+}
+
+// Evaluate applies the budget to the period containing asOf. The spending of
+// the descendants of the category in categories counts too, as
+// Categories.Totals rolls it up; nil categories count only the category
+// itself. Blocks after asOf are not counted.
+func (budget Budget) Evaluate(blocks Blocks, categories Categories, asOf time.Time) BudgetEvaluation {
+	asOf = Day(asOf)
+	own := budget.own(blocks, categories)
+	from, to := budget.PeriodBounds(asOf)
+
+	evaluation := BudgetEvaluation{Budget: budget, From: from, To: to, Carried: money.New(0, budget.Limit.Currency())}
+	if budget.Rollover != "" && budget.Rollover != RolloverNone {
+		evaluation.Carried = budget.carried(own, from)
+	}
+	evaluation.Limit = budget.Limit.Add(evaluation.Carried)
+	evaluation.Spent = budget.spent(own, from, asOf.AddDate(0, 0, 1))
+	evaluation.Remaining = evaluation.Limit.Sub(evaluation.Spent)
+
+	// Linear projection over the days of the period
+	elapsed := int64(asOf.Sub(from).Hours()/24) + 1
+	total := int64(to.Sub(from).Hours() / 24)
+	evaluation.Projected = evaluation.Spent.MultiplyRat(total, elapsed, money.RoundHalfUp)
+
+	switch {
+	case evaluation.Spent.GreaterThan(evaluation.Limit):
+		evaluation.Status = BudgetOver
+	case evaluation.Projected.GreaterThan(evaluation.Limit) ||
+		evaluation.Spent.Float() >= evaluation.Limit.Float()*budgetWarningShare:
+		evaluation.Status = BudgetWarning
+	default:
+		evaluation.Status = BudgetOK
+	}
+	return evaluation
+}
+
+// carried adds up the rollover of the periods before the one starting at
+// current
+func (budget Budget) carried(own Blocks, current time.Time) money.Money {
+	carried := money.New(0, budget.Limit.Currency())
+
+	start := budget.Start
+	if start.IsZero() {
+		for i := range own {
+			if start.IsZero() || own[i].Date.Before(start) {
+				start = own[i].Date
+			}
+		}
+	}
+	if start.IsZero() {
+		return carried
+	}
+
+	for from, to := budget.PeriodBounds(start); from.Before(current); from, to = to, budget.nextPeriodEnd(to) {
+		left := budget.Limit.Add(carried).Sub(budget.spent(own, from, to))
+		switch {
+		case left.IsPositive() && (budget.Rollover == RolloverUnderspend || budget.Rollover == RolloverBoth):
+			carried = left
+		case left.IsNegative() && (budget.Rollover == RolloverOverspend || budget.Rollover == RolloverBoth):
+			carried = left
+		default:
+			carried = money.New(0, budget.Limit.Currency())
+		}
+	}
+	return carried
+}
+
+func (budget Budget) nextPeriodEnd(from time.Time) time.Time {
+	_, to := budget.PeriodBounds(from)
+	return to
+}
+
+func (budget Budget) spent(own Blocks, from time.Time, to time.Time) money.Money {
+	var period Blocks
+	for i := range own {
+		if !own[i].Date.Before(from) && own[i].Date.Before(to) {
+			period = append(period, own[i])
+		}
+	}
+	// Movements in other currencies than the limit cannot count towards it
+	if budget.Limit.Currency() == "" {
+		return period.GetTotalAmount()
+	}
+	return period.TotalsByCurrency().In(budget.Limit.Currency())
+}
+
+// own returns the blocks of the budget category and of its descendants in
+// categories; split blocks only count with the amount of their lines in them
+func (budget Budget) own(b Blocks, categories Categories) Blocks {
+	var own Blocks
+	for i := range b {
+		for _, part := range b[i].Parts() {
+			if budget.covers(part.Category.ShortName, categories) {
+				own = append(own, b[i].asPart(part))
+			}
+		}
+	}
+	return own
+}
+
+func (budget Budget) covers(shortName string, categories Categories) bool {
+	return shortName == budget.CategoryShortName || contains(categories.Ancestors(shortName), budget.CategoryShortName)
+}
+
+// Evaluate applies every budget to the period containing asOf, see
+// Budget.Evaluate
+func (budgets Budgets) Evaluate(blocks Blocks, categories Categories, asOf time.Time) []BudgetEvaluation {
+	evaluations := make([]BudgetEvaluation, 0, len(budgets))
+	for _, budget := range budgets {
+		evaluations = append(evaluations, budget.Evaluate(blocks, categories, asOf))
+	}
+	return evaluations
+}
+
+// ForCategory returns the budgets of a category
+func (budgets Budgets) ForCategory(shortName string) Budgets {
+	var own Budgets
+	for _, budget := range budgets {
+		if budget.CategoryShortName == shortName {
+			own = append(own, budget)
+		}
+	}
+	return own
+}
+
+// StatusForCategory is the worst status of the category budgets as of a
+// day, or BudgetOK when it has none
+func (budgets Budgets) StatusForCategory(shortName string, blocks Blocks, categories Categories, asOf time.Time) BudgetStatus {
+	return worstStatus(budgets.ForCategory(shortName).Evaluate(blocks, categories, asOf))
+}
+
+// StatusForDay is the worst status, as of that day, of the budgets whose
+// category has spending on that day
+func (budgets Budgets) StatusForDay(blocks Blocks, categories Categories, day time.Time) BudgetStatus {
+	var evaluations []BudgetEvaluation
+	dayBlocks := blocks.GetBlocksForDay(day)
+	for _, budget := range budgets {
+		if len(budget.own(dayBlocks, categories)) > 0 {
+			evaluations = append(evaluations, budget.Evaluate(blocks, categories, day))
+		}
+	}
+	return worstStatus(evaluations)
+}
+
+func worstStatus(evaluations []BudgetEvaluation) BudgetStatus {
+	status := BudgetOK
+	for _, evaluation := range evaluations {
+		switch {
+		case evaluation.Status == BudgetOver:
+			return BudgetOver
+		case evaluation.Status == BudgetWarning:
+			status = BudgetWarning
+		}
+	}
+	return status
+}
+
+// Color returns the colour widgets paint a status with
+func (status BudgetStatus) Color() color.NRGBA {
+	switch status {
+	case BudgetOver:
+		return color.NRGBA{R: 192, G: 64, B: 64, A: 200}
+	case BudgetWarning:
+		return color.NRGBA{R: 230, G: 160, B: 30, A: 200}
+	default:
+		return color.NRGBA{R: 64, G: 192, B: 64, A: 128}
+	}
+}
+
+func (status BudgetStatus) GetStyle() *widget.CustomTextGridStyle {
+	bgColor := status.Color()
+	return &widget.CustomTextGridStyle{FGColor: &color.NRGBA{R: 255, G: 255, B: 255, A: 255}, BGColor: &bgColor}
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"testing"
+	"txeo-gui-library/money"
+)
+
+func categorized(concept string, date string, minor int64, category string) Block {
+	block := testBlock(concept, date, minor)
+	block.Category = Category{ShortName: category}
+	return block
+}
+
+func TestBudgetPeriodBounds(t *testing.T) {
+	tests := []struct {
+		period BudgetPeriod
+		day    string
+		from   string
+		to     string
+	}{
+		{BudgetWeekly, "2025-03-05", "2025-03-03", "2025-03-10"}, // Weeks start on Monday
+		{BudgetWeekly, "2025-03-09", "2025-03-03", "2025-03-10"},
+		{BudgetMonthly, "2025-02-14", "2025-02-01", "2025-03-01"},
+		{BudgetQuarterly, "2025-05-15", "2025-04-01", "2025-07-01"},
+		{BudgetYearly, "2025-05-15", "2025-01-01", "2026-01-01"},
+		{"", "2025-12-31", "2025-12-01", "2026-01-01"},
+	}
+	for _, test := range tests {
+		from, to := Budget{Period: test.period}.PeriodBounds(mustDay(test.day))
+		if from.Format(DateLayout) != test.from || to.Format(DateLayout) != test.to {
+			t.Errorf("%s period of %s: %s..%s, want %s..%s", test.period, test.day, from.Format(DateLayout), to.Format(DateLayout), test.from, test.to)
+		}
+	}
+}
+
+func TestBudgetEvaluate(t *testing.T) {
+	categories := Categories{{ShortName: "food"}, {ShortName: "bakery", ParentShortName: "food"}, {ShortName: "fun"}}
+	transfer := categorized("HUCHA", "2025-03-03", 9000, "food")
+	transfer.TransferID = "T1"
+	dollars := *NewBlockWithMoney("DUTY FREE", mustDay("2025-03-04"), "", money.New(9000, "USD"), money.Money{})
+	dollars.Category = Category{ShortName: "food"}
+	blocks := Blocks{
+		categorized("SUPER", "2025-03-02", 3000, "food"),
+		categorized("PAN", "2025-03-05", 1000, "bakery"),
+		categorized("CINE", "2025-03-06", 5000, "fun"),
+		transfer, // Internal transfers are not spending
+		dollars,  // Nor movements in another currency
+		categorized("SUPER", "2025-03-20", 2000, "food"),
+		categorized("DEVOLUCION", "2025-03-25", -500, "food"),
+	}
+	budget := Budget{CategoryShortName: "food", Period: BudgetMonthly, Limit: money.New(10000, "EUR")}
+
+	tests := []struct {
+		asOf       string
+		categories Categories
+		spent      int64
+		projected  int64
+		status     BudgetStatus
+	}{
+		{"2025-03-10", nil, 3000, 9300, BudgetOK},              // Without the tree only food itself counts
+		{"2025-03-10", categories, 4000, 12400, BudgetWarning}, // On track to exceed it
+		{"2025-03-31", categories, 5500, 5500, BudgetOK},       // Refunds lower the spending
+		{"2025-04-01", categories, 0, 0, BudgetOK},             // A new period
+	}
+	for _, test := range tests {
+		evaluation := budget.Evaluate(blocks, test.categories, mustDay(test.asOf))
+		if evaluation.Spent.Minor() != test.spent || evaluation.Projected.Minor() != test.projected || evaluation.Status != test.status {
+			t.Errorf("as of %s: spent %s projected %s %s, want %d %d %s", test.asOf, evaluation.Spent, evaluation.Projected, evaluation.Status, test.spent, test.projected, test.status)
+		}
+		if evaluation.Remaining.Minor() != 10000-test.spent || !evaluation.Limit.Equal(budget.Limit) {
+			t.Errorf("as of %s: limit %s remaining %s", test.asOf, evaluation.Limit, evaluation.Remaining)
+		}
+	}
+
+	blocks = append(blocks, categorized("SUPER", "2025-03-28", 5000, "food"))
+	if evaluation := budget.Evaluate(blocks, categories, mustDay("2025-03-31")); evaluation.Status != BudgetOver || evaluation.Remaining.Minor() != -500 {
+		t.Errorf("over the limit: %s remaining %s", evaluation.Status, evaluation.Remaining)
+	}
+	// 80% of the limit is a warning even at a slow pace
+	if evaluation := budget.Evaluate(Blocks{categorized("SUPER", "2025-03-28", 8000, "food")}, nil, mustDay("2025-03-31")); evaluation.Status != BudgetWarning {
+		t.Errorf("at 80%%: %s", evaluation.Status)
+	}
+}
+
+func TestBudgetRollover(t *testing.T) {
+	tests := []struct {
+		jan, feb int64
+		policy   RolloverPolicy
+		carried  int64
+	}{
+		{6000, 15000, RolloverNone, 0},
+		{6000, 15000, RolloverUnderspend, 0},    // +40 then 140-150 is not carried
+		{6000, 15000, RolloverOverspend, -5000}, // The 40 left in January are lost
+		{6000, 15000, RolloverBoth, -1000},
+		{15000, 6000, RolloverUnderspend, 4000},
+		{15000, 6000, RolloverOverspend, -1000},
+		{15000, 6000, RolloverBoth, -1000},
+	}
+	for _, test := range tests {
+		blocks := Blocks{categorized("SUPER", "2025-01-10", test.jan, "food"), categorized("SUPER", "2025-02-10", test.feb, "food")}
+		budget := Budget{CategoryShortName: "food", Period: BudgetMonthly, Limit: money.New(10000, "EUR"), Rollover: test.policy}
+		evaluation := budget.Evaluate(blocks, nil, mustDay("2025-03-15"))
+		if evaluation.Carried.Minor() != test.carried || evaluation.Limit.Minor() != 10000+test.carried {
+			t.Errorf("%s after %d and %d: carried %s limit %s, want %d", test.policy, test.jan, test.feb, evaluation.Carried, evaluation.Limit, test.carried)
+		}
+	}
+
+	// Start counts empty periods before the first block too
+	blocks := Blocks{categorized("SUPER", "2025-02-10", 6000, "food")}
+	budget := Budget{CategoryShortName: "food", Period: BudgetMonthly, Limit: money.New(10000, "EUR"), Rollover: RolloverUnderspend, Start: mustDay("2025-01-01")}
+	if carried := budget.Evaluate(blocks, nil, mustDay("2025-03-15")).Carried.Minor(); carried != 14000 {
+		t.Errorf("carried %d from January, want 140.00", carried)
+	}
+}
+
+func TestBudgetSplitBlocks(t *testing.T) {
+	market := categorized("MERCADONA", "2025-03-02", 9000, "food")
+	if err := market.AddSplit(Category{ShortName: "home"}, money.New(3000, "EUR"), ""); err != nil {
+		t.Fatal(err)
+	}
+	home := Budget{CategoryShortName: "home", Period: BudgetMonthly, Limit: money.New(2500, "EUR")}
+	food := Budget{CategoryShortName: "food", Period: BudgetMonthly, Limit: money.New(10000, "EUR")}
+
+	// Every budget only counts its line of the split block
+	evaluations := Budgets{home, food}.Evaluate(Blocks{market}, nil, mustDay("2025-03-31"))
+	if evaluations[0].Spent.Minor() != 3000 || evaluations[0].Status != BudgetOver || evaluations[1].Spent.Minor() != 6000 {
+		t.Errorf("home spent %s %s, food spent %s", evaluations[0].Spent, evaluations[0].Status, evaluations[1].Spent)
+	}
+}
+
+func TestBudgetStatuses(t *testing.T) {
+	categories := Categories{{ShortName: "food"}, {ShortName: "bakery", ParentShortName: "food"}, {ShortName: "fun"}}
+	budgets := Budgets{
+		{CategoryShortName: "food", Period: BudgetMonthly, Limit: money.New(10000, "EUR")},
+		{CategoryShortName: "food", Period: BudgetWeekly, Limit: money.New(2000, "EUR")},
+		{CategoryShortName: "fun", Period: BudgetMonthly, Limit: money.New(10000, "EUR")},
+	}
+	blocks := Blocks{
+		categorized("SUPER", "2025-03-03", 1000, "food"),
+		categorized("PAN", "2025-03-04", 1500, "bakery"), // Goes over the weekly food budget
+		categorized("CINE", "2025-03-05", 1000, "fun"),
+	}
+
+	if status := budgets.StatusForCategory("food", blocks, categories, mustDay("2025-03-04")); status != BudgetOver {
+		t.Errorf("food %s, want the worst of its budgets", status)
+	}
+	if status := budgets.StatusForCategory("food", blocks, nil, mustDay("2025-03-04")); status != BudgetWarning {
+		t.Errorf("food without the tree %s, want only on track to exceed the weekly budget", status)
+	}
+	if status := budgets.StatusForCategory("home", blocks, categories, mustDay("2025-03-04")); status != BudgetOK {
+		t.Errorf("a category without budgets is %s", status)
+	}
+
+	days := map[string]BudgetStatus{
+		"2025-03-03": BudgetWarning, // 10.00 of 20.00 in one of seven days
+		"2025-03-04": BudgetOver,
+		"2025-03-05": BudgetOK, // Only fun spent that day
+		"2025-03-06": BudgetOK, // Nothing spent
+	}
+	for day, want := range days {
+		if status := budgets.StatusForDay(blocks, categories, mustDay(day)); status != want {
+			t.Errorf("%s: %s, want %s", day, status, want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
 )
 
 // type Category struct {
@@ -21,17 +22,19 @@ import (
 
 // Category model adapted for SQLite
 type Category struct {
-	ID          int            // SQLite uses int for primary keys by default
-	Name        string         // The name of the category
-	ShortName   string         // The short name of the category
-	Count       int            // A count of how many times this category is used
-	Deleted     bool           // Whether the category is marked as deleted
-	Traduction  sql.NullString // To handle cases where a translation might be optional or NULL
-	Icon        string
-	Color       string
-	Tags        Tags
-	Subcategory string
-	Concepts    Concepts
+	ID         int            // SQLite uses int for primary keys by default
+	Name       string         // The name of the category
+	ShortName  string         // The short name of the category
+	Count      int            // How many blocks, or split lines, are in this category
+	Deleted    bool           // Whether the category is marked as deleted
+	Traduction sql.NullString // To handle cases where a translation might be optional or NULL
+	Icon       string
+	Color      string
+	Tags       Tags
+	Kind       CategoryKind // Empty is treated as CategoryExpense, see Semantics
+	// ShortName of the parent category, empty for top level categories
+	ParentShortName string
+	Concepts        Concepts
 }
 type Tags []Tag
 
@@ -42,7 +45,9 @@ type Tag struct {
 type Categories []Category
 type Concepts []Concept
 type Concept struct {
-	Name              string
+	Name              string // Raw concept as given by the bank
+	CanonicalName     string // Merchant given by DefaultMerchantNormalizer, e.g. "AMAZON"
+	Merchant          MerchantInfo
 	Icon              string
 	ShortName         string
 	Tags              Tags
@@ -58,50 +63,42 @@ func (categories *Categories) PrintCategories() {
 func (category Category) GetUnknownCategory(b Block) Category {
 
 	return Category{
-		Name:        "?",
-		Icon:        "❓",
-		Color:       "",
-		Subcategory: "",
-		Tags:        Tags{},
-		Concepts:    []Concept{b.Concept},
-		ShortName:   "Desconocido",
+		Name:      "?",
+		Icon:      "❓",
+		Color:     "",
+		Tags:      Tags{},
+		Concepts:  []Concept{b.Concept},
+		ShortName: "Desconocido",
 	}
 }
+
+// TryToAssignCategory runs DefaultRules followed by an exact match rule for
+// every stored concept. Use Rules.Explain to see which rule matched, and
+// Categories.SuggestConcepts for close concepts when none did.
 func (category Category) TryToAssignCategory(b Block, categories []Category) Category {
+	return category.TryToAssignCategoryWithRules(b, categories, DefaultRules)
+}
+func (category Category) TryToAssignCategoryWithRules(b Block, categories []Category, rules Rules) Category {
 
-	var categoryName string
-	var categoryShortName string
-	var categoryIcon string
-	var categoryColor string
-	var categorySubcategory string
-	var categoryTags Tags
-
-CATEGORIES_LABEL:
-	for _, category := range categories {
-		for _, storedConcept := range category.Concepts {
-			if storedConcept.Name == b.Concept.Name {
-
-				// Assign the category to the block
-				categoryName = category.Name
-				categoryShortName = category.ShortName
-				categoryIcon = category.Icon
-				categoryColor = category.Color
-				categorySubcategory = category.Subcategory
-				categoryTags = category.Tags
-				break CATEGORIES_LABEL
-			}
-		}
-	}
+	rules = append(append(Rules{}, rules...), RulesFromCategories(categories)...)
+	matched, _ := rules.Categorize(b, categories)
 
 	return Category{
-		Name:        categoryName,
-		ShortName:   categoryShortName,
-		Icon:        categoryIcon,
-		Color:       categoryColor,
-		Subcategory: categorySubcategory,
-		Tags:        categoryTags,
+		Name:            matched.Name,
+		ShortName:       matched.ShortName,
+		Icon:            matched.Icon,
+		Color:           matched.Color,
+		Kind:            matched.Kind,
+		ParentShortName: matched.ParentShortName,
+		Tags:            matched.Tags,
 	}
 }
+
+// SuggestConcepts returns the stored concepts that look like the block
+// concept, for "did you mean" suggestions.
+func (categories Categories) SuggestConcepts(b Block) FuzzyCandidates {
+	return DefaultFuzzyMatcher.Candidates(b, categories)
+}
 func (categories Categories) GetCategories() Categories {
 	return categories
 }
@@ -110,8 +107,8 @@ func (categories *Categories) AssignCategoryToSelectedConcept(row int, category
 	// Get The slected block
 	selectedBlock := Blocks[row]
 
-	fmt.Printf("Category %s selected for row %d\n", category.Name, row+1)
-	fmt.Printf("Concept %s selected for row %d\n", selectedBlock.Concept.Name, row+1)
+	log.Debugf("Category %s selected for row %d", category.Name, row+1)
+	log.Debugf("Concept %s selected for row %d", selectedBlock.Concept.Name, row+1)
 
 	// Get the selected category
 	// selectedCategory := selectedBlock.Category
@@ -129,9 +126,22 @@ func (categories *Categories) AssignCategoryToSelectedConcept(row int, category
 
 func NewConceptFromString(conceptAsString string) Concept {
 
+	canonical, merchant := DefaultMerchantNormalizer.Normalize(conceptAsString)
 	return Concept{
-		Name: conceptAsString,
+		Name:          conceptAsString,
+		CanonicalName: canonical,
+		Merchant:      merchant,
+	}
+}
+
+// Canonical returns CanonicalName, normalizing Name when it was not set
+// (e.g. for concepts loaded from the database)
+func (c Concept) Canonical() string {
+	if c.CanonicalName != "" {
+		return c.CanonicalName
 	}
+	canonical, _ := DefaultMerchantNormalizer.Normalize(c.Name)
+	return canonical
 }
 
 func (categories *Categories) SortByShortName() {
@@ -146,12 +156,14 @@ func (categories *Categories) SortByShortName() {
 }
 
 func PrintCategoriesAndConcepts(cats Categories) {
-	// Print a master table for categories
+	// Print a master table for categories, children indented under their parent
 	categoryTable := tablewriter.NewWriter(os.Stdout)
-	categoryTable.SetHeader([]string{"Category Name", "Short Name", "Icon", "Subcategory"})
+	categoryTable.SetHeader([]string{"Category Name", "Short Name", "Icon", "Kind"})
 
-	for _, c := range cats {
-		categoryTable.Append([]string{c.Name, c.ShortName, c.Icon, c.Subcategory})
+	nodes := cats.inTreeOrder()
+	for _, node := range nodes {
+		c := node.Category
+		categoryTable.Append([]string{treeIndent(node.Depth) + c.Name, c.ShortName, c.Icon, string(c.Semantics())})
 	}
 	categoryTable.SetBorder(true)
 	categoryTable.SetAutoWrapText(false)
@@ -159,12 +171,13 @@ func PrintCategoriesAndConcepts(cats Categories) {
 	categoryTable.Render()
 
 	// For each category, print a concepts table
-	for _, c := range cats {
+	for _, node := range nodes {
+		c := node.Category
 		if len(c.Concepts) == 0 {
 			continue
 		}
 
-		fmt.Printf("\n=== Concepts for Category: %s (%s) ===\n", c.Name, c.ShortName)
+		fmt.Printf("\n=== Concepts for Category: %s (%s) ===\n", cats.Path(c.ShortName), c.ShortName)
 
 		conceptTable := tablewriter.NewWriter(os.Stdout)
 		conceptTable.SetHeader([]string{"Concept Name", "Short Name", "Icon", "Tags"})
@@ -196,10 +209,13 @@ func (cats Categories) PrintCombinedTable() {
 	// If you want to show categories even if they have no concepts, set this to true:
 	showEmptyCategories := false
 
-	for _, c := range cats {
-		if len(c.Concepts) == 0 && showEmptyCategories {
+	for _, node := range cats.inTreeOrder() {
+		c := node.Category
+		name := treeIndent(node.Depth) + c.Name
+		// Parents are always shown so their children stay under them
+		if len(c.Concepts) == 0 && (showEmptyCategories || len(node.Children) > 0) {
 			// Print a row with only category info and empty concepts
-			table.Append([]string{fmt.Sprintf("%d", c.ID), c.Name, c.ShortName, c.Icon, "", "", "", ""})
+			table.Append([]string{fmt.Sprintf("%d", c.ID), name, c.ShortName, c.Icon, "", "", "", ""})
 			continue
 		}
 
@@ -212,7 +228,7 @@ func (cats Categories) PrintCombinedTable() {
 
 			table.Append([]string{
 				fmt.Sprintf("%d", c.ID), // Category ID
-				c.Name,
+				name,
 				c.ShortName,
 				c.Icon,
 				co.Name,
@@ -225,3 +241,19 @@ func (cats Categories) PrintCombinedTable() {
 
 	table.Render()
 }
+
+// inTreeOrder lists the categories depth first, every child after its parent
+func (cats Categories) inTreeOrder() []*CategoryNode {
+	var nodes []*CategoryNode
+	for _, root := range cats.Tree() {
+		root.Walk(func(node *CategoryNode) { nodes = append(nodes, node) })
+	}
+	return nodes
+}
+
+func treeIndent(depth int) string {
+	if depth == 0 {
+		return ""
+	}
+	return strings.Repeat("   ", depth-1) + "└─ "
+}
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"txeo-gui-library/money"
+)
+
+var (
+	ErrCategoryCycle          = errors.New("category would be its own ancestor")
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+)
+
+// CategoryKind tells what the money of a category is, so widgets and totals
+// do not depend on category names
+type CategoryKind string
+
+const (
+	CategoryExpense    CategoryKind = "expense"
+	CategoryIncome     CategoryKind = "income"
+	CategoryTransfer   CategoryKind = "transfer"   // Between own accounts, out of income and expense totals
+	CategorySavings    CategoryKind = "savings"    // Money put aside, e.g. into the hucha
+	CategoryWithdrawal CategoryKind = "withdrawal" // Money taken back from savings
+)
+
+// Semantics returns Kind, or CategoryExpense when it is not set
+func (category Category) Semantics() CategoryKind {
+	if category.Kind == "" {
+		return CategoryExpense
+	}
+	return category.Kind
+}
+
+// CategoryNode is a category with its children, in the order of the
+// Categories it was built from
+type CategoryNode struct {
+	Category Category
+	Depth    int // 0 for top level categories
+	Children []*CategoryNode
+}
+
+// Find returns the category with the given short name
+func (categories Categories) Find(shortName string) (Category, bool) {
+	for _, category := range categories {
+		if category.ShortName == shortName {
+			return category, true
+		}
+	}
+	return Category{}, false
+}
+
+// Tree returns the top level categories with their descendants. Categories
+// whose parent is missing (e.g. deleted) are shown at the top level, so
+// every category appears once even in a tree that fails ValidateTree.
+func (categories Categories) Tree() []*CategoryNode {
+	nodes := make(map[string]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ShortName] = &CategoryNode{Category: category}
+	}
+
+	var roots []*CategoryNode
+	for _, category := range categories {
+		node := nodes[category.ShortName]
+		parent, ok := nodes[category.ParentShortName]
+		if !ok || categories.cycles(category.ShortName) {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	var setDepth func(nodes []*CategoryNode, depth int)
+	setDepth = func(nodes []*CategoryNode, depth int) {
+		for _, node := range nodes {
+			node.Depth = depth
+			setDepth(node.Children, depth+1)
+		}
+	}
+	setDepth(roots, 0)
+	return roots
+}
+
+// Walk visits the node and its descendants depth first
+func (node *CategoryNode) Walk(visit func(node *CategoryNode)) {
+	visit(node)
+	for _, child := range node.Children {
+		child.Walk(visit)
+	}
+}
+
+// ValidateTree checks that every parent exists and that no category is its
+// own ancestor
+func (categories Categories) ValidateTree() error {
+	for _, category := range categories {
+		if category.ParentShortName == "" {
+			continue
+		}
+		if _, ok := categories.Find(category.ParentShortName); !ok {
+			return fmt.Errorf("%w: %q, parent of %q", ErrParentCategoryNotFound, category.ParentShortName, category.ShortName)
+		}
+		if categories.cycles(category.ShortName) {
+			return fmt.Errorf("%w: %q", ErrCategoryCycle, category.ShortName)
+		}
+	}
+	return nil
+}
+
+// cycles reports whether walking up from shortName comes back to it
+func (categories Categories) cycles(shortName string) bool {
+	seen := map[string]bool{}
+	for current := shortName; current != ""; {
+		if seen[current] {
+			return current == shortName
+		}
+		seen[current] = true
+		category, ok := categories.Find(current)
+		if !ok {
+			return false
+		}
+		current = category.ParentShortName
+	}
+	return false
+}
+
+// Ancestors returns the short names of the parent, grandparent and so on of
+// a category
+func (categories Categories) Ancestors(shortName string) []string {
+	var ancestors []string
+	seen := map[string]bool{shortName: true}
+	category, ok := categories.Find(shortName)
+	for ok && category.ParentShortName != "" && !seen[category.ParentShortName] {
+		seen[category.ParentShortName] = true
+		ancestors = append(ancestors, category.ParentShortName)
+		category, ok = categories.Find(category.ParentShortName)
+	}
+	return ancestors
+}
+
+// Descendants returns the short names of the children, grandchildren and so
+// on of a category
+func (categories Categories) Descendants(shortName string) []string {
+	var descendants []string
+	for _, category := range categories {
+		for _, ancestor := range categories.Ancestors(category.ShortName) {
+			if ancestor == shortName {
+				descendants = append(descendants, category.ShortName)
+				break
+			}
+		}
+	}
+	return descendants
+}
+
+// Path returns the names from the top level category down to the given
+// one, e.g. "Casa › Muebles"
+func (categories Categories) Path(shortName string) string {
+	category, ok := categories.Find(shortName)
+	if !ok {
+		return shortName
+	}
+	names := []string{category.Name}
+	for _, ancestor := range categories.Ancestors(shortName) {
+		parent, _ := categories.Find(ancestor)
+		names = append([]string{parent.Name}, names...)
+	}
+	return strings.Join(names, " › ")
+}
+
+// SetParent moves a category under another one, or to the top level when
+// parent is empty. A child without a Kind takes the one of its new parent.
+func (categories Categories) SetParent(shortName string, parent string) error {
+	i := -1
+	for j := range categories {
+		if categories[j].ShortName == shortName {
+			i = j
+		}
+	}
+	if i == -1 {
+		return fmt.Errorf("category %q not found", shortName)
+	}
+
+	parentCategory, ok := categories.Find(parent)
+	if parent != "" && !ok {
+		return fmt.Errorf("%w: %q", ErrParentCategoryNotFound, parent)
+	}
+	if parent == shortName || (parent != "" && contains(categories.Ancestors(parent), shortName)) {
+		return fmt.Errorf("%w: %q under %q", ErrCategoryCycle, shortName, parent)
+	}
+
+	categories[i].ParentShortName = parent
+	if categories[i].Kind == "" {
+		categories[i].Kind = parentCategory.Kind
+	}
+	return nil
+}
+
+// RollUp adds the total of every category to all its ancestors, so the
+// total of a category includes its descendants
+func (categories Categories) RollUp(totals map[string]money.Totals) map[string]money.Totals {
+	rolled := make(map[string]money.Totals, len(totals))
+	add := func(shortName string, total money.Totals) {
+		if rolled[shortName] == nil {
+			rolled[shortName] = money.Totals{}
+		}
+		rolled[shortName].AddTotals(total)
+	}
+	for shortName, total := range totals {
+		add(shortName, total)
+		for _, ancestor := range categories.Ancestors(shortName) {
+			add(ancestor, total)
+		}
+	}
+	return rolled
+}
+
+// Totals sums the blocks of every category including its descendants per
+// currency, split lines counted in their own category and internal
+// transfers skipped
+func (categories Categories) Totals(blocks Blocks) map[string]money.Totals {
+	return categories.RollUp(blocks.TotalsByCategory())
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
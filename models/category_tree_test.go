@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"txeo-gui-library/money"
+)
+
+func testCategoryTree() Categories {
+	return Categories{
+		{Name: "Casa", ShortName: "home"},
+		{Name: "Muebles", ShortName: "furniture", ParentShortName: "home"},
+		{Name: "Sillas", ShortName: "chairs", ParentShortName: "furniture"},
+		{Name: "Nómina", ShortName: "salary", Kind: CategoryIncome},
+		{Name: "Luz", ShortName: "power", ParentShortName: "home"},
+		{Name: "Huérfana", ShortName: "orphan", ParentShortName: "deleted"},
+	}
+}
+
+func TestCategoryTree(t *testing.T) {
+	categories := testCategoryTree()
+	var visited []string
+	for _, root := range categories.Tree() {
+		root.Walk(func(node *CategoryNode) {
+			visited = append(visited, strings.Repeat("-", node.Depth)+node.Category.ShortName)
+		})
+	}
+	// A category whose parent is missing is shown at the top level
+	if got := strings.Join(visited, " "); got != "home -furniture --chairs -power salary orphan" {
+		t.Errorf("tree %s", got)
+	}
+
+	if ancestors := categories.Ancestors("chairs"); strings.Join(ancestors, ",") != "furniture,home" {
+		t.Errorf("ancestors %v", ancestors)
+	}
+	if descendants := categories.Descendants("home"); strings.Join(descendants, ",") != "furniture,chairs,power" {
+		t.Errorf("descendants %v", descendants)
+	}
+	if path := categories.Path("chairs"); path != "Casa › Muebles › Sillas" {
+		t.Errorf("path %q", path)
+	}
+	if err := categories.ValidateTree(); !errors.Is(err, ErrParentCategoryNotFound) {
+		t.Errorf("ValidateTree with an orphan: %v", err)
+	}
+}
+
+func TestCategoryCycles(t *testing.T) {
+	categories := testCategoryTree()[:5]
+	if err := categories.ValidateTree(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, parent := range []string{"chairs", "furniture", "home"} {
+		if err := categories.SetParent("home", parent); !errors.Is(err, ErrCategoryCycle) {
+			t.Errorf("home under %s: %v, want ErrCategoryCycle", parent, err)
+		}
+	}
+	if err := categories.SetParent("power", "nope"); !errors.Is(err, ErrParentCategoryNotFound) {
+		t.Errorf("unknown parent: %v", err)
+	}
+	if err := categories.SetParent("chairs", ""); err != nil || categories.Ancestors("chairs") != nil {
+		t.Errorf("moving to the top level: %v, ancestors %v", err, categories.Ancestors("chairs"))
+	}
+
+	// A cycle written by hand, e.g. in an old database, is reported and the
+	// tree still shows every category once
+	categories[0].ParentShortName = "chairs"
+	categories[2].ParentShortName = "furniture"
+	if err := categories.ValidateTree(); !errors.Is(err, ErrCategoryCycle) {
+		t.Errorf("ValidateTree with a cycle: %v", err)
+	}
+	count := 0
+	for _, root := range categories.Tree() {
+		root.Walk(func(*CategoryNode) { count++ })
+	}
+	if count != len(categories) {
+		t.Errorf("%d nodes in a tree with a cycle, want %d", count, len(categories))
+	}
+}
+
+func TestCategoryKinds(t *testing.T) {
+	if kind := (Category{}).Semantics(); kind != CategoryExpense {
+		t.Errorf("a category without kind is %s", kind)
+	}
+	if kind := (Category{Kind: CategorySavings}).Semantics(); kind != CategorySavings {
+		t.Errorf("a savings category is %s", kind)
+	}
+
+	// A child without kind takes the one of its new parent
+	categories := Categories{{ShortName: "salary", Kind: CategoryIncome}, {ShortName: "bonus"}, {ShortName: "fee", Kind: CategoryExpense}}
+	if err := categories.SetParent("bonus", "salary"); err != nil || categories[1].Kind != CategoryIncome {
+		t.Errorf("bonus under salary: %v, kind %s", err, categories[1].Kind)
+	}
+	if err := categories.SetParent("fee", "salary"); err != nil || categories[2].Kind != CategoryExpense {
+		t.Errorf("fee under salary: %v, kind %s", err, categories[2].Kind)
+	}
+}
+
+func TestCategoryRollUp(t *testing.T) {
+	categories := testCategoryTree()
+	totals := map[string]money.Totals{
+		"chairs": {"EUR": money.New(1000, "EUR")},
+		"power":  {"EUR": money.New(500, "EUR"), "USD": money.New(200, "USD")},
+		"home":   {"EUR": money.New(100, "EUR")},
+	}
+	rolled := categories.RollUp(totals)
+	if rolled["home"].In("EUR").Minor() != 1600 || rolled["home"].In("USD").Minor() != 200 || rolled["furniture"].In("EUR").Minor() != 1000 {
+		t.Errorf("rolled up %v", rolled)
+	}
+	if totals["home"].In("EUR").Minor() != 100 {
+		t.Errorf("RollUp changed its input: %v", totals["home"])
+	}
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Layout used to print Block dates
+const DateLayout = "2006-01-02"
+
+var (
+	ErrInvalidDate   = errors.New("invalid date")
+	ErrAmbiguousDate = errors.New("ambiguous date")
+)
+
+var (
+	isoDatePattern     = regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})(?:[T ].*)?$`)
+	dayFirstPattern    = regexp.MustCompile(`^(\d{1,2})[-/.](\d{1,2})[-/.](\d{2}|\d{4})$`)
+	compactDatePattern = regexp.MustCompile(`^\d{6}$|^\d{8}$`)
+)
+
+// DateOrder tells ParseDateOrder how to read dates that do not start with
+// a four digit year, such as "03/04/2025" or "250302"
+type DateOrder int
+
+const (
+	DateOrderAuto DateOrder = iota // Whichever reading is valid; ErrAmbiguousDate when both are
+	DayFirst                       // dd/mm/yyyy and ddmmyy, as Spanish banks write them
+	MonthFirst                     // mm/dd/yyyy and mmddyy
+	YearFirst                      // yymmdd and yyyymmdd, as Norma 43 and OFX write them
+)
+
+// ParseDate reads the date layouts found in bank exports:
+//
+//	yyyy-mm-dd (also with "/" or "." and an optional time, as in ISO 20022)
+//	dd/mm/yyyy, dd-mm-yyyy, dd.mm.yyyy, dd/mm/yy, dd-mm-yy (or month first)
+//	yymmdd (Norma 43), yyyymmdd (OFX), ddmmyy and ddmmyyyy
+//
+// A date with two valid readings, such as "03/04/2025" (day or month first)
+// or "250302" (yymmdd or ddmmyy), returns ErrAmbiguousDate; use
+// ParseDateOrder when the source is known.
+func ParseDate(s string) (time.Time, error) {
+	return ParseDateOrder(s, DateOrderAuto)
+}
+
+// ParseDateOrder is ParseDate reading the dates without a separated four
+// digit year in the given order. YearFirst only applies to compact dates;
+// dd/mm/yyyy ones are then read as with DateOrderAuto.
+func ParseDateOrder(s string, order DateOrder) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if m := isoDatePattern.FindStringSubmatch(s); m != nil {
+		return buildDate(s, m[1], m[2], m[3])
+	}
+	if m := dayFirstPattern.FindStringSubmatch(s); m != nil {
+		return parseDayMonthDate(s, m[1], m[2], m[3], order)
+	}
+	if compactDatePattern.MatchString(s) {
+		return parseCompactDate(s, order)
+	}
+
+	return time.Time{}, fmt.Errorf("%w %q", ErrInvalidDate, s)
+}
+
+func parseDayMonthDate(s string, first string, second string, year string, order DateOrder) (time.Time, error) {
+	switch order {
+	case DayFirst:
+		return buildDate(s, year, second, first)
+	case MonthFirst:
+		return buildDate(s, year, first, second)
+	}
+
+	dayFirst, dayFirstErr := buildDate(s, year, second, first)
+	monthFirst, monthFirstErr := buildDate(s, year, first, second)
+	switch {
+	case dayFirstErr != nil:
+		return monthFirst, monthFirstErr
+	case monthFirstErr != nil || dayFirst.Equal(monthFirst):
+		return dayFirst, nil
+	}
+	return time.Time{}, fmt.Errorf("%w %q: %s or %s", ErrAmbiguousDate, s, dayFirst.Format(DateLayout), monthFirst.Format(DateLayout))
+}
+
+// parseCompactDate reads yymmdd, ddmmyy and mmddyy, or their yyyy forms
+func parseCompactDate(s string, order DateOrder) (time.Time, error) {
+	yearFirst := func() (time.Time, error) {
+		if len(s) == 6 {
+			return buildDate(s, s[0:2], s[2:4], s[4:6])
+		}
+		return buildDate(s, s[0:4], s[4:6], s[6:8])
+	}
+	// The year is last in the other orders
+	first, second, year := s[0:2], s[2:4], s[4:]
+
+	switch order {
+	case YearFirst:
+		return yearFirst()
+	case DayFirst:
+		return buildDate(s, year, second, first)
+	case MonthFirst:
+		return buildDate(s, year, first, second)
+	}
+
+	yearFirstDate, yearFirstErr := yearFirst()
+	dayFirstDate, dayFirstErr := buildDate(s, year, second, first)
+	switch {
+	case yearFirstErr != nil:
+		return dayFirstDate, dayFirstErr
+	case dayFirstErr != nil || yearFirstDate.Equal(dayFirstDate):
+		return yearFirstDate, nil
+	}
+	return time.Time{}, fmt.Errorf("%w %q: %s or %s", ErrAmbiguousDate, s, yearFirstDate.Format(DateLayout), dayFirstDate.Format(DateLayout))
+}
+
+// buildDate validates the parts and returns the date at midnight UTC.
+// Two digit years are taken as 20yy.
+func buildDate(source string, year string, month string, day string) (time.Time, error) {
+	var y, m, d int
+	if _, err := fmt.Sscanf(year+" "+month+" "+day, "%d %d %d", &y, &m, &d); err != nil {
+		return time.Time{}, fmt.Errorf("%w %q", ErrInvalidDate, source)
+	}
+	if len(year) == 2 {
+		y += 2000
+	}
+
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	// time.Date normalizes 31/02 into March; reject it instead
+	if t.Year() != y || int(t.Month()) != m || t.Day() != d {
+		return time.Time{}, fmt.Errorf("%w %q", ErrInvalidDate, source)
+	}
+	return t, nil
+}
+
+// Day truncates a time to its calendar date at midnight UTC, so dates from
+// different sources can be compared and used as map keys.
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// SameDay reports whether both times fall on the same calendar date.
+func SameDay(a time.Time, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2025-03-02", "2025-03-02"},
+		{"2025/3/2", "2025-03-02"},
+		{"2025-03-02T10:15:00+01:00", "2025-03-02"},
+		{" 2025.03.02 ", "2025-03-02"},
+		{"13/04/2025", "2025-04-13"}, // Only day first is valid
+		{"04/13/2025", "2025-04-13"}, // Only month first is valid
+		{"04/04/2025", "2025-04-04"}, // Both readings are the same date
+		{"31-12-24", "2024-12-31"},
+		{"29.02.2024", "2024-02-29"},
+		{"991231", "2099-12-31"},   // Two digit years are 20yy; 99/12/31 is not a date
+		{"020399", "2099-03-02"},   // Not a yymmdd date, so ddmmyy
+		{"20250302", "2025-03-02"}, // OFX yyyymmdd
+		{"02032025", "2025-03-02"}, // Not a yyyymmdd date, so ddmmyyyy
+	}
+	for _, test := range tests {
+		got, err := ParseDate(test.input)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", test.input, err)
+			continue
+		}
+		if got.Format(DateLayout) != test.want {
+			t.Errorf("ParseDate(%q) = %s, want %s", test.input, got.Format(DateLayout), test.want)
+		}
+	}
+}
+
+func TestParseDateErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{"03/04/2025", ErrAmbiguousDate},
+		{"1/2/25", ErrAmbiguousDate},
+		{"250302", ErrAmbiguousDate}, // 2025-03-02 or 2002-03-25
+		{"311224", ErrAmbiguousDate}, // 2031-12-24 or 2024-12-31
+		{"", ErrInvalidDate},
+		{"yesterday", ErrInvalidDate},
+		{"31/02/2025", ErrInvalidDate},
+		{"2025-13-01", ErrInvalidDate},
+		{"13/13/2025", ErrInvalidDate},
+		{"2503", ErrInvalidDate},
+		{"999999", ErrInvalidDate},
+	}
+	for _, test := range tests {
+		got, err := ParseDate(test.input)
+		if !errors.Is(err, test.err) {
+			t.Errorf("ParseDate(%q) = %s, %v, want %v", test.input, got.Format(DateLayout), err, test.err)
+		}
+	}
+}
+
+func TestParseDateOrder(t *testing.T) {
+	tests := []struct {
+		input string
+		order DateOrder
+		want  string
+	}{
+		{"03/04/2025", DayFirst, "2025-04-03"},
+		{"03/04/2025", MonthFirst, "2025-03-04"},
+		{"13/04/2025", DayFirst, "2025-04-13"},
+		{"2025-03-04", MonthFirst, "2025-03-04"}, // The order does not apply to separated year first dates
+		{"250302", YearFirst, "2025-03-02"},
+		{"250302", DayFirst, "2002-03-25"},
+		{"311224", DayFirst, "2024-12-31"},
+		{"123124", MonthFirst, "2024-12-31"},
+		{"20250302", YearFirst, "2025-03-02"},
+		{"02032025", DayFirst, "2025-03-02"},
+		{"13/04/2025", YearFirst, "2025-04-13"}, // Read as with DateOrderAuto
+	}
+	for _, test := range tests {
+		got, err := ParseDateOrder(test.input, test.order)
+		if err != nil || got.Format(DateLayout) != test.want {
+			t.Errorf("ParseDateOrder(%q, %d) = %s, %v, want %s", test.input, test.order, got.Format(DateLayout), err, test.want)
+		}
+	}
+
+	if _, err := ParseDateOrder("13/04/2025", MonthFirst); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("ParseDateOrder(13/04/2025, MonthFirst) error = %v, want ErrInvalidDate", err)
+	}
+	if _, err := ParseDateOrder("311224", YearFirst); err != nil {
+		t.Errorf("ParseDateOrder(311224, YearFirst): %v", err)
+	}
+	if _, err := ParseDateOrder("03/04/2025", YearFirst); !errors.Is(err, ErrAmbiguousDate) {
+		t.Errorf("ParseDateOrder(03/04/2025, YearFirst) error = %v, want ErrAmbiguousDate", err)
+	}
+}
@@ -0,0 +1,259 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+	"txeo-gui-library/money"
+)
+
+var (
+	ErrNoBalance        = errors.New("no bank balance to start the forecast from")
+	ErrForecastAccounts = errors.New("blocks of several accounts cannot share a forecast")
+)
+
+// ScheduledTransaction is a movement the user knows is coming, e.g. a tax
+// payment or a rent rise. Repeat is empty for one-off movements.
+type ScheduledTransaction struct {
+	Date    time.Time
+	Concept string
+	Amount  money.Money // Positive for expenses, negative for income, like Block.Amount
+	Repeat  RecurrencePeriod
+	Account string // Account.ID, empty for the account of any forecast
+}
+
+// ForecastOptions tune Blocks.Forecast
+type ForecastOptions struct {
+	Days    int     // Days to project after asOf
+	History int     // Days of history used for the discretionary average
+	Z       float64 // Width of the confidence band in standard deviations, 1.64 is about 90%
+}
+
+var DefaultForecastOptions = ForecastOptions{Days: 30, History: 90, Z: 1.64}
+
+// ForecastDay is the projected end of day balance with its confidence band
+type ForecastDay struct {
+	Date          time.Time
+	Balance       money.Money
+	Low           money.Money
+	High          money.Money
+	Known         money.Money // Net amount of the imported movements that day, up to the last imported day
+	Recurring     money.Money // Net amount of the recurring items expected that day
+	Scheduled     money.Money // Net amount of the scheduled transactions that day
+	Discretionary money.Money // Average daily spending outside recurring items
+}
+
+type Forecast struct {
+	Account      string
+	Start        time.Time   // Day of the starting balance
+	StartBalance money.Money // Last balance given by the bank
+	// Average daily spending of every category outside recurring items
+	DiscretionaryByCategory map[string]money.Money
+	Days                    []ForecastDay
+}
+
+// Forecast projects the daily balance of one account from the last balance
+// given by the bank on or before asOf to options.Days days after asOf. The
+// days up to the last imported one take the imported movements without a
+// balance; the later ones subtract the recurring items of the account still
+// active, the scheduled transactions on their dates and the average daily
+// discretionary spending of the last options.History days. The band widens
+// with the day to day variance of the discretionary spending and of the
+// recurring amounts. Use ForecastByAccount for blocks of several accounts.
+func (b Blocks) Forecast(recurring RecurringSeriesList, scheduled []ScheduledTransaction, asOf time.Time, options ForecastOptions) (Forecast, error) {
+	if len(b.ByAccount()) > 1 {
+		return Forecast{}, ErrForecastAccounts
+	}
+
+	asOf = Day(asOf)
+	var past Blocks
+	for _, block := range b.chronological() {
+		if !Day(block.Date).After(asOf) {
+			past = append(past, block)
+		}
+	}
+
+	anchor := len(past) - 1
+	for anchor >= 0 && !past[anchor].HasBalance() {
+		anchor--
+	}
+	if anchor < 0 {
+		return Forecast{}, ErrNoBalance
+	}
+	start := past[anchor]
+	currency := start.Balance.Currency()
+	forecast := Forecast{Account: start.Account, Start: Day(start.Date), StartBalance: start.Balance}
+
+	// Movements imported after the last bank balance
+	balance := float64(start.Balance.Minor())
+	known := map[time.Time]int64{}
+	for _, block := range past[anchor+1:] {
+		if block.Amount.Currency() != currency {
+			continue
+		}
+		if Day(block.Date).Equal(forecast.Start) {
+			balance -= float64(block.Amount.Minor())
+		} else {
+			known[Day(block.Date)] += block.Amount.Minor()
+		}
+	}
+	lastImported := Day(past[len(past)-1].Date)
+
+	mean, variance, byCategory := past.discretionarySpending(recurring, asOf, options.History, currency)
+	forecast.DiscretionaryByCategory = byCategory
+
+	cumulativeVariance := 0.0
+	for day := forecast.Start.AddDate(0, 0, 1); !day.After(asOf.AddDate(0, 0, options.Days)); day = day.AddDate(0, 0, 1) {
+		forecastDay := ForecastDay{Date: day, Known: money.New(known[day], currency), Recurring: money.New(0, currency), Scheduled: money.New(0, currency), Discretionary: money.New(0, currency)}
+		if !day.After(lastImported) {
+			balance -= float64(known[day])
+		} else {
+			forecastDay.Discretionary = money.New(int64(math.Round(mean)), currency)
+			for _, s := range recurring.Active() {
+				if s.Account != forecast.Account || s.LastAmount.Currency() != currency || len(s.ExpectedBetween(day, day.AddDate(0, 0, 1))) == 0 {
+					continue
+				}
+				forecastDay.Recurring = forecastDay.Recurring.Add(s.LastAmount)
+				cumulativeVariance += s.Occurrences.amountVariance()
+			}
+			for _, t := range scheduled {
+				if (t.Account == "" || t.Account == forecast.Account) && t.Amount.Currency() == currency && t.occursOn(day) {
+					forecastDay.Scheduled = forecastDay.Scheduled.Add(t.Amount)
+				}
+			}
+
+			balance -= float64(forecastDay.Recurring.Minor()+forecastDay.Scheduled.Minor()) + mean
+			cumulativeVariance += variance
+		}
+		spread := options.Z * math.Sqrt(cumulativeVariance)
+
+		forecastDay.Balance = money.New(int64(math.Round(balance)), currency)
+		forecastDay.Low = money.New(int64(math.Round(balance-spread)), currency)
+		forecastDay.High = money.New(int64(math.Round(balance+spread)), currency)
+		forecast.Days = append(forecast.Days, forecastDay)
+	}
+	return forecast, nil
+}
+
+// ForecastByAccount forecasts every account on its own, since balances of
+// different accounts, maybe in different currencies, cannot follow each
+// other. Accounts without a bank balance are left out.
+func (b Blocks) ForecastByAccount(recurring RecurringSeriesList, scheduled []ScheduledTransaction, asOf time.Time, options ForecastOptions) (map[string]Forecast, error) {
+	forecasts := map[string]Forecast{}
+	for id, own := range b.ByAccount() {
+		forecast, err := own.Forecast(recurring, scheduled, asOf, options)
+		if errors.Is(err, ErrNoBalance) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		forecasts[id] = forecast
+	}
+	return forecasts, nil
+}
+
+// discretionarySpending returns the mean and variance, in minor units, of
+// the daily spending outside recurring items and transfers over the last
+// history days (fewer when the blocks start later), and the mean of every
+// category
+func (b Blocks) discretionarySpending(recurring RecurringSeriesList, asOf time.Time, history int, currency string) (float64, float64, map[string]money.Money) {
+	byCategory := map[string]money.Money{}
+	if history <= 0 {
+		return 0, 0, byCategory
+	}
+
+	inSeries := map[string]bool{}
+	for _, s := range recurring {
+		for i := range s.Occurrences {
+			inSeries[s.Occurrences[i].fingerprintKey()] = true
+		}
+	}
+
+	from := asOf.AddDate(0, 0, -history+1)
+	if len(b) > 0 {
+		if earliest := Day(b.chronological()[0].Date); earliest.After(from) {
+			from = earliest
+		}
+	}
+	history = int(asOf.Sub(from).Hours()/24) + 1
+	daily := make([]float64, history)
+	categoryTotals := map[string]int64{}
+	for i := range b {
+		block := b[i]
+		if block.IsTransfer() || block.Amount.Currency() != currency || block.Date.Before(from) || inSeries[block.fingerprintKey()] {
+			continue
+		}
+		daily[int(Day(block.Date).Sub(from).Hours()/24)] += float64(block.Amount.Minor())
+		for _, part := range block.Parts() {
+			categoryTotals[part.Category.ShortName] += part.Amount.Minor()
+		}
+	}
+
+	mean, variance := meanAndVariance(daily)
+	for category, total := range categoryTotals {
+		byCategory[category] = money.New(total, currency).Divide(int64(history), money.RoundHalfEven)
+	}
+	return mean, variance, byCategory
+}
+
+func (b Blocks) amountVariance() float64 {
+	amounts := make([]float64, len(b))
+	for i := range b {
+		amounts[i] = float64(b[i].Amount.Minor())
+	}
+	_, variance := meanAndVariance(amounts)
+	return variance
+}
+
+func meanAndVariance(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	squares := 0.0
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, squares / float64(len(values))
+}
+
+func (t ScheduledTransaction) occursOn(day time.Time) bool {
+	date := Day(t.Date)
+	if t.Repeat == "" || !date.Before(day) {
+		return date.Equal(day)
+	}
+
+	// Every date is counted on the day of month of the first one, so a
+	// payment on the 31st is back on the 31st after February
+	for date.Before(day) {
+		date = nextOccurrence(date, t.Repeat, t.Date.Day())
+	}
+	return date.Equal(day)
+}
+
+// Day returns the projection of a day
+func (f Forecast) Day(date time.Time) (ForecastDay, bool) {
+	for _, day := range f.Days {
+		if SameDay(day.Date, date) {
+			return day, true
+		}
+	}
+	return ForecastDay{}, false
+}
+
+// FirstNegative returns the first day whose projected balance, or with
+// pessimistic set the low end of its band, is below zero
+func (f Forecast) FirstNegative(pessimistic bool) (ForecastDay, bool) {
+	for _, day := range f.Days {
+		if day.Balance.IsNegative() || (pessimistic && day.Low.IsNegative()) {
+			return day, true
+		}
+	}
+	return ForecastDay{}, false
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"txeo-gui-library/money"
+)
+
+func TestScheduledTransactionOccursOn(t *testing.T) {
+	rent := ScheduledTransaction{Date: mustDay("2025-01-31"), Amount: money.New(80000, "EUR"), Repeat: Monthly}
+	for date, want := range map[string]bool{
+		"2025-01-31": true,
+		"2025-02-28": true,
+		"2025-03-31": true, // Not the 28th it would drift to from February
+		"2025-03-28": false,
+		"2025-03-03": false,
+		"2025-04-30": true,
+		"2025-12-31": true,
+		"2025-01-30": false,
+	} {
+		if got := rent.occursOn(mustDay(date)); got != want {
+			t.Errorf("monthly rent on %s: %v, want %v", date, got, want)
+		}
+	}
+
+	once := ScheduledTransaction{Date: mustDay("2025-06-30"), Amount: money.New(100, "EUR")}
+	if !once.occursOn(mustDay("2025-06-30")) || once.occursOn(mustDay("2025-07-30")) {
+		t.Errorf("a one-off transaction repeats")
+	}
+}
+
+func TestForecastFromLastBankBalance(t *testing.T) {
+	anchor := testBlock("SUPER", "2025-03-01", 700)
+	anchor.Balance = money.New(100000, "EUR")
+	blocks := Blocks{anchor}
+
+	options := ForecastOptions{Days: 2, History: 7, Z: 1.64}
+	forecast, err := blocks.Forecast(nil, nil, mustDay("2025-03-07"), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forecast.Start.Format(DateLayout) != "2025-03-01" || forecast.StartBalance.Minor() != 100000 {
+		t.Errorf("start %s %s", forecast.Start.Format(DateLayout), forecast.StartBalance)
+	}
+
+	// 7.00 spent in the 7 days up to asOf is 1.00 a day, also on the days
+	// between the last balance and asOf
+	if len(forecast.Days) != 8 || forecast.Days[0].Date.Format(DateLayout) != "2025-03-02" {
+		t.Fatalf("%d days from %s, want 8 from 2025-03-02", len(forecast.Days), forecast.Days[0].Date.Format(DateLayout))
+	}
+	for i, day := range forecast.Days {
+		want := int64(100000 - 100*(i+1))
+		if day.Balance.Minor() != want || day.Discretionary.Minor() != 100 {
+			t.Errorf("%s: balance %s discretionary %s, want %d", day.Date.Format(DateLayout), day.Balance, day.Discretionary, want)
+		}
+		if !day.Low.LessThan(day.Balance) || !day.Balance.LessThan(day.High) {
+			t.Errorf("%s: band %s..%s around %s", day.Date.Format(DateLayout), day.Low, day.High, day.Balance)
+		}
+	}
+	if forecast.DiscretionaryByCategory[""].Minor() != 100 {
+		t.Errorf("discretionary by category %v", forecast.DiscretionaryByCategory)
+	}
+}
+
+func TestForecastImportedMovementsAndScheduled(t *testing.T) {
+	salary := testBlock("NOMINA", "2025-03-01", -100000)
+	salary.Balance = money.New(100000, "EUR")
+	blocks := Blocks{salary, testBlock("CAFE", "2025-03-01", 200), testBlock("CAFE", "2025-03-03", 1000)}
+	// The estimated balances of the reconciled series are not a start
+	if series := blocks.Reconcile().Series; !series[len(series)-1].Estimated {
+		t.Fatalf("the last daily balance should be estimated")
+	}
+
+	scheduled := []ScheduledTransaction{
+		{Date: mustDay("2025-03-06"), Amount: money.New(20000, "EUR")},
+		{Date: mustDay("2025-03-06"), Amount: money.New(5000, "EUR"), Account: "other"},
+		{Date: mustDay("2025-03-07"), Amount: money.New(5000, "USD")},
+	}
+	forecast, err := blocks.Forecast(nil, scheduled, mustDay("2025-03-05"), ForecastOptions{Days: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		date      string
+		balance   int64
+		known     int64
+		scheduled int64
+	}{
+		{"2025-03-02", 99800, 0, 0},
+		{"2025-03-03", 98800, 1000, 0},
+		{"2025-03-04", 98800, 0, 0}, // After the last imported day
+		{"2025-03-05", 98800, 0, 0},
+		{"2025-03-06", 78800, 0, 20000},
+		{"2025-03-07", 78800, 0, 0},
+		{"2025-03-08", 78800, 0, 0},
+	}
+	if len(forecast.Days) != len(want) {
+		t.Fatalf("%d days, want %d", len(forecast.Days), len(want))
+	}
+	for i, w := range want {
+		day := forecast.Days[i]
+		if day.Date.Format(DateLayout) != w.date || day.Balance.Minor() != w.balance || day.Known.Minor() != w.known || day.Scheduled.Minor() != w.scheduled {
+			t.Errorf("day %d: %s balance %s known %s scheduled %s, want %+v", i, day.Date.Format(DateLayout), day.Balance, day.Known, day.Scheduled, w)
+		}
+	}
+}
+
+func TestForecastRecurringMonthEnd(t *testing.T) {
+	blocks := testBlocks("SPOTIFY", 999, "2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30", "2025-05-31")
+	blocks.SetAccount("main")
+	blocks[len(blocks)-1].Balance = money.New(50000, "EUR")
+	recurring := blocks.FindRecurring(DefaultRecurringOptions)
+
+	forecast, err := blocks.Forecast(recurring, nil, mustDay("2025-06-01"), ForecastOptions{Days: 61})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var charged []string
+	for _, day := range forecast.Days {
+		if !day.Recurring.IsZero() {
+			charged = append(charged, day.Date.Format(DateLayout))
+		}
+	}
+	if len(charged) != 2 || charged[0] != "2025-06-30" || charged[1] != "2025-07-31" {
+		t.Errorf("recurring charged on %v, want 2025-06-30 and 2025-07-31", charged)
+	}
+	if last := forecast.Days[len(forecast.Days)-1]; last.Balance.Minor() != 50000-2*999 {
+		t.Errorf("balance on %s: %s", last.Date.Format(DateLayout), last.Balance)
+	}
+
+	// A series of another account is not charged
+	other := blocks.FindRecurring(DefaultRecurringOptions)
+	other[0].Account = "savings"
+	if forecast, _ := blocks.Forecast(other, nil, mustDay("2025-06-01"), ForecastOptions{Days: 61}); forecast.Days[len(forecast.Days)-1].Balance.Minor() != 50000 {
+		t.Errorf("the series of another account was charged")
+	}
+}
+
+func TestForecastByAccount(t *testing.T) {
+	euros := testBlock("NOMINA", "2025-03-01", -100000)
+	euros.Account, euros.Balance = "main", money.New(100000, "EUR")
+	dollars := *NewBlockWithMoney("DEPOSIT", mustDay("2025-03-02"), "", money.New(-5000, "USD"), money.New(5000, "USD"))
+	dollars.Account = "usd"
+	noBalance := testBlock("CASH", "2025-03-02", 1000)
+	noBalance.Account = "cash"
+	blocks := Blocks{euros, dollars, noBalance}
+
+	if _, err := blocks.Forecast(nil, nil, mustDay("2025-03-05"), ForecastOptions{Days: 1}); !errors.Is(err, ErrForecastAccounts) {
+		t.Errorf("Forecast of several accounts error = %v, want ErrForecastAccounts", err)
+	}
+	if _, err := (Blocks{noBalance}).Forecast(nil, nil, mustDay("2025-03-05"), ForecastOptions{Days: 1}); !errors.Is(err, ErrNoBalance) {
+		t.Errorf("Forecast without balances error = %v, want ErrNoBalance", err)
+	}
+
+	scheduled := []ScheduledTransaction{{Date: mustDay("2025-03-06"), Amount: money.New(30000, "EUR"), Account: "main"}}
+	forecasts, err := blocks.ForecastByAccount(nil, scheduled, mustDay("2025-03-05"), ForecastOptions{Days: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forecasts) != 2 {
+		t.Fatalf("%d forecasts, want main and usd", len(forecasts))
+	}
+	if last := forecasts["main"].Days[len(forecasts["main"].Days)-1]; last.Balance.Minor() != 70000 || last.Balance.Currency() != "EUR" {
+		t.Errorf("main ends at %s, want 700.00 EUR", last.Balance)
+	}
+	if last := forecasts["usd"].Days[len(forecasts["usd"].Days)-1]; last.Balance.Minor() != 5000 || last.Balance.Currency() != "USD" {
+		t.Errorf("usd ends at %s, want 50.00 USD", last.Balance)
+	}
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// FuzzyCandidate is a stored concept that looks like a block concept
+type FuzzyCandidate struct {
+	Concept    Concept
+	Category   Category
+	Score      float64 // Weighted mix of EditScore and TokenScore, 0..1
+	EditScore  float64 // 1 - normalized edit distance of the cleaned names
+	TokenScore float64 // Share of the shorter name's words found in the other
+}
+type FuzzyCandidates []FuzzyCandidate
+
+// FuzzyMatcher scores stored concepts against a block concept by their
+// canonical merchant names, so bank noise (card numbers, dates, "COMPRA
+// TARJ." prefixes, trailing cities) does not count.
+// Candidates are only suggestions: nothing is categorized from them without
+// the user picking one.
+type FuzzyMatcher struct {
+	Threshold float64 // Candidates below this score are dropped
+	Limit     int     // Maximum number of candidates, 0 for all
+}
+
+// DefaultFuzzyMatcher is used by Categories.SuggestConcepts
+var DefaultFuzzyMatcher = FuzzyMatcher{Threshold: 0.75, Limit: 5}
+
+// Weight of the edit distance in the score, the rest goes to the words
+const fuzzyEditWeight = 0.4
+
+// Words that banks put before the merchant name
+var conceptNoisePrefixes = map[string]bool{
+	"COMPRA": true, "COMPRAS": true, "TARJ": true, "TARJETA": true, "TJ": true, "TJT": true,
+	"PAGO": true, "EN": true, "CONTACTLESS": true, "CARD": true, "OP": true, "MOVIL": true,
+}
+
+// Cities that banks append after the merchant name
+var conceptNoiseCities = map[string]bool{
+	"MADRID": true, "BARCELONA": true, "VALENCIA": true, "SEVILLA": true, "ZARAGOZA": true,
+	"MALAGA": true, "MURCIA": true, "PALMA": true, "BILBAO": true, "ALICANTE": true,
+	"CORDOBA": true, "VALLADOLID": true, "VIGO": true, "GIJON": true, "GRANADA": true,
+	"CASTELLON": true, "GANDIA": true, "TORRENT": true, "PATERNA": true, "LUXEMBOURG": true,
+	"LUXEMBURGO": true, "DUBLIN": true, "LONDON": true, "PARIS": true, "AMSTERDAM": true,
+}
+
+// Country codes that may follow the city
+var conceptNoiseCountries = map[string]bool{"ES": true, "ESP": true, "ESPANA": true, "LU": true, "IE": true, "GB": true}
+
+// CleanConcept normalizes a concept with NormalizeConcept and removes the
+// usual bank noise, e.g. "COMPRA TARJ. 5402XXXXXXXX1234 MERCADONA VALENCIA"
+// becomes "MERCADONA".
+func CleanConcept(s string) string {
+	words, _ := cleanConceptWords(s)
+	return strings.Join(words, " ")
+}
+
+// cleanConceptWords returns the words left by CleanConcept and the trailing
+// city words it removed.
+func cleanConceptWords(s string) ([]string, []string) {
+	var words []string
+	for _, word := range strings.Fields(NormalizeConcept(s)) {
+		// Masked card numbers leave a run of X once the digits are gone
+		if strings.Trim(word, "X") == "" || len(word) == 1 {
+			continue
+		}
+		words = append(words, word)
+	}
+
+	start, end := 0, len(words)
+	for start < end-1 && conceptNoisePrefixes[words[start]] {
+		start++
+	}
+	var location []string
+	for end > start+1 && (conceptNoiseCities[words[end-1]] || conceptNoiseCountries[words[end-1]]) {
+		end--
+		if conceptNoiseCities[words[end]] {
+			location = append([]string{words[end]}, location...)
+		}
+	}
+	return words[start:end], location
+}
+
+// Candidates returns the concepts of the non deleted categories scoring at
+// least Threshold against the block concept, best first.
+func (matcher FuzzyMatcher) Candidates(b Block, categories Categories) FuzzyCandidates {
+	name := b.Concept.Canonical()
+	if name == "" {
+		return nil
+	}
+
+	var candidates FuzzyCandidates
+	for _, category := range categories {
+		if category.Deleted {
+			continue
+		}
+		for _, concept := range category.Concepts {
+			candidate := scoreConcept(name, concept.Canonical())
+			if candidate.Score < matcher.Threshold {
+				continue
+			}
+			candidate.Concept = concept
+			candidate.Category = category
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if matcher.Limit > 0 && len(candidates) > matcher.Limit {
+		candidates = candidates[:matcher.Limit]
+	}
+	return candidates
+}
+
+func scoreConcept(a string, b string) FuzzyCandidate {
+	if a == "" || b == "" {
+		return FuzzyCandidate{}
+	}
+
+	edit := similarity(a, b)
+	tokens := tokenOverlap(strings.Fields(a), strings.Fields(b))
+	return FuzzyCandidate{
+		Score:      fuzzyEditWeight*edit + (1-fuzzyEditWeight)*tokens,
+		EditScore:  edit,
+		TokenScore: tokens,
+	}
+}
+
+// tokenOverlap is the share of the shorter word list found in the longer
+// one. Words match when they are at least 80% similar, so small typos and
+// truncations still count.
+func tokenOverlap(a []string, b []string) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	used := make([]bool, len(b))
+	found := 0
+	for _, word := range a {
+		for i, other := range b {
+			if !used[i] && similarity(word, other) >= 0.8 {
+				used[i] = true
+				found++
+				break
+			}
+		}
+	}
+	return float64(found) / float64(len(a))
+}
+
+// similarity is 1 minus the Levenshtein distance divided by the longer length
+func similarity(a string, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
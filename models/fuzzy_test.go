@@ -0,0 +1,81 @@
+package models
+
+import "testing"
+
+func TestCleanConcept(t *testing.T) {
+	tests := map[string]string{
+		"COMPRA TARJ. 5402XXXXXXXX1234 MERCADONA VALENCIA": "MERCADONA",
+		"Pago contactless Café Central Madrid ES":          "CAFE CENTRAL",
+		"AMAZON EU SARL LUXEMBOURG LU":                     "AMAZON EU SARL",
+		"COMPRA":                                           "COMPRA", // A lone prefix is the merchant
+		"VALENCIA":                                         "VALENCIA",
+		"":                                                 "",
+	}
+	for concept, want := range tests {
+		if got := CleanConcept(concept); got != want {
+			t.Errorf("CleanConcept(%q) = %q, want %q", concept, got, want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"MERCADONA", "MERCADONA", 1},
+		{"MERCADONA", "MERCADONNA", 0.9},
+		{"", "", 1},
+		{"ABC", "", 0},
+		{"CAFÉ", "CAFE", 0.75}, // Runes, not bytes
+	}
+	for _, test := range tests {
+		if got := similarity(test.a, test.b); got < test.want-1e-9 || got > test.want+1e-9 {
+			t.Errorf("similarity(%q, %q) = %f, want %f", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestFuzzyCandidates(t *testing.T) {
+	categories := Categories{
+		{ShortName: "food", Concepts: Concepts{{Name: "MERCADONA"}, {Name: "CARREFOUR EXPRESS"}}},
+		{ShortName: "fun", Concepts: Concepts{{Name: "CINES LUMIERE"}}},
+		{ShortName: "old", Deleted: true, Concepts: Concepts{{Name: "MERCADONNA"}}},
+	}
+
+	candidates := categories.SuggestConcepts(testBlock("COMPRA TARJ. 5402XXXXXXXX1234 MERCADONNA VALENCIA", "2025-03-02", 1000))
+	if len(candidates) != 1 || candidates[0].Concept.Name != "MERCADONA" || candidates[0].Category.ShortName != "food" {
+		t.Fatalf("candidates %+v, want MERCADONA in food only", candidates)
+	}
+	if c := candidates[0]; c.Score < DefaultFuzzyMatcher.Threshold || c.Score >= 1 || c.TokenScore != 1 {
+		t.Errorf("score %f edit %f tokens %f", c.Score, c.EditScore, c.TokenScore)
+	}
+
+	// Words in another order or truncated still match
+	if candidates := categories.SuggestConcepts(testBlock("CARREFOUR EXPRES", "2025-03-02", 1000)); len(candidates) != 1 || candidates[0].Concept.Name != "CARREFOUR EXPRESS" {
+		t.Errorf("truncated word: %+v", candidates)
+	}
+	if candidates := categories.SuggestConcepts(testBlock("LIDL", "2025-03-02", 1000)); len(candidates) != 0 {
+		t.Errorf("unrelated concept: %+v", candidates)
+	}
+
+	// Best first, cut at Limit
+	many := Categories{{ShortName: "food", Concepts: Concepts{{Name: "BAR PEPA"}, {Name: "BAR PEPE"}, {Name: "BAR PEPITO"}}}}
+	matcher := FuzzyMatcher{Threshold: 0.5, Limit: 2}
+	candidates = matcher.Candidates(testBlock("BAR PEPE", "2025-03-02", 300), many)
+	if len(candidates) != 2 || candidates[0].Concept.Name != "BAR PEPE" || candidates[0].Score != 1 || candidates[1].Score > candidates[0].Score {
+		t.Errorf("ranked candidates %+v", candidates)
+	}
+}
+
+func TestFuzzyMatchesDoNotCategorize(t *testing.T) {
+	categories := Categories{{Name: "Comida", ShortName: "food", Concepts: Concepts{{Name: "MERCADONA", CategoryShortName: "food"}}}}
+	block := testBlock("MERCADONNA", "2025-03-02", 1000)
+	if len(categories.SuggestConcepts(block)) == 0 {
+		t.Fatal("no candidate for a typo")
+	}
+	// Only the user picking a candidate assigns it
+	if category := (Category{}).TryToAssignCategory(block, categories); category.ShortName == "food" {
+		t.Errorf("a fuzzy match categorized the block")
+	}
+}
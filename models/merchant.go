@@ -0,0 +1,180 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// OperationType is the kind of movement a bank concept describes
+type OperationType string
+
+const (
+	OperationUnknown        OperationType = ""
+	OperationCardPurchase   OperationType = "card_purchase"
+	OperationDirectDebit    OperationType = "direct_debit"
+	OperationTransfer       OperationType = "transfer"
+	OperationBizum          OperationType = "bizum"
+	OperationCashWithdrawal OperationType = "cash_withdrawal"
+)
+
+// MerchantInfo is the metadata extracted from a raw concept
+type MerchantInfo struct {
+	Operation  OperationType
+	CardSuffix string // Last four digits of the card, when given
+	Location   string // Trailing city, e.g. "LUXEMBOURG"
+	Pattern    string // Name of the MerchantPattern that matched
+}
+
+// MerchantPattern recognizes one bank format. The expression runs on the
+// uppercased concept without accents and may use the named groups
+// "merchant", "card" and "location".
+type MerchantPattern struct {
+	Name       string
+	Expression *regexp.Regexp
+	Operation  OperationType
+}
+
+// MerchantAlias renames every merchant starting with Prefix
+type MerchantAlias struct {
+	Prefix    string `json:"prefix"`
+	Canonical string `json:"canonical"`
+}
+
+// MerchantNormalizer turns raw bank concepts into a canonical merchant name.
+// Patterns are tried in order and the first match wins; aliases are applied
+// to the cleaned merchant afterwards.
+type MerchantNormalizer struct {
+	Patterns []MerchantPattern
+	Aliases  []MerchantAlias
+}
+
+// Built-in formats of CaixaBank, BBVA, Santander and Sabadell statements
+var defaultMerchantPatterns = []MerchantPattern{
+	{"card-bbva", regexp.MustCompile(`^COMPRA EN (?P<merchant>.+?),? CON LA TARJETA\s*:?\s*(?P<card>[0-9X*]+)`), OperationCardPurchase},
+	{"card", regexp.MustCompile(`^(?:COMPRAS?|PAGO)(?: CON)?\s+(?:TARJ(?:ETA)?\.?|TJT?\.?)\s*(?:CONTACTLESS\s+)?(?P<card>[0-9X*]{4,19})?\s*(?P<merchant>.*)$`), OperationCardPurchase},
+	{"card-movil", regexp.MustCompile(`^PAGO MOVIL EN (?P<merchant>.+)$`), OperationCardPurchase},
+	{"direct-debit", regexp.MustCompile(`^(?:CARGO )?(?:RECIBOS?|ADEUDO|DOMICILIACION)\.?\s+(?:SEPA\s+)?(?:DE\s+)?(?P<merchant>.*)$`), OperationDirectDebit},
+	{"bizum", regexp.MustCompile(`^BIZUM\s*(?:ENVIADO|RECIBIDO)?\s*(?:A|DE)?\s+(?P<merchant>.*)$`), OperationBizum},
+	{"transfer", regexp.MustCompile(`^(?:TRANSFERENCIA|TRANSFER|TRANSF|TRF)\b\.?\s*(?:SEPA\s+)?(?:INMEDIATA\s+)?(?:(?:A FAVOR DE|DE|A)\s+)?(?P<merchant>.*)$`), OperationTransfer},
+	{"cash", regexp.MustCompile(`^(?:REINTEGRO|RETIRADA|DISPOSICION)\s+(?:EFECTIVO\s+)?(?:CAJERO\s+)?(?P<card>[0-9X*]{4,19})?\s*(?P<merchant>.*)$`), OperationCashWithdrawal},
+}
+
+var defaultMerchantAliases = []MerchantAlias{
+	{"AMAZON", "AMAZON"},
+	{"AMZN", "AMAZON"},
+	{"PAYPAL", "PAYPAL"},
+	{"MERCADONA", "MERCADONA"},
+	{"NETFLIX", "NETFLIX"},
+	{"SPOTIFY", "SPOTIFY"},
+}
+
+// DefaultMerchantNormalizer is used by NewConceptFromString. Add your own
+// patterns and aliases to it at startup.
+var DefaultMerchantNormalizer = NewMerchantNormalizer()
+
+// NewMerchantNormalizer returns a normalizer with the built-in patterns
+func NewMerchantNormalizer() *MerchantNormalizer {
+	return &MerchantNormalizer{
+		Patterns: append([]MerchantPattern{}, defaultMerchantPatterns...),
+		Aliases:  append([]MerchantAlias{}, defaultMerchantAliases...),
+	}
+}
+
+// AddPattern adds a user pattern, tried before the existing ones
+func (n *MerchantNormalizer) AddPattern(name string, expression string, operation OperationType) error {
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		return fmt.Errorf("merchant pattern %q: %w", name, err)
+	}
+	n.Patterns = append([]MerchantPattern{{Name: name, Expression: re, Operation: operation}}, n.Patterns...)
+	return nil
+}
+
+// AddAlias adds a user alias, tried before the existing ones
+func (n *MerchantNormalizer) AddAlias(prefix string, canonical string) {
+	alias := MerchantAlias{Prefix: NormalizeConcept(prefix), Canonical: canonical}
+	n.Aliases = append([]MerchantAlias{alias}, n.Aliases...)
+}
+
+// LoadMerchantPatterns adds the patterns and aliases of a JSON file such as
+//
+//	{"patterns": [{"name": "nomina", "pattern": "^ABONO NOMINA (?P<merchant>.*)$", "operation": "transfer"}],
+//	 "aliases": [{"prefix": "AMZN", "canonical": "AMAZON"}]}
+func (n *MerchantNormalizer) LoadMerchantPatterns(r io.Reader) error {
+	var file struct {
+		Patterns []struct {
+			Name      string        `json:"name"`
+			Pattern   string        `json:"pattern"`
+			Operation OperationType `json:"operation"`
+		} `json:"patterns"`
+		Aliases []MerchantAlias `json:"aliases"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return err
+	}
+
+	// Prepend in reverse so the file order is kept
+	for i := len(file.Patterns) - 1; i >= 0; i-- {
+		pattern := file.Patterns[i]
+		if err := n.AddPattern(pattern.Name, pattern.Pattern, pattern.Operation); err != nil {
+			return err
+		}
+	}
+	for i := len(file.Aliases) - 1; i >= 0; i-- {
+		n.AddAlias(file.Aliases[i].Prefix, file.Aliases[i].Canonical)
+	}
+	return nil
+}
+
+// Normalize returns the canonical merchant of a raw concept and its metadata.
+// "COMPRA TARJ. 5402XXXXXXXX1234 AMAZON EU SARL LUXEMBOURG" gives "AMAZON",
+// card suffix "1234", location "LUXEMBOURG" and a card purchase.
+func (n *MerchantNormalizer) Normalize(raw string) (string, MerchantInfo) {
+	var info MerchantInfo
+	text := strings.Join(strings.Fields(accentReplacer.Replace(strings.ToUpper(raw))), " ")
+	merchant := text
+
+	for _, pattern := range n.Patterns {
+		match := pattern.Expression.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		info.Pattern = pattern.Name
+		info.Operation = pattern.Operation
+		if i := pattern.Expression.SubexpIndex("merchant"); i >= 0 && strings.TrimSpace(match[i]) != "" {
+			merchant = match[i]
+		}
+		if i := pattern.Expression.SubexpIndex("card"); i >= 0 {
+			info.CardSuffix = cardSuffix(match[i])
+		}
+		if i := pattern.Expression.SubexpIndex("location"); i >= 0 {
+			info.Location = NormalizeConcept(match[i])
+		}
+		break
+	}
+
+	words, location := cleanConceptWords(merchant)
+	if info.Location == "" {
+		info.Location = strings.Join(location, " ")
+	}
+	canonical := strings.Join(words, " ")
+	for _, alias := range n.Aliases {
+		if canonical == alias.Prefix || strings.HasPrefix(canonical, alias.Prefix+" ") {
+			canonical = alias.Canonical
+			break
+		}
+	}
+	return canonical, info
+}
+
+// cardSuffix keeps the last four digits of a card number, which are the
+// ones banks leave unmasked
+func cardSuffix(card string) string {
+	if len(card) < 4 || strings.Trim(card[len(card)-4:], "0123456789") != "" {
+		return ""
+	}
+	return card[len(card)-4:]
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeMerchant(t *testing.T) {
+	tests := []struct {
+		raw       string
+		merchant  string
+		operation OperationType
+		card      string
+		location  string
+	}{
+		{"COMPRA TARJ. 5402XXXXXXXX1234 AMAZON EU SARL LUXEMBOURG", "AMAZON", OperationCardPurchase, "1234", "LUXEMBOURG"},
+		{"Compra en Mercadona Valencia, con la tarjeta : 4940XXXXXXXX5678", "MERCADONA", OperationCardPurchase, "5678", "VALENCIA"},
+		{"PAGO MOVIL EN CAFÉ CENTRAL", "CAFE CENTRAL", OperationCardPurchase, "", ""},
+		{"RECIBO SEPA IBERDROLA CLIENTES", "IBERDROLA CLIENTES", OperationDirectDebit, "", ""},
+		{"BIZUM ENVIADO A JUAN PEREZ", "JUAN PEREZ", OperationBizum, "", ""},
+		{"TRANSFERENCIA A FAVOR DE ACME SL", "ACME SL", OperationTransfer, "", ""},
+		{"REINTEGRO CAJERO 4940XXXXXXXX5678 BANKINTER", "BANKINTER", OperationCashWithdrawal, "5678", ""},
+		{"REINTEGRO CAJERO XXXXXXXXXXXX", "REINTEGRO CAJERO", OperationCashWithdrawal, "", ""}, // No merchant, the whole concept is kept
+		{"PANADERIA  LOPEZ", "PANADERIA LOPEZ", OperationUnknown, "", ""},
+	}
+	for _, test := range tests {
+		merchant, info := DefaultMerchantNormalizer.Normalize(test.raw)
+		if merchant != test.merchant || info.Operation != test.operation || info.CardSuffix != test.card || info.Location != test.location {
+			t.Errorf("Normalize(%q) = %q %+v, want %q %s card %q in %q", test.raw, merchant, info, test.merchant, test.operation, test.card, test.location)
+		}
+	}
+}
+
+func TestMerchantNormalizerExtensions(t *testing.T) {
+	n := NewMerchantNormalizer()
+	if err := n.AddPattern("broken", "(", OperationUnknown); err == nil {
+		t.Errorf("an invalid expression was added")
+	}
+
+	file := `{"patterns": [{"name": "nomina", "pattern": "^ABONO NOMINA (?P<merchant>.*)$", "operation": "transfer"},
+	                       {"name": "abono", "pattern": "^ABONO (?P<merchant>.*)$"}],
+	          "aliases": [{"prefix": "acme", "canonical": "ACME"}]}`
+	if err := n.LoadMerchantPatterns(strings.NewReader(file)); err != nil {
+		t.Fatal(err)
+	}
+	// File patterns keep their order and come before the built-in ones
+	merchant, info := n.Normalize("ABONO NOMINA ACME SOFTWARE SL")
+	if merchant != "ACME" || info.Pattern != "nomina" || info.Operation != OperationTransfer {
+		t.Errorf("loaded pattern gives %q %+v", merchant, info)
+	}
+	if merchant, _ := n.Normalize("ACMEX SL"); merchant != "ACMEX SL" {
+		t.Errorf("an alias renamed %q", merchant)
+	}
+
+	// The default normalizer is left alone
+	if merchant, info := DefaultMerchantNormalizer.Normalize("ABONO NOMINA ACME SOFTWARE SL"); merchant == "ACME" || info.Pattern != "" {
+		t.Errorf("default normalizer changed: %q %+v", merchant, info)
+	}
+	if err := n.LoadMerchantPatterns(strings.NewReader(`{"patterns": [`)); err == nil {
+		t.Errorf("a broken file was loaded")
+	}
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// MergeStatus is the outcome of merging one incoming block
+type MergeStatus string
+
+const (
+	MergeNew       MergeStatus = "new"
+	MergeDuplicate MergeStatus = "duplicate"
+	MergeConflict  MergeStatus = "conflict" // Probably a duplicate, the user decides
+)
+
+// MergeOptions tune how probable duplicates are found
+type MergeOptions struct {
+	Window        int     // Days a probable duplicate may be away from the incoming block
+	MinSimilarity float64 // Minimum concept score of a probable duplicate
+}
+
+var DefaultMergeOptions = MergeOptions{Window: 3, MinSimilarity: 0.6}
+
+// MergeEntry describes one incoming block. Accept starts true only for new
+// blocks; set it on conflicts the user wants to keep.
+type MergeEntry struct {
+	Status   MergeStatus
+	Incoming Block
+	Existing int     // Index in the existing blocks, -1 when there is none
+	Score    float64 // Concept similarity with the existing block
+	Reason   string
+	Accept   bool
+}
+
+type MergeReport struct {
+	Entries []MergeEntry
+}
+
+// Count returns how many entries have the given status
+func (r MergeReport) Count(status MergeStatus) int {
+	count := 0
+	for _, entry := range r.Entries {
+		if entry.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Merge compares incoming blocks, e.g. from a statement that overlaps the
+// ones already imported, with b and reports which are new. Every existing
+// block matches at most one incoming block, so same-day twins are only
+// reported as duplicates when both twins were already imported. Two blocks
+// whose balances differ are never the same movement.
+func (b Blocks) Merge(incoming Blocks, options MergeOptions) MergeReport {
+	report := MergeReport{Entries: make([]MergeEntry, len(incoming))}
+	claimed := make([]bool, len(b))
+	for i := range incoming {
+		report.Entries[i] = MergeEntry{Status: MergeNew, Incoming: incoming[i], Existing: -1, Reason: "no matching movement", Accept: true}
+	}
+
+	// Exact matches first, so they are not taken by a probable one
+	for i := range report.Entries {
+		entry := &report.Entries[i]
+		for j := range b {
+			if claimed[j] {
+				continue
+			}
+			if reason, ok := exactDuplicate(entry.Incoming, b[j]); ok {
+				claimed[j] = true
+				*entry = MergeEntry{Status: MergeDuplicate, Incoming: entry.Incoming, Existing: j, Score: 1, Reason: reason}
+				break
+			}
+			if reason, ok := externalIDConflict(entry.Incoming, b[j]); ok {
+				claimed[j] = true
+				*entry = MergeEntry{Status: MergeConflict, Incoming: entry.Incoming, Existing: j, Reason: reason}
+				break
+			}
+		}
+	}
+
+	for i := range report.Entries {
+		entry := &report.Entries[i]
+		if entry.Existing >= 0 {
+			continue
+		}
+
+		best, bestScore := -1, 0.0
+		for j := range b {
+			if claimed[j] {
+				continue
+			}
+			if score, ok := probableDuplicate(entry.Incoming, b[j], options); ok && score > bestScore {
+				best, bestScore = j, score
+			}
+		}
+		if best >= 0 {
+			claimed[best] = true
+			*entry = MergeEntry{
+				Status:   MergeConflict,
+				Incoming: entry.Incoming,
+				Existing: best,
+				Score:    bestScore,
+				Reason:   fmt.Sprintf("same amount as %q on %s", b[best].Concept.Name, b[best].FormatDate()),
+			}
+		}
+	}
+	return report
+}
+
+// ApplyMerge appends the accepted entries of a report made by Merge
+func (b *Blocks) ApplyMerge(report MergeReport) int {
+	added := 0
+	for _, entry := range report.Entries {
+		if entry.Accept {
+			b.AddBlock(entry.Incoming)
+			added++
+		}
+	}
+	return added
+}
+
+func exactDuplicate(incoming Block, existing Block) (string, bool) {
+	if incoming.ExternalID != "" && incoming.ExternalID == existing.ExternalID {
+		if SameDay(incoming.Date, existing.Date) && incoming.Amount.Equal(existing.Amount) {
+			return "same bank identifier", true
+		}
+		return "", false
+	}
+	if !SameDay(incoming.Date, existing.Date) || !incoming.Amount.Equal(existing.Amount) || !sameBalance(incoming, existing) {
+		return "", false
+	}
+
+	switch {
+	case NormalizeConcept(incoming.Concept.Name) == NormalizeConcept(existing.Concept.Name):
+		return "same date, amount and concept", true
+	case incoming.HasBalance() && existing.HasBalance():
+		// The balance pins the position in the statement
+		return "same date, amount and balance", true
+	}
+	return "", false
+}
+
+// externalIDConflict catches a bank identifier seen again with other data
+func externalIDConflict(incoming Block, existing Block) (string, bool) {
+	if incoming.ExternalID == "" || incoming.ExternalID != existing.ExternalID {
+		return "", false
+	}
+	return fmt.Sprintf("bank identifier %s already imported with other date or amount", incoming.ExternalID), true
+}
+
+func probableDuplicate(incoming Block, existing Block, options MergeOptions) (float64, bool) {
+	if !incoming.Amount.Equal(existing.Amount) || !sameBalance(incoming, existing) {
+		return 0, false
+	}
+	if incoming.ExternalID != "" && existing.ExternalID != "" {
+		return 0, false
+	}
+
+	days := Day(incoming.Date).Sub(Day(existing.Date)) / (24 * time.Hour)
+	if days < 0 {
+		days = -days
+	}
+	if int(days) > options.Window {
+		return 0, false
+	}
+
+	score := scoreConcept(incoming.Concept.Canonical(), existing.Concept.Canonical()).Score
+	return score, score >= options.MinSimilarity
+}
+
+// sameBalance is false only when both blocks have a balance and they differ
+func sameBalance(a Block, b Block) bool {
+	if !a.HasBalance() || !b.HasBalance() {
+		return true
+	}
+	return a.Balance.Equal(b.Balance)
+}
@@ -0,0 +1,80 @@
+package models
+
+import "testing"
+
+func TestMergeDuplicates(t *testing.T) {
+	existing := Blocks{
+		testBlock("MERCADONA", "2025-03-02", 1234),
+		testBlock("CAFE", "2025-03-03", 150),
+		testBlock("CAFE", "2025-03-03", 150),
+		testBlock("AMAZON MARKETPLACE", "2025-03-05", 2999),
+	}
+	withID := testBlock("NOMINA", "2025-03-01", -150000)
+	withID.ExternalID = "A1"
+	existing = append(existing, withID)
+
+	movedID := testBlock("NOMINA", "2025-03-02", -150000)
+	movedID.ExternalID = "A1"
+	incoming := Blocks{
+		testBlock("mercadona ", "2025-03-02", 1234), // Same movement, other spacing
+		testBlock("CAFE", "2025-03-03", 150),
+		testBlock("CAFE", "2025-03-03", 150),
+		testBlock("CAFE", "2025-03-03", 150),                // A third coffee that day
+		testBlock("AMAZON MKTPLACE", "2025-03-07", 2999),    // The bank posted it later
+		testBlock("AMAZON MARKETPLACE", "2025-03-12", 2999), // Out of the window
+		movedID,
+		testBlock("FARMACIA", "2025-03-04", 1234),
+	}
+
+	report := existing.Merge(incoming, DefaultMergeOptions)
+	want := []struct {
+		status   MergeStatus
+		existing int
+		accept   bool
+	}{
+		{MergeDuplicate, 0, false},
+		{MergeDuplicate, 1, false},
+		{MergeDuplicate, 2, false},
+		{MergeNew, -1, true},
+		{MergeConflict, 3, false},
+		{MergeNew, -1, true},
+		{MergeConflict, 4, false},
+		{MergeNew, -1, true},
+	}
+	for i, w := range want {
+		entry := report.Entries[i]
+		if entry.Status != w.status || entry.Existing != w.existing || entry.Accept != w.accept {
+			t.Errorf("entry %d %s: %s with %d accept %v (%s), want %s with %d accept %v", i, entry.Incoming.Concept.Name, entry.Status, entry.Existing, entry.Accept, entry.Reason, w.status, w.existing, w.accept)
+		}
+	}
+	if report.Count(MergeNew) != 3 || report.Count(MergeDuplicate) != 3 || report.Count(MergeConflict) != 2 {
+		t.Errorf("counts %d new, %d duplicate, %d conflict", report.Count(MergeNew), report.Count(MergeDuplicate), report.Count(MergeConflict))
+	}
+
+	// Accepting a conflict adds it with the new blocks
+	report.Entries[4].Accept = true
+	merged := append(Blocks{}, existing...)
+	if added := merged.ApplyMerge(report); added != 4 || len(merged) != len(existing)+4 {
+		t.Errorf("ApplyMerge added %d, %d blocks", added, len(merged))
+	}
+}
+
+func TestMergeBalances(t *testing.T) {
+	existing := Blocks{withBalance("TRANSFERENCIA", "2025-03-02", 5000, 100000), withBalance("BIZUM", "2025-03-02", 5000, 95000)}
+	incoming := Blocks{
+		withBalance("TRANSF. A JUAN", "2025-03-02", 5000, 95000), // The balance pins it to the second one
+		withBalance("TRANSFERENCIA", "2025-03-02", 5000, 90000),  // Another movement despite the same concept
+		testBlock("TRANSFERENCIA", "2025-03-02", 5000),           // Without balance the concept decides
+	}
+
+	report := existing.Merge(incoming, DefaultMergeOptions)
+	if entry := report.Entries[0]; entry.Status != MergeDuplicate || entry.Existing != 1 {
+		t.Errorf("same balance: %s with %d (%s)", entry.Status, entry.Existing, entry.Reason)
+	}
+	if entry := report.Entries[1]; entry.Status != MergeNew {
+		t.Errorf("other balance: %s with %d (%s)", entry.Status, entry.Existing, entry.Reason)
+	}
+	if entry := report.Entries[2]; entry.Status != MergeDuplicate || entry.Existing != 0 {
+		t.Errorf("no balance: %s with %d (%s)", entry.Status, entry.Existing, entry.Reason)
+	}
+}
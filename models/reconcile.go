@@ -0,0 +1,137 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"txeo-gui-library/money"
+)
+
+// BalanceBreak is a place where the bank balance does not follow from the
+// previous balance and the amounts in between, usually because a movement
+// is missing from the import.
+type BalanceBreak struct {
+	Previous Block       // Last block with a balance before the break
+	Current  Block       // Block whose balance does not match
+	Expected money.Money // Balance that Previous and the amounts give
+	Missing  money.Money // Amount of the missing movements, positive for expenses like Block.Amount
+	From     time.Time   // The missing movements happened between From and To
+	To       time.Time
+}
+
+func (b BalanceBreak) String() string {
+	return fmt.Sprintf("%s..%s: expected balance %s, bank says %s, missing %s",
+		b.From.Format(DateLayout), b.To.Format(DateLayout), b.Expected.Format(Locale), b.Current.Balance.Format(Locale), b.Missing.Format(Locale))
+}
+
+// DailyBalance is the balance at the end of a day
+type DailyBalance struct {
+	Date      time.Time
+	Balance   money.Money
+	Estimated bool // Computed from the amounts, the bank gave no balance that day
+}
+
+type Reconciliation struct {
+	Breaks  []BalanceBreak
+	Series  []DailyBalance
+	Checked int // Number of balances checked against the previous one
+}
+
+// OK reports whether every balance matched
+func (r Reconciliation) OK() bool {
+	return len(r.Breaks) == 0
+}
+
+// Reconcile walks the blocks of one account in date order and checks that
+// every bank balance equals the previous one minus the amounts in between
+// (amounts are positive for expenses). Blocks listed newest first, as
+// CaixaBank exports them, are walked backwards so the order within a day is
+// kept. Use Accounts.ReconcileAccounts when the blocks come from several
+// accounts.
+func (b Blocks) Reconcile() Reconciliation {
+	var result Reconciliation
+	ordered := b.chronological()
+
+	var previous *Block
+	var sinceBalance money.Money // Amounts after previous
+	for i := range ordered {
+		block := &ordered[i]
+		if !block.HasBalance() {
+			sinceBalance = sinceBalance.Add(block.Amount)
+			continue
+		}
+
+		if previous != nil {
+			result.Checked++
+			expected := previous.Balance.Sub(sinceBalance).Sub(block.Amount)
+			if !expected.Equal(block.Balance) {
+				result.Breaks = append(result.Breaks, BalanceBreak{
+					Previous: *previous,
+					Current:  *block,
+					Expected: expected,
+					Missing:  expected.Sub(block.Balance),
+					From:     Day(previous.Date),
+					To:       Day(block.Date),
+				})
+			}
+		}
+		previous = block
+		sinceBalance = money.Money{}
+	}
+
+	result.Series = ordered.dailyBalances()
+	return result
+}
+
+// chronological returns a copy sorted by date, keeping the statement order
+// within a day
+func (b Blocks) chronological() Blocks {
+	ordered := make(Blocks, len(b))
+	copy(ordered, b)
+	if len(ordered) > 1 && ordered[0].Date.After(ordered[len(ordered)-1].Date) {
+		for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool { return Day(ordered[i].Date).Before(Day(ordered[j].Date)) })
+	return ordered
+}
+
+// dailyBalances builds the end of day balance of every day between the
+// first and the last block. Bank balances are used when given; the others
+// are computed from the nearest one.
+func (b Blocks) dailyBalances() []DailyBalance {
+	first := -1
+	for i := range b {
+		if b[i].HasBalance() {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+
+	// Balance before the first block, worked back from the first known one
+	running := b[first].Balance
+	for i := first; i >= 0; i-- {
+		running = running.Add(b[i].Amount)
+	}
+
+	var series []DailyBalance
+	day := Day(b[0].Date)
+	estimated := true
+	for i := range b {
+		for current := Day(b[i].Date); day.Before(current); day = day.AddDate(0, 0, 1) {
+			series = append(series, DailyBalance{Date: day, Balance: running, Estimated: estimated})
+		}
+
+		running = running.Sub(b[i].Amount)
+		estimated = true
+		if b[i].HasBalance() {
+			running = b[i].Balance
+			estimated = false
+		}
+	}
+	return append(series, DailyBalance{Date: day, Balance: running, Estimated: estimated})
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"txeo-gui-library/money"
+)
+
+func withBalance(concept string, date string, minor int64, balance int64) Block {
+	block := testBlock(concept, date, minor)
+	block.Balance = money.New(balance, "EUR")
+	return block
+}
+
+func TestReconcileGaps(t *testing.T) {
+	blocks := Blocks{
+		withBalance("NOMINA", "2025-03-01", -100000, 100000),
+		testBlock("CAFE", "2025-03-02", 200), // No balance, counted in the next check
+		withBalance("SUPER", "2025-03-03", 5000, 94800),
+		withBalance("GASOLINA", "2025-03-06", 4000, 85800), // A 50.00 expense is missing
+		withBalance("BAR", "2025-03-07", 800, 85000),
+	}
+
+	result := blocks.Reconcile()
+	if result.Checked != 3 || result.OK() || len(result.Breaks) != 1 {
+		t.Fatalf("checked %d, breaks %v", result.Checked, result.Breaks)
+	}
+	gap := result.Breaks[0]
+	if gap.Previous.Concept.Name != "SUPER" || gap.Current.Concept.Name != "GASOLINA" {
+		t.Errorf("break between %q and %q, want SUPER and GASOLINA", gap.Previous.Concept.Name, gap.Current.Concept.Name)
+	}
+	if gap.Expected.Minor() != 90800 || gap.Missing.Minor() != 5000 {
+		t.Errorf("expected %s missing %s, want 908.00 and 50.00", gap.Expected, gap.Missing)
+	}
+	if gap.From.Format(DateLayout) != "2025-03-03" || gap.To.Format(DateLayout) != "2025-03-06" {
+		t.Errorf("gap %s..%s", gap.From.Format(DateLayout), gap.To.Format(DateLayout))
+	}
+	if !strings.Contains(gap.String(), "2025-03-03..2025-03-06") {
+		t.Errorf("String() = %q", gap.String())
+	}
+
+	// A missing income gives a negative Missing
+	income := Blocks{withBalance("A", "2025-03-01", 100, 1000), withBalance("B", "2025-03-02", 100, 1500)}
+	if result := income.Reconcile(); len(result.Breaks) != 1 || result.Breaks[0].Missing.Minor() != -600 {
+		t.Errorf("missing income: %v", result.Breaks)
+	}
+}
+
+func TestReconcileNewestFirst(t *testing.T) {
+	// CaixaBank lists the newest movement first, also within a day
+	blocks := Blocks{
+		withBalance("BAR", "2025-03-02", 300, 96700),
+		withBalance("SUPER", "2025-03-02", 3000, 97000),
+		withBalance("NOMINA", "2025-03-01", -100000, 100000),
+	}
+	if result := blocks.Reconcile(); !result.OK() || result.Checked != 2 {
+		t.Errorf("checked %d, breaks %v", result.Checked, result.Breaks)
+	}
+}
+
+func TestReconcileSeries(t *testing.T) {
+	blocks := Blocks{
+		testBlock("CAFE", "2025-03-01", 200),
+		withBalance("SUPER", "2025-03-02", 5000, 94800),
+		testBlock("BAR", "2025-03-04", 800),
+	}
+	series := blocks.Reconcile().Series
+	want := []struct {
+		date      string
+		balance   int64
+		estimated bool
+	}{
+		{"2025-03-01", 99800, true}, // Worked back from the first bank balance
+		{"2025-03-02", 94800, false},
+		{"2025-03-03", 94800, false}, // No movements, the bank balance holds
+		{"2025-03-04", 94000, true},
+	}
+	if len(series) != len(want) {
+		t.Fatalf("%d days, want %d", len(series), len(want))
+	}
+	for i, w := range want {
+		day := series[i]
+		if day.Date.Format(DateLayout) != w.date || day.Balance.Minor() != w.balance || day.Estimated != w.estimated {
+			t.Errorf("day %d: %s %s estimated %v, want %+v", i, day.Date.Format(DateLayout), day.Balance, day.Estimated, w)
+		}
+	}
+
+	if series := (Blocks{testBlock("CAFE", "2025-03-01", 200)}).Reconcile().Series; series != nil {
+		t.Errorf("series without balances %v", series)
+	}
+}
@@ -0,0 +1,335 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+	"txeo-gui-library/money"
+)
+
+// RecurrencePeriod is how often a recurring series repeats
+type RecurrencePeriod string
+
+const (
+	Weekly    RecurrencePeriod = "weekly"
+	Monthly   RecurrencePeriod = "monthly"
+	Quarterly RecurrencePeriod = "quarterly"
+	Yearly    RecurrencePeriod = "yearly"
+)
+
+// Misses in a row after the last occurrence that end a series
+const maxTrailingMisses = 2
+
+// Nominal length and allowed drift, in days, of every period
+var recurrencePeriods = []struct {
+	period    RecurrencePeriod
+	days      float64
+	tolerance float64
+}{
+	{Weekly, 7, 1},
+	{Monthly, 30.44, 4},
+	{Quarterly, 91.31, 10},
+	{Yearly, 365.25, 15},
+}
+
+// RecurringOptions tune FindRecurring
+type RecurringOptions struct {
+	MinOccurrences  int       // Blocks needed to call a series recurring
+	AmountTolerance float64   // Relative distance to the median amount, e.g. 0.25
+	PriceIncrease   float64   // Relative rise between occurrences reported as a price increase
+	AsOf            time.Time // Occurrences expected before this day and not found are missed; zero to skip
+}
+
+var DefaultRecurringOptions = RecurringOptions{MinOccurrences: 3, AmountTolerance: 0.25, PriceIncrease: 0.01}
+
+// PriceChange is an occurrence that costs more than the previous one
+type PriceChange struct {
+	Date time.Time
+	From money.Money
+	To   money.Money
+}
+
+// RecurringSeries is a direct debit, subscription or any other movement
+// that repeats with a regular period
+type RecurringSeries struct {
+	Merchant       string // Canonical concept shared by the occurrences
+	Account        string
+	Period         RecurrencePeriod
+	Occurrences    Blocks // In date order
+	AverageAmount  money.Money
+	LastAmount     money.Money
+	NextDate       time.Time
+	PriceIncreases []PriceChange
+	Missed         []time.Time // Expected dates without an occurrence
+	Active         bool        // False once too many occurrences in a row are missing, e.g. a cancelled subscription
+}
+type RecurringSeriesList []RecurringSeries
+
+// FindRecurring groups the blocks by account, canonical concept and sign and
+// keeps the groups whose amounts are close to their median and whose dates
+// follow a weekly, monthly, quarterly or yearly rhythm. Internal transfers
+// are ignored.
+func (b Blocks) FindRecurring(options RecurringOptions) RecurringSeriesList {
+	groups := map[[3]string]Blocks{}
+	var keys [][3]string
+	for i := 0; i < len(b); i++ {
+		if b[i].IsTransfer() || b[i].Amount.IsZero() {
+			continue
+		}
+		sign := "expense"
+		if b[i].Amount.IsNegative() {
+			sign = "income"
+		}
+		key := [3]string{b[i].Account, b[i].Concept.Canonical(), sign}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], b[i])
+	}
+
+	var list RecurringSeriesList
+	for _, key := range keys {
+		occurrences := groups[key].withinAmountTolerance(options.AmountTolerance)
+		if len(occurrences) < max(options.MinOccurrences, 2) {
+			continue
+		}
+		sort.SliceStable(occurrences, func(i, j int) bool { return occurrences[i].Date.Before(occurrences[j].Date) })
+
+		series, ok := newRecurringSeries(key[1], key[0], occurrences, options)
+		if ok {
+			list = append(list, series)
+		}
+	}
+
+	// Upcoming first, ended series last
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Active != list[j].Active {
+			return list[i].Active
+		}
+		return list[i].NextDate.Before(list[j].NextDate)
+	})
+	return list
+}
+
+// withinAmountTolerance drops the blocks whose amount is too far from the
+// median, such as a one-off purchase at a subscription's merchant
+func (b Blocks) withinAmountTolerance(tolerance float64) Blocks {
+	amounts := make([]int64, len(b))
+	for i := range b {
+		amounts[i] = b[i].Amount.Abs().Minor()
+	}
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i] < amounts[j] })
+	median := float64(amounts[len(amounts)/2])
+
+	var kept Blocks
+	for i := range b {
+		if math.Abs(float64(b[i].Amount.Abs().Minor())-median) <= median*tolerance {
+			kept = append(kept, b[i])
+		}
+	}
+	return kept
+}
+
+func newRecurringSeries(merchant string, account string, occurrences Blocks, options RecurringOptions) (RecurringSeries, bool) {
+	var intervals []float64
+	for i := 1; i < len(occurrences); i++ {
+		intervals = append(intervals, Day(occurrences[i].Date).Sub(Day(occurrences[i-1].Date)).Hours()/24)
+	}
+	sorted := append([]float64{}, intervals...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	for _, p := range recurrencePeriods {
+		if math.Abs(median-p.days) > p.tolerance {
+			continue
+		}
+
+		// Intervals must be whole periods; a gap of k periods means k-1 misses
+		irregular := 0
+		for _, interval := range intervals {
+			k := math.Round(interval / p.days)
+			if k == 0 || math.Abs(interval-k*p.days) > p.tolerance*k {
+				irregular++
+			}
+		}
+		if irregular*5 > len(intervals) {
+			return RecurringSeries{}, false
+		}
+
+		series := RecurringSeries{
+			Merchant:    merchant,
+			Account:     account,
+			Period:      p.period,
+			Occurrences: occurrences,
+			LastAmount:  occurrences[len(occurrences)-1].Amount,
+		}
+		series.AverageAmount = occurrences.averageAmount()
+		series.PriceIncreases = occurrences.priceIncreases(options.PriceIncrease)
+		series.findMissed(p.tolerance, options.AsOf)
+		return series, true
+	}
+	return RecurringSeries{}, false
+}
+
+func (b Blocks) averageAmount() money.Money {
+	var total money.Money
+	for i := range b {
+		total = total.Add(b[i].Amount)
+	}
+	return total.Divide(int64(len(b)), money.RoundHalfEven)
+}
+
+func (b Blocks) priceIncreases(threshold float64) []PriceChange {
+	var changes []PriceChange
+	for i := 1; i < len(b); i++ {
+		from, to := b[i-1].Amount.Abs(), b[i].Amount.Abs()
+		if float64(to.Minor()) > float64(from.Minor())*(1+threshold) {
+			changes = append(changes, PriceChange{Date: b[i].Date, From: b[i-1].Amount, To: b[i].Amount})
+		}
+	}
+	return changes
+}
+
+// next returns the date one period after t, on the day of month of the
+// series
+func (s RecurringSeries) next(t time.Time) time.Time {
+	return nextOccurrence(t, s.Period, s.dayOfMonth(t))
+}
+
+// dayOfMonth is the most common day of month of the occurrences, the later
+// one on a tie, so a series on the 31st stays there after February. A
+// series without occurrences keeps the day of t.
+func (s RecurringSeries) dayOfMonth(t time.Time) int {
+	counts := map[int]int{}
+	day := t.Day()
+	for i := range s.Occurrences {
+		d := s.Occurrences[i].Date.Day()
+		counts[d]++
+		if counts[d] > counts[day] || (counts[d] == counts[day] && d > day) {
+			day = d
+		}
+	}
+	return day
+}
+
+// nextOccurrence returns the date one period after t. Monthly, quarterly and
+// yearly periods land on day, or on the last day of shorter months, and
+// count from the date on day nearest to t, so an occurrence moved a few days
+// (e.g. to the next working day) does not move the ones after it.
+func nextOccurrence(t time.Time, period RecurrencePeriod, day int) time.Time {
+	months := 1
+	switch period {
+	case Weekly:
+		return t.AddDate(0, 0, 7)
+	case Quarterly:
+		months = 3
+	case Yearly:
+		months = 12
+	}
+
+	nearest := dateOnDay(t, t.Month()-1, day)
+	for _, month := range []time.Month{t.Month(), t.Month() + 1} {
+		if candidate := dateOnDay(t, month, day); absDuration(candidate.Sub(t)) < absDuration(nearest.Sub(t)) {
+			nearest = candidate
+		}
+	}
+	return dateOnDay(nearest, nearest.Month()+time.Month(months), day)
+}
+
+// dateOnDay returns day of the given month of the year of t (months out of
+// 1..12 roll over the year), clamped to the last day of the month
+func dateOnDay(t time.Time, month time.Month, day int) time.Time {
+	first := time.Date(t.Year(), month, 1, 0, 0, 0, 0, t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return time.Date(first.Year(), first.Month(), min(day, last), 0, 0, 0, 0, t.Location())
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// findMissed walks the expected dates from the last seen occurrence and sets
+// Missed and NextDate
+func (s *RecurringSeries) findMissed(tolerance float64, asOf time.Time) {
+	window := time.Duration(tolerance*24) * time.Hour
+	last := Day(s.Occurrences[0].Date)
+	for i := 1; i < len(s.Occurrences); i++ {
+		current := Day(s.Occurrences[i].Date)
+		for expected := s.next(last); expected.Add(window).Before(current); expected = s.next(expected) {
+			s.Missed = append(s.Missed, expected)
+			last = expected
+		}
+		last = current
+	}
+
+	s.NextDate = s.next(last)
+	s.Active = true
+	if asOf.IsZero() {
+		return
+	}
+	asOf = Day(asOf)
+	for trailing := 0; s.NextDate.Add(window).Before(asOf); trailing++ {
+		if trailing == maxTrailingMisses {
+			s.Active = false
+			s.NextDate = time.Time{}
+			return
+		}
+		s.Missed = append(s.Missed, s.NextDate)
+		s.NextDate = s.next(s.NextDate)
+	}
+}
+
+// ExpectedBetween returns the expected dates from NextDate on that fall in
+// [from, to). Ended series expect nothing.
+func (s RecurringSeries) ExpectedBetween(from time.Time, to time.Time) []time.Time {
+	var dates []time.Time
+	if !s.Active {
+		return dates
+	}
+	for expected := s.NextDate; expected.Before(to); expected = s.next(expected) {
+		if !expected.Before(from) {
+			dates = append(dates, expected)
+		}
+	}
+	return dates
+}
+
+// Expenses returns the series we pay, such as subscriptions and direct debits
+func (list RecurringSeriesList) Expenses() RecurringSeriesList {
+	return list.filter(func(s RecurringSeries) bool { return s.LastAmount.IsPositive() })
+}
+
+func (list RecurringSeriesList) ByPeriod(period RecurrencePeriod) RecurringSeriesList {
+	return list.filter(func(s RecurringSeries) bool { return s.Period == period })
+}
+
+// Due returns the series expected at least once in [from, to)
+func (list RecurringSeriesList) Due(from time.Time, to time.Time) RecurringSeriesList {
+	return list.filter(func(s RecurringSeries) bool { return len(s.ExpectedBetween(from, to)) > 0 })
+}
+
+func (list RecurringSeriesList) WithPriceIncreases() RecurringSeriesList {
+	return list.filter(func(s RecurringSeries) bool { return len(s.PriceIncreases) > 0 })
+}
+
+// Active returns the series that have not ended
+func (list RecurringSeriesList) Active() RecurringSeriesList {
+	return list.filter(func(s RecurringSeries) bool { return s.Active })
+}
+
+func (list RecurringSeriesList) WithMissed() RecurringSeriesList {
+	return list.filter(func(s RecurringSeries) bool { return len(s.Missed) > 0 })
+}
+
+func (list RecurringSeriesList) filter(keep func(RecurringSeries) bool) RecurringSeriesList {
+	var filtered RecurringSeriesList
+	for _, series := range list {
+		if keep(series) {
+			filtered = append(filtered, series)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"testing"
+	"time"
+	"txeo-gui-library/money"
+)
+
+func mustDay(s string) time.Time {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func testBlock(concept string, date string, minor int64) Block {
+	return *NewBlockWithMoney(concept, mustDay(date), "", money.New(minor, "EUR"), money.Money{})
+}
+
+func testBlocks(concept string, minor int64, dates ...string) Blocks {
+	var blocks Blocks
+	for _, date := range dates {
+		blocks = append(blocks, testBlock(concept, date, minor))
+	}
+	return blocks
+}
+
+func formatDates(dates []time.Time) []string {
+	formatted := []string{}
+	for _, date := range dates {
+		formatted = append(formatted, date.Format(DateLayout))
+	}
+	return formatted
+}
+
+func sameDates(dates []time.Time, want ...string) bool {
+	formatted := formatDates(dates)
+	if len(formatted) != len(want) {
+		return false
+	}
+	for i := range want {
+		if formatted[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		from   string
+		period RecurrencePeriod
+		day    int
+		want   string
+	}{
+		{"2025-01-31", Monthly, 31, "2025-02-28"},
+		{"2025-02-28", Monthly, 31, "2025-03-31"}, // Back on the 31st after February
+		{"2024-02-29", Monthly, 31, "2024-03-31"},
+		{"2025-03-03", Monthly, 31, "2025-03-31"}, // A February payment moved to March
+		{"2025-12-31", Monthly, 31, "2026-01-31"},
+		{"2025-01-15", Monthly, 15, "2025-02-15"},
+		{"2025-11-30", Quarterly, 30, "2026-02-28"},
+		{"2026-02-28", Quarterly, 30, "2026-05-30"},
+		{"2024-02-29", Yearly, 29, "2025-02-28"},
+		{"2025-02-28", Yearly, 29, "2026-02-28"},
+		{"2025-01-31", Weekly, 31, "2025-02-07"},
+	}
+	for _, test := range tests {
+		if got := nextOccurrence(mustDay(test.from), test.period, test.day); got.Format(DateLayout) != test.want {
+			t.Errorf("nextOccurrence(%s, %s, %d) = %s, want %s", test.from, test.period, test.day, got.Format(DateLayout), test.want)
+		}
+	}
+}
+
+func TestFindRecurringMonthEnd(t *testing.T) {
+	blocks := testBlocks("SPOTIFY", 999, "2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30", "2025-05-31")
+	options := DefaultRecurringOptions
+	options.AsOf = mustDay("2025-06-10")
+
+	list := blocks.FindRecurring(options)
+	if len(list) != 1 {
+		t.Fatalf("%d series, want 1", len(list))
+	}
+	series := list[0]
+	if series.Period != Monthly || !series.Active || len(series.Missed) != 0 || series.NextDate.Format(DateLayout) != "2025-06-30" {
+		t.Errorf("series %s active %v missed %v next %s", series.Period, series.Active, formatDates(series.Missed), series.NextDate.Format(DateLayout))
+	}
+	expected := series.ExpectedBetween(mustDay("2025-06-01"), mustDay("2026-01-01"))
+	if !sameDates(expected, "2025-06-30", "2025-07-31", "2025-08-31", "2025-09-30", "2025-10-31", "2025-11-30", "2025-12-31") {
+		t.Errorf("expected %v", formatDates(expected))
+	}
+
+	// A payment moved to the next working day does not shift the series
+	moved := testBlocks("SPOTIFY", 999, "2025-01-31", "2025-03-03", "2025-03-31", "2025-04-30")
+	options.AsOf = mustDay("2025-05-20")
+	list = moved.FindRecurring(options)
+	if len(list) != 1 || len(list[0].Missed) != 0 || list[0].NextDate.Format(DateLayout) != "2025-05-31" {
+		t.Errorf("moved payment: %d series, missed %v next %s", len(list), formatDates(list[0].Missed), list[0].NextDate.Format(DateLayout))
+	}
+}
+
+func TestFindRecurringMissed(t *testing.T) {
+	blocks := testBlocks("GIMNASIO", 3500, "2025-01-10", "2025-02-10", "2025-04-10", "2025-05-10")
+	list := blocks.FindRecurring(DefaultRecurringOptions)
+	if len(list) != 1 || !sameDates(list[0].Missed, "2025-03-10") || list[0].NextDate.Format(DateLayout) != "2025-06-10" {
+		t.Fatalf("series %+v, want March missed", list)
+	}
+
+	tests := []struct {
+		asOf   string
+		active bool
+		missed []string
+	}{
+		{"2025-06-12", true, []string{"2025-03-10"}},                             // Within the tolerance
+		{"2025-06-20", true, []string{"2025-03-10", "2025-06-10"}},               // One trailing miss
+		{"2025-07-20", true, []string{"2025-03-10", "2025-06-10", "2025-07-10"}}, // Two
+		{"2025-08-20", false, []string{"2025-03-10", "2025-06-10", "2025-07-10"}},
+	}
+	for _, test := range tests {
+		options := DefaultRecurringOptions
+		options.AsOf = mustDay(test.asOf)
+		list := blocks.FindRecurring(options)
+		if len(list) != 1 {
+			t.Errorf("as of %s: %d series", test.asOf, len(list))
+			continue
+		}
+		if list[0].Active != test.active || !sameDates(list[0].Missed, test.missed...) {
+			t.Errorf("as of %s: active %v missed %v, want %v %v", test.asOf, list[0].Active, formatDates(list[0].Missed), test.active, test.missed)
+		}
+		if !test.active && (!list[0].NextDate.IsZero() || len(list[0].ExpectedBetween(mustDay("2025-01-01"), mustDay("2026-01-01"))) != 0) {
+			t.Errorf("as of %s: an ended series expects %s", test.asOf, list[0].NextDate)
+		}
+	}
+}
+
+func TestFindRecurringGrouping(t *testing.T) {
+	blocks := testBlocks("NETFLIX", 1299, "2025-01-05", "2025-02-05", "2025-03-05")
+	blocks = append(blocks, testBlock("NETFLIX", "2025-02-20", 8000)) // One-off purchase, far from the median
+	blocks = append(blocks, testBlock("NETFLIX", "2025-04-05", 1399)) // Price increase
+	blocks = append(blocks, testBlocks("NOMINA", -150000, "2025-01-28", "2025-02-28", "2025-03-28")...)
+	blocks = append(blocks, testBlocks("CAFE", 250, "2025-01-02", "2025-01-03", "2025-01-20", "2025-03-01")...) // Irregular
+	weekly := testBlocks("CLASE", 2000, "2025-03-03", "2025-03-10", "2025-03-17", "2025-03-24")
+	blocks = append(blocks, weekly...)
+	transfers := testBlocks("HUCHA", 5000, "2025-01-01", "2025-02-01", "2025-03-01")
+	for i := range transfers {
+		transfers[i].TransferID = "T"
+	}
+	blocks = append(blocks, transfers...)
+
+	list := blocks.FindRecurring(DefaultRecurringOptions)
+	byMerchant := map[string]RecurringSeries{}
+	for _, series := range list {
+		byMerchant[series.Merchant] = series
+	}
+	if len(list) != 3 {
+		t.Errorf("%d series %v, want NETFLIX, NOMINA and CLASE", len(list), byMerchant)
+	}
+
+	netflix := byMerchant[testBlock("NETFLIX", "2025-01-01", 0).Concept.Canonical()]
+	if len(netflix.Occurrences) != 4 || netflix.LastAmount.Minor() != 1399 || len(netflix.PriceIncreases) != 1 || netflix.PriceIncreases[0].To.Minor() != 1399 {
+		t.Errorf("netflix %d occurrences, last %s, increases %v", len(netflix.Occurrences), netflix.LastAmount, netflix.PriceIncreases)
+	}
+	if netflix.AverageAmount.Minor() != 1324 {
+		t.Errorf("netflix average %s, want 13.24", netflix.AverageAmount)
+	}
+	if series := byMerchant[testBlock("CLASE", "2025-01-01", 0).Concept.Canonical()]; series.Period != Weekly || series.NextDate.Format(DateLayout) != "2025-03-31" {
+		t.Errorf("weekly series %s next %s", series.Period, series.NextDate)
+	}
+
+	if expenses := list.Expenses(); len(expenses) != 2 {
+		t.Errorf("%d expense series, want 2 without the salary", len(expenses))
+	}
+	if due := list.Due(mustDay("2025-03-29"), mustDay("2025-04-29")); len(due) != 2 {
+		t.Errorf("%d series due, want the salary and the weekly class", len(due))
+	}
+	if len(list.WithPriceIncreases()) != 1 || len(list.ByPeriod(Weekly)) != 1 {
+		t.Errorf("filters: %d with increases, %d weekly", len(list.WithPriceIncreases()), len(list.ByPeriod(Weekly)))
+	}
+}
@@ -0,0 +1,321 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"txeo-gui-library/money"
+)
+
+// MatchType tells how a Rule compares its pattern with a text
+type MatchType string
+
+const (
+	MatchExact      MatchType = "exact"
+	MatchPrefix     MatchType = "prefix"
+	MatchContains   MatchType = "contains"
+	MatchRegex      MatchType = "regex"
+	MatchNormalized MatchType = "normalized" // Equal after NormalizeConcept
+)
+
+// AmountSign restricts a Rule to expenses or income
+type AmountSign string
+
+const (
+	AnySign     AmountSign = ""
+	ExpenseSign AmountSign = "expense" // Amount > 0
+	IncomeSign  AmountSign = "income"  // Amount < 0
+)
+
+// Rule assigns CategoryShortName to the blocks that meet all its conditions.
+// Empty conditions always hold. MinAmount and MaxAmount compare against the
+// absolute Amount and are ignored when they have no currency (zero value).
+// With LeaveUncategorized the block is left without category, to force a
+// manual choice.
+type Rule struct {
+	Name               string         `json:"name"`
+	Priority           int            `json:"priority"`
+	CategoryShortName  string         `json:"category,omitempty"`
+	LeaveUncategorized bool           `json:"leaveUncategorized,omitempty"`
+	Concept            string         `json:"concept,omitempty"`
+	Match              MatchType      `json:"match,omitempty"`
+	Concept2           string         `json:"concept2,omitempty"`
+	Concept2Match      MatchType      `json:"concept2Match,omitempty"`
+	Sign               AmountSign     `json:"sign,omitempty"`
+	MinAmount          money.Money    `json:"-"`
+	MaxAmount          money.Money    `json:"-"`
+	Weekdays           []time.Weekday `json:"weekdays,omitempty"`
+}
+
+type Rules []Rule
+
+// RuleTrace records why a rule did or did not match a block
+type RuleTrace struct {
+	Rule    Rule
+	Matched bool
+	Reason  string
+}
+
+// RuleExplanation is the result of Rules.Explain
+type RuleExplanation struct {
+	Matched  *Rule
+	Category Category
+	Trace    []RuleTrace
+}
+
+func (e RuleExplanation) String() string {
+	var lines []string
+	for _, trace := range e.Trace {
+		status := "✗"
+		if trace.Matched {
+			status = "✓"
+		}
+		lines = append(lines, fmt.Sprintf("%s [%d] %s: %s", status, trace.Rule.Priority, trace.Rule.Name, trace.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DefaultRules are checked before the concepts stored in the categories.
+var DefaultRules = Rules{
+	{
+		Name:               "Hucha DIGI transfers are categorized by hand",
+		Priority:           100,
+		Concept:            "TRANSFER.HUCHA DIGI",
+		Match:              MatchExact,
+		LeaveUncategorized: true,
+	},
+}
+
+// LoadRules reads rules from JSON. Amount limits are given as decimal strings
+// with a currency, e.g. {"minAmount": "10.00", "currency": "EUR"}.
+func LoadRules(r io.Reader) (Rules, error) {
+	var raw []struct {
+		Rule
+		MinAmount string `json:"minAmount"`
+		MaxAmount string `json:"maxAmount"`
+		Currency  string `json:"currency"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	rules := make(Rules, 0, len(raw))
+	for _, item := range raw {
+		rule := item.Rule
+		currency := item.Currency
+		if currency == "" {
+			currency = Locale.Currency
+		}
+		if item.MinAmount != "" {
+			amount, err := money.ParseDecimal(item.MinAmount, currency)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+			rule.MinAmount = amount
+		}
+		if item.MaxAmount != "" {
+			amount, err := money.ParseDecimal(item.MaxAmount, currency)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+			rule.MaxAmount = amount
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rules.Validate()
+}
+
+// Validate checks that every regex compiles.
+func (rules Rules) Validate() error {
+	for _, rule := range rules {
+		for _, pair := range [][2]string{{rule.Concept, string(rule.Match)}, {rule.Concept2, string(rule.Concept2Match)}} {
+			if MatchType(pair[1]) == MatchRegex {
+				if _, err := compileRuleRegex(pair[0]); err != nil {
+					return fmt.Errorf("rule %q: %w", rule.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RulesFromCategories turns every stored concept into an exact match rule
+// with priority 0, which is how TryToAssignCategory used to work.
+func RulesFromCategories(categories Categories) Rules {
+	var rules Rules
+	for _, category := range categories {
+		if category.Deleted {
+			continue
+		}
+		for _, concept := range category.Concepts {
+			rules = append(rules, Rule{
+				Name:              "concept " + concept.Name,
+				CategoryShortName: category.ShortName,
+				Concept:           concept.Name,
+				Match:             MatchExact,
+			})
+		}
+	}
+	return rules
+}
+
+// Sorted returns the rules by descending priority, keeping the given order
+// for equal priorities.
+func (rules Rules) Sorted() Rules {
+	sorted := make(Rules, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority > sorted[j].Priority })
+	return sorted
+}
+
+// Categorize returns the category of the first matching rule, or an empty
+// Category when no rule matches (or the rule leaves the block uncategorized).
+func (rules Rules) Categorize(b Block, categories Categories) (Category, *Rule) {
+	explanation := rules.evaluate(b, categories, false)
+	return explanation.Category, explanation.Matched
+}
+
+// Explain evaluates the rules like Categorize and records the outcome of
+// every rule tried, so the UI can show why a block got its category.
+func (rules Rules) Explain(b Block, categories Categories) RuleExplanation {
+	return rules.evaluate(b, categories, true)
+}
+
+func (rules Rules) evaluate(b Block, categories Categories, trace bool) RuleExplanation {
+	var explanation RuleExplanation
+	for _, rule := range rules.Sorted() {
+		reason, ok := rule.matches(b)
+
+		var category Category
+		if ok && !rule.LeaveUncategorized {
+			var found bool
+			category, found = findCategory(categories, rule.CategoryShortName)
+			if !found {
+				ok, reason = false, fmt.Sprintf("category %q not found", rule.CategoryShortName)
+			}
+		}
+
+		if trace {
+			explanation.Trace = append(explanation.Trace, RuleTrace{Rule: rule, Matched: ok, Reason: reason})
+		}
+		if ok {
+			matched := rule
+			explanation.Matched = &matched
+			explanation.Category = category
+			return explanation
+		}
+	}
+	return explanation
+}
+
+// matches checks the conditions in order and returns the first that fails.
+func (rule Rule) matches(b Block) (string, bool) {
+	if rule.Concept != "" && !matchText(rule.Match, rule.Concept, b.Concept.Name) {
+		return fmt.Sprintf("concept %q does not match %s %q", b.Concept.Name, matchName(rule.Match), rule.Concept), false
+	}
+	if rule.Concept2 != "" && !matchText(rule.Concept2Match, rule.Concept2, b.Concept2) {
+		return fmt.Sprintf("concept2 %q does not match %s %q", b.Concept2, matchName(rule.Concept2Match), rule.Concept2), false
+	}
+
+	switch rule.Sign {
+	case ExpenseSign:
+		if !b.Amount.IsPositive() {
+			return "not an expense", false
+		}
+	case IncomeSign:
+		if !b.Amount.IsNegative() {
+			return "not income", false
+		}
+	}
+
+	amount := b.Amount.Abs()
+	if rule.MinAmount.Currency() != "" && (!amount.SameCurrency(rule.MinAmount) || amount.LessThan(rule.MinAmount)) {
+		return fmt.Sprintf("amount %s below %s", amount, rule.MinAmount), false
+	}
+	if rule.MaxAmount.Currency() != "" && (!amount.SameCurrency(rule.MaxAmount) || amount.GreaterThan(rule.MaxAmount)) {
+		return fmt.Sprintf("amount %s above %s", amount, rule.MaxAmount), false
+	}
+
+	if len(rule.Weekdays) > 0 {
+		found := false
+		for _, weekday := range rule.Weekdays {
+			found = found || b.Date.Weekday() == weekday
+		}
+		if !found {
+			return fmt.Sprintf("%s is not one of the rule weekdays", b.Date.Weekday()), false
+		}
+	}
+
+	return "all conditions met", true
+}
+
+func matchName(match MatchType) string {
+	if match == "" {
+		return string(MatchExact)
+	}
+	return string(match)
+}
+
+func matchText(match MatchType, pattern string, text string) bool {
+	switch match {
+	case MatchPrefix:
+		return strings.HasPrefix(strings.ToUpper(text), strings.ToUpper(pattern))
+	case MatchContains:
+		return strings.Contains(strings.ToUpper(text), strings.ToUpper(pattern))
+	case MatchRegex:
+		re, err := compileRuleRegex(pattern)
+		return err == nil && re.MatchString(text)
+	case MatchNormalized:
+		return NormalizeConcept(text) == NormalizeConcept(pattern)
+	default:
+		return text == pattern
+	}
+}
+
+var (
+	ruleRegexCache = map[string]*regexp.Regexp{}
+	ruleRegexMutex sync.Mutex
+)
+
+func compileRuleRegex(pattern string) (*regexp.Regexp, error) {
+	ruleRegexMutex.Lock()
+	defer ruleRegexMutex.Unlock()
+
+	if re, ok := ruleRegexCache[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	ruleRegexCache[pattern] = re
+	return re, nil
+}
+
+var (
+	accentReplacer   = strings.NewReplacer("Á", "A", "É", "E", "Í", "I", "Ó", "O", "Ú", "U", "Ü", "U", "Ñ", "N", "Ç", "C", "À", "A", "È", "E", "Ò", "O")
+	nonLetterPattern = regexp.MustCompile(`[^A-Z]+`)
+)
+
+// NormalizeConcept uppercases a concept, removes accents, digits and
+// punctuation and collapses spaces, so "Compra Mercadona 1234" and
+// "COMPRA MERCADONA 5678" both become "COMPRA MERCADONA".
+func NormalizeConcept(s string) string {
+	s = accentReplacer.Replace(strings.ToUpper(s))
+	s = nonLetterPattern.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func findCategory(categories Categories, shortName string) (Category, bool) {
+	for _, category := range categories {
+		if category.ShortName == shortName && !category.Deleted {
+			return category, true
+		}
+	}
+	return Category{}, false
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"txeo-gui-library/money"
+)
+
+var (
+	ErrSplitSum      = errors.New("split amounts do not add up to the block amount")
+	ErrSplitCurrency = errors.New("split currency differs from the block currency")
+	ErrSplitTooFew   = errors.New("a split needs at least two lines")
+	ErrSplitZero     = errors.New("split line with zero amount")
+	ErrSplitSign     = errors.New("split line with the opposite sign of the block amount")
+	ErrSplitIndex    = errors.New("split line does not exist")
+)
+
+// Split is the part of a block amount that goes to one category
+type Split struct {
+	Category Category
+	Amount   money.Money // Same sign convention as Block.Amount
+	Note     string
+}
+type Splits []Split
+
+// Validate checks that the lines are at least two, have no zero amounts,
+// use the block currency and sign, and add up exactly to amount.
+func (splits Splits) Validate(amount money.Money) error {
+	if len(splits) < 2 {
+		return ErrSplitTooFew
+	}
+
+	total := money.New(0, amount.Currency())
+	for i, split := range splits {
+		if split.Amount.Currency() != amount.Currency() {
+			return fmt.Errorf("%w: line %d is %s", ErrSplitCurrency, i, split.Amount.Currency())
+		}
+		if split.Amount.IsZero() {
+			return fmt.Errorf("%w: line %d", ErrSplitZero, i)
+		}
+		if split.Amount.IsNegative() != amount.IsNegative() {
+			return fmt.Errorf("%w: line %d is %s", ErrSplitSign, i, split.Amount)
+		}
+		total = total.Add(split.Amount)
+	}
+	if !total.Equal(amount) {
+		return fmt.Errorf("%w: %s instead of %s", ErrSplitSum, total, amount)
+	}
+	return nil
+}
+
+// IsSplit reports whether the block amount is shared by several categories
+func (b Block) IsSplit() bool {
+	return len(b.Splits) > 0
+}
+
+// Parts returns the split lines, or a single line with the block category
+// and amount when the block is not split. Aggregations go through Parts.
+func (b Block) Parts() Splits {
+	if b.IsSplit() {
+		return b.Splits
+	}
+	return Splits{{Category: b.Category, Amount: b.Amount}}
+}
+
+// PrimaryCategory is the category of the largest line, used to style a
+// split block as a whole
+func (b Block) PrimaryCategory() Category {
+	parts := b.Parts()
+	primary := parts[0]
+	for _, part := range parts[1:] {
+		if part.Amount.Abs().GreaterThan(primary.Amount.Abs()) {
+			primary = part
+		}
+	}
+	return primary.Category
+}
+
+// SetSplits replaces the split lines after validating them
+func (b *Block) SetSplits(splits Splits) error {
+	if err := splits.Validate(b.Amount); err != nil {
+		return err
+	}
+	b.Splits = append(Splits{}, splits...)
+	return nil
+}
+
+// AddSplit moves amount from the first line (the block category when it is
+// not split yet) to a new line of the given category.
+func (b *Block) AddSplit(category Category, amount money.Money, note string) error {
+	splits := append(Splits{}, b.Parts()...)
+	if err := b.checkSplitCurrency(len(splits), amount); err != nil {
+		return err
+	}
+	splits[0].Amount = splits[0].Amount.Sub(amount)
+	splits = append(splits, Split{Category: category, Amount: amount, Note: note})
+	return b.SetSplits(splits)
+}
+
+// EditSplit replaces line i. A change of amount is balanced on the first
+// line, so the first line itself can only change category and note.
+func (b *Block) EditSplit(i int, split Split) error {
+	if i < 0 || i >= len(b.Splits) {
+		return fmt.Errorf("%w: %d", ErrSplitIndex, i)
+	}
+
+	splits := append(Splits{}, b.Splits...)
+	if i == 0 {
+		split.Amount = splits[0].Amount
+	} else {
+		if err := b.checkSplitCurrency(i, split.Amount); err != nil {
+			return err
+		}
+		splits[0].Amount = splits[0].Amount.Add(splits[i].Amount).Sub(split.Amount)
+	}
+	splits[i] = split
+	return b.SetSplits(splits)
+}
+
+// checkSplitCurrency returns ErrSplitCurrency, as Validate would, before
+// the amount of line i is added to the others
+func (b *Block) checkSplitCurrency(i int, amount money.Money) error {
+	if amount.Currency() != b.Amount.Currency() {
+		return fmt.Errorf("%w: line %d is %s", ErrSplitCurrency, i, amount.Currency())
+	}
+	return nil
+}
+
+// RemoveSplit removes line i, giving its amount back to the first line.
+// When one line is left the block is no longer split and takes its
+// category.
+func (b *Block) RemoveSplit(i int) error {
+	if i <= 0 || i >= len(b.Splits) {
+		return fmt.Errorf("%w: %d", ErrSplitIndex, i)
+	}
+
+	splits := append(Splits{}, b.Splits...)
+	splits[0].Amount = splits[0].Amount.Add(splits[i].Amount)
+	splits = append(splits[:i], splits[i+1:]...)
+	if len(splits) == 1 {
+		b.ClearSplits()
+		b.Category = splits[0].Category
+		return nil
+	}
+	return b.SetSplits(splits)
+}
+
+// ClearSplits puts the whole amount back on the block category
+func (b *Block) ClearSplits() {
+	b.Splits = nil
+}
+
+// ByCategory returns one block per part, with the amount and category of
+// the part, so per category sums work for split blocks too
+func (b Blocks) ByCategory() map[string]Blocks {
+	categories := map[string]Blocks{}
+	for i := range b {
+		for _, part := range b[i].Parts() {
+			categories[part.Category.ShortName] = append(categories[part.Category.ShortName], b[i].asPart(part))
+		}
+	}
+	return categories
+}
+
+// TotalsByCategory sums the amounts of every category per currency,
+// skipping internal transfers
+func (b Blocks) TotalsByCategory() map[string]money.Totals {
+	totals := map[string]money.Totals{}
+	for category, blocks := range b.ByCategory() {
+		totals[category] = blocks.TotalsByCurrency()
+	}
+	return totals
+}
+
+// asPart is the block narrowed to one of its parts
+func (b Block) asPart(part Split) Block {
+	b.Category = part.Category
+	b.Amount = part.Amount
+	b.Splits = nil
+	return b
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"txeo-gui-library/money"
+)
+
+func TestSplitsValidate(t *testing.T) {
+	food, home := Category{ShortName: "food"}, Category{ShortName: "home"}
+	amount := money.New(9000, "EUR")
+	tests := []struct {
+		name   string
+		splits Splits
+		want   error
+	}{
+		{"ok", Splits{{Category: food, Amount: money.New(6000, "EUR")}, {Category: home, Amount: money.New(3000, "EUR")}}, nil},
+		{"one line", Splits{{Category: food, Amount: amount}}, ErrSplitTooFew},
+		{"short", Splits{{Category: food, Amount: money.New(6000, "EUR")}, {Category: home, Amount: money.New(2000, "EUR")}}, ErrSplitSum},
+		{"zero", Splits{{Category: food, Amount: amount}, {Category: home, Amount: money.New(0, "EUR")}}, ErrSplitZero},
+		{"currency", Splits{{Category: food, Amount: money.New(6000, "EUR")}, {Category: home, Amount: money.New(3000, "USD")}}, ErrSplitCurrency},
+		{"sign", Splits{{Category: food, Amount: money.New(10000, "EUR")}, {Category: home, Amount: money.New(-1000, "EUR")}}, ErrSplitSign},
+	}
+	for _, test := range tests {
+		if err := test.splits.Validate(amount); !errors.Is(err, test.want) || (test.want == nil && err != nil) {
+			t.Errorf("%s: %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestEditSplits(t *testing.T) {
+	food, home, fun := Category{ShortName: "food"}, Category{ShortName: "home"}, Category{ShortName: "fun"}
+	block := categorized("MERCADONA", "2025-03-02", 9000, "food")
+	if block.IsSplit() || len(block.Parts()) != 1 || block.Parts()[0].Amount.Minor() != 9000 {
+		t.Fatalf("an unsplit block has parts %+v", block.Parts())
+	}
+
+	// New lines take their amount from the first one
+	if err := block.AddSplit(home, money.New(3000, "EUR"), "bombillas"); err != nil {
+		t.Fatal(err)
+	}
+	if err := block.AddSplit(fun, money.New(1000, "EUR"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if amounts := splitAmounts(block); amounts != [3]int64{5000, 3000, 1000} || block.Splits[1].Note != "bombillas" {
+		t.Fatalf("lines %v", block.Splits)
+	}
+	if err := block.AddSplit(fun, money.New(5000, "EUR"), ""); !errors.Is(err, ErrSplitZero) {
+		t.Errorf("emptying the first line: %v", err)
+	}
+	if err := block.AddSplit(fun, money.New(100, "USD"), ""); !errors.Is(err, ErrSplitCurrency) {
+		t.Errorf("a line in dollars: %v", err)
+	}
+
+	// Editing a line balances the change on the first one, which only changes
+	// category and note
+	if err := block.EditSplit(1, Split{Category: home, Amount: money.New(4000, "EUR")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := block.EditSplit(0, Split{Category: food, Amount: money.New(1, "EUR"), Note: "fruta"}); err != nil {
+		t.Fatal(err)
+	}
+	if amounts := splitAmounts(block); amounts != [3]int64{4000, 4000, 1000} || block.Splits[0].Note != "fruta" {
+		t.Errorf("lines after editing %v", block.Splits)
+	}
+	if err := block.EditSplit(3, Split{}); !errors.Is(err, ErrSplitIndex) {
+		t.Errorf("editing a missing line: %v", err)
+	}
+	if primary := block.PrimaryCategory(); primary.ShortName != "food" {
+		t.Errorf("primary category %q, want the first of the largest lines", primary.ShortName)
+	}
+
+	// Removing lines gives their amount back; the last one unsplits the block
+	if err := block.RemoveSplit(0); !errors.Is(err, ErrSplitIndex) {
+		t.Errorf("removing the first line: %v", err)
+	}
+	if err := block.RemoveSplit(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := block.EditSplit(1, Split{Category: fun, Amount: money.New(9000, "EUR")}); !errors.Is(err, ErrSplitZero) {
+		t.Errorf("moving everything to line 1: %v", err)
+	}
+	if err := block.RemoveSplit(1); err != nil {
+		t.Fatal(err)
+	}
+	if block.IsSplit() || block.Category.ShortName != "food" || block.Amount.Minor() != 9000 {
+		t.Errorf("unsplit block %q %s splits %v", block.Category.ShortName, block.Amount, block.Splits)
+	}
+}
+
+func splitAmounts(block Block) [3]int64 {
+	var amounts [3]int64
+	for i, split := range block.Splits {
+		if i < len(amounts) {
+			amounts[i] = split.Amount.Minor()
+		}
+	}
+	return amounts
+}
+
+func TestSplitTotals(t *testing.T) {
+	categories := Categories{{ShortName: "food"}, {ShortName: "bakery", ParentShortName: "food"}, {ShortName: "home"}}
+	market := categorized("MERCADONA", "2025-03-02", 9000, "food")
+	if err := market.SetSplits(Splits{
+		{Category: Category{ShortName: "bakery"}, Amount: money.New(1000, "EUR")},
+		{Category: Category{ShortName: "food"}, Amount: money.New(5000, "EUR")},
+		{Category: Category{ShortName: "home"}, Amount: money.New(3000, "EUR")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	transfer := categorized("HUCHA", "2025-03-02", 2000, "home")
+	transfer.TransferID = "T1"
+	blocks := Blocks{market, categorized("PAN", "2025-03-03", 200, "bakery"), transfer}
+
+	byCategory := blocks.ByCategory()
+	if len(byCategory["home"]) != 2 || byCategory["home"][0].Amount.Minor() != 3000 || byCategory["home"][0].IsSplit() {
+		t.Errorf("home parts %v", byCategory["home"])
+	}
+
+	totals := blocks.TotalsByCategory()
+	if totals["food"].In("EUR").Minor() != 5000 || totals["bakery"].In("EUR").Minor() != 1200 || totals["home"].In("EUR").Minor() != 3000 {
+		t.Errorf("totals by category %v", totals)
+	}
+	if rolled := categories.Totals(blocks); rolled["food"].In("EUR").Minor() != 6200 || rolled["bakery"].In("EUR").Minor() != 1200 {
+		t.Errorf("rolled up totals %v", rolled)
+	}
+	if total, err := blocks.Total(); err != nil || total.Minor() != 9200 {
+		t.Errorf("total %s, %v, want 92.00 without the transfer", total, err)
+	}
+}
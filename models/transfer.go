@@ -0,0 +1,118 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// TransferOptions tune how the legs of an internal transfer are paired
+type TransferOptions struct {
+	Window int // Days the incoming leg may be booked after (or before) the outgoing one
+}
+
+var DefaultTransferOptions = TransferOptions{Window: 3}
+
+// TransferPair links the two legs of an internal transfer by their index in
+// the blocks given to PairTransfers. In is -1 when the other account was not
+// imported but the concept names it.
+type TransferPair struct {
+	ID  string
+	Out int // Leg leaving money, positive Amount
+	In  int // Leg receiving money, negative Amount
+}
+
+// IsTransfer reports whether the block is a leg of an internal transfer.
+// Transfers are neither expense nor income: totals and the daily net skip
+// them.
+func (b Block) IsTransfer() bool {
+	return b.TransferID != ""
+}
+
+// PairTransfers finds the outgoing and incoming legs of transfers between
+// these accounts and sets the same TransferID on both. Legs must have
+// opposite amounts, be in different accounts and be at most Window days
+// apart; a leg must also look like a transfer (operation type or the
+// concept naming the other account). The best hinted, closest candidate
+// wins. A transfer leg whose concept names another account is marked too
+// when its pair is not among the blocks, e.g. the hucha was not imported.
+func (accounts Accounts) PairTransfers(blocks Blocks, options TransferOptions) []TransferPair {
+	var pairs []TransferPair
+	paired := make([]bool, len(blocks))
+	for i := range blocks {
+		if blocks[i].IsTransfer() {
+			paired[i] = true
+		}
+	}
+
+	for out := range blocks {
+		if paired[out] || !blocks[out].Amount.IsPositive() {
+			continue
+		}
+
+		best, bestHints, bestDistance := -1, 0, time.Duration(0)
+		for in := range blocks {
+			if paired[in] || blocks[in].Account == blocks[out].Account || !blocks[in].Amount.Equal(blocks[out].Amount.Neg()) {
+				continue
+			}
+			distance := Day(blocks[in].Date).Sub(Day(blocks[out].Date))
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance > time.Duration(options.Window)*24*time.Hour {
+				continue
+			}
+
+			hints := accounts.transferHints(blocks[out], blocks[in]) + accounts.transferHints(blocks[in], blocks[out])
+			if hints == 0 {
+				continue
+			}
+			if best < 0 || hints > bestHints || (hints == bestHints && distance < bestDistance) {
+				best, bestHints, bestDistance = in, hints, distance
+			}
+		}
+
+		if best >= 0 {
+			id := "T" + Fingerprint(blocks[out].fingerprintKey()+"|"+blocks[best].fingerprintKey(), 0)
+			blocks[out].TransferID, blocks[best].TransferID = id, id
+			paired[out], paired[best] = true, true
+			pairs = append(pairs, TransferPair{ID: id, Out: out, In: best})
+		}
+	}
+
+	// Legs of transfers to or from accounts that were not imported
+	for i := range blocks {
+		if !paired[i] && accounts.IsInternalTransfer(blocks[i]) {
+			id := "T" + Fingerprint(blocks[i].fingerprintKey(), 0)
+			blocks[i].TransferID = id
+			pair := TransferPair{ID: id, Out: i, In: -1}
+			if blocks[i].Amount.IsNegative() {
+				pair.Out, pair.In = -1, i
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// transferHints counts what makes leg look like a transfer towards other:
+// the bank calls it a transfer, and its concept names other's account.
+func (accounts Accounts) transferHints(leg Block, other Block) int {
+	hints := 0
+	if leg.Concept.Merchant.Operation == OperationTransfer {
+		hints++
+	}
+	if account, ok := accounts.Find(other.Account); ok && accounts.names(leg, account) {
+		hints++
+	}
+	return hints
+}
+
+// names reports whether the concept of b mentions the account's name or IBAN
+func (accounts Accounts) names(b Block, account Account) bool {
+	canonical := " " + b.Concept.Canonical() + " "
+	if name := NormalizeConcept(account.Name); name != "" && strings.Contains(canonical, " "+name+" ") {
+		return true
+	}
+	text := strings.ToUpper(strings.Join(strings.Fields(b.Concept.Name+b.Concept2), ""))
+	return account.IBAN != "" && strings.Contains(text, account.IBAN)
+}
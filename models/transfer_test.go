@@ -0,0 +1,78 @@
+package models
+
+import "testing"
+
+func TestPairTransfers(t *testing.T) {
+	accounts := Accounts{{ID: "main", Name: "Nomina"}, {ID: "savings", Name: "Hucha"}}
+	leg := func(concept string, date string, minor int64, account string) Block {
+		block := testBlock(concept, date, minor)
+		block.Account = account
+		return block
+	}
+	blocks := Blocks{
+		leg("TRANSFERENCIA A HUCHA", "2025-03-01", 5000, "main"),
+		leg("TRANSFERENCIA DE NOMINA", "2025-03-03", -5000, "savings"), // Booked two days later
+		leg("SUPER", "2025-03-02", 3000, "main"),
+		leg("DEVOLUCION", "2025-03-02", -3000, "savings"), // Same amount, nothing says transfer
+		leg("TRANSFERENCIA A HUCHA", "2025-03-10", 1000, "main"),
+		leg("TRANSFERENCIA DE NOMINA", "2025-03-20", -1000, "savings"), // Out of the window
+		leg("TRANSFERENCIA A JUAN", "2025-03-05", 2000, "main"),
+		leg("TRANSFERENCIA", "2025-03-05", -2000, "main"), // Same account
+	}
+
+	pairs := accounts.PairTransfers(blocks, DefaultTransferOptions)
+	pairedWith := map[int]int{}
+	for _, pair := range pairs {
+		pairedWith[pair.Out] = pair.In
+		if pair.Out >= 0 && blocks[pair.Out].TransferID != pair.ID || pair.In >= 0 && blocks[pair.In].TransferID != pair.ID {
+			t.Errorf("pair %+v: legs not marked", pair)
+		}
+	}
+
+	if in, ok := pairedWith[0]; !ok || in != 1 {
+		t.Errorf("hucha transfer paired with %d, want 1", in)
+	}
+	if blocks[2].IsTransfer() || blocks[3].IsTransfer() || blocks[6].IsTransfer() || blocks[7].IsTransfer() {
+		t.Errorf("a purchase, a refund or a transfer within an account was paired")
+	}
+	// Legs whose concept names the other account are marked on their own
+	if in, ok := pairedWith[4]; !ok || in != -1 || !blocks[5].IsTransfer() || blocks[4].TransferID == blocks[5].TransferID {
+		t.Errorf("legs out of the window: %v, %q %q", pairs, blocks[4].TransferID, blocks[5].TransferID)
+	}
+	if len(pairs) != 3 {
+		t.Errorf("%d pairs %v, want 3", len(pairs), pairs)
+	}
+
+	// Pairing again keeps the IDs and adds nothing
+	ids := []string{blocks[0].TransferID, blocks[1].TransferID}
+	if again := accounts.PairTransfers(blocks, DefaultTransferOptions); len(again) != 0 || blocks[0].TransferID != ids[0] || blocks[1].TransferID != ids[1] {
+		t.Errorf("second pairing %v", again)
+	}
+}
+
+func TestPairTransfersPrefersHintsThenDistance(t *testing.T) {
+	accounts := Accounts{{ID: "main", Name: "Nomina"}, {ID: "savings", Name: "Hucha"}, {ID: "card", Name: "Tarjeta"}}
+	blocks := Blocks{
+		testBlock("TRANSFERENCIA A HUCHA", "2025-03-01", 5000),
+		testBlock("TRANSFERENCIA", "2025-03-01", -5000),           // Same day, only the bank says transfer
+		testBlock("TRANSFERENCIA DE NOMINA", "2025-03-02", -5000), // Names the account too
+		testBlock("TRANSFERENCIA", "2025-03-04", -5000),
+	}
+	blocks[0].Account, blocks[1].Account, blocks[2].Account, blocks[3].Account = "main", "card", "savings", "card"
+
+	pairs := accounts.PairTransfers(blocks, DefaultTransferOptions)
+	if len(pairs) == 0 || pairs[0].Out != 0 || pairs[0].In != 2 {
+		t.Fatalf("pairs %v, want the outgoing leg with the one naming it", pairs)
+	}
+
+	// With equal hints the closest leg wins
+	blocks = Blocks{
+		testBlock("TRANSFERENCIA", "2025-03-05", 5000),
+		testBlock("TRANSFERENCIA", "2025-03-02", -5000),
+		testBlock("TRANSFERENCIA", "2025-03-06", -5000),
+	}
+	blocks[0].Account, blocks[1].Account, blocks[2].Account = "main", "savings", "savings"
+	if pairs := accounts.PairTransfers(blocks, DefaultTransferOptions); len(pairs) != 1 || pairs[0].In != 2 {
+		t.Errorf("pairs %v, want the leg a day later", pairs)
+	}
+}
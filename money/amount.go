@@ -0,0 +1,406 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount in the minor units (cents) of a currency. The zero
+// value has no currency and adopts the currency of whatever it is added to,
+// so it can be used as the start of a sum.
+type Money struct {
+	minor    int64
+	currency string
+}
+
+// RoundingMode decides what happens to the fraction of a minor unit
+type RoundingMode int
+
+const (
+	RoundHalfUp   RoundingMode = iota // 0.5 away from zero
+	RoundHalfEven                     // 0.5 to the nearest even unit (banker's rounding)
+	RoundHalfDown                     // 0.5 towards zero
+	RoundDown                         // Towards zero (truncate)
+	RoundUp                           // Away from zero
+	RoundFloor                        // Towards negative infinity
+	RoundCeiling                      // Towards positive infinity
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+	ErrInvalidRatios    = errors.New("money: allocation ratios must be non-negative and not all zero")
+)
+
+// Number of decimals of the currencies that do not use two
+var currencyExponents = map[string]int{
+	"JPY": 0, "KRW": 0, "CLP": 0, "ISK": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+}
+
+var currencySymbols = map[string]string{
+	"EUR": "€", "USD": "$", "GBP": "£", "JPY": "¥", "CHF": "CHF",
+}
+
+// New returns an amount of minor units, e.g. New(1250, "EUR") is 12,50 €.
+func New(minor int64, currency string) Money {
+	return Money{minor: minor, currency: strings.ToUpper(currency)}
+}
+
+// FromFloat converts a float amount, rounding half away from zero at the
+// currency precision. It exists for callers that still work with float64.
+func FromFloat(value float64, currency string) Money {
+	currency = strings.ToUpper(currency)
+	minor, err := parseDecimal(strconv.FormatFloat(value, 'f', -1, 64), Exponent(currency), RoundHalfUp)
+	if err != nil {
+		// NaN or Inf
+		return Money{currency: currency}
+	}
+	return Money{minor: minor, currency: currency}
+}
+
+// ParseDecimal reads a machine formatted amount such as "-1234.5" (OFX,
+// ISO 20022). Extra decimals are only accepted when they are zeros.
+func ParseDecimal(s string, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	minor, err := parseDecimal(strings.TrimSpace(s), Exponent(currency), -1)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{minor: minor, currency: currency}, nil
+}
+
+// Exponent returns the number of decimals used by a currency.
+func Exponent(currency string) int {
+	if exponent, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// CurrencyExponents returns the currencies that do not use two decimals,
+// with their number of decimals
+func CurrencyExponents() map[string]int {
+	exponents := make(map[string]int, len(currencyExponents))
+	for currency, exponent := range currencyExponents {
+		exponents[currency] = exponent
+	}
+	return exponents
+}
+
+// Symbol returns the usual symbol of a currency, or its ISO code.
+func Symbol(currency string) string {
+	if symbol, ok := currencySymbols[currency]; ok {
+		return symbol
+	}
+	return currency
+}
+
+func (m Money) Minor() int64                { return m.minor }
+func (m Money) Currency() string            { return m.currency }
+func (m Money) IsZero() bool                { return m.minor == 0 }
+func (m Money) IsPositive() bool            { return m.minor > 0 }
+func (m Money) IsNegative() bool            { return m.minor < 0 }
+func (m Money) Neg() Money                  { return Money{minor: -m.minor, currency: m.currency} }
+func (m Money) WithMinor(minor int64) Money { return Money{minor: minor, currency: m.currency} }
+
+// Float returns the amount as a float64, for display and legacy callers.
+func (m Money) Float() float64 {
+	return float64(m.minor) / math.Pow10(Exponent(m.currency))
+}
+
+func (m Money) Sign() int {
+	switch {
+	case m.minor < 0:
+		return -1
+	case m.minor > 0:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) Abs() Money {
+	if m.minor < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Add returns m + other. It panics when both amounts carry different
+// currencies, so it is only meant for amounts known to share one, such as
+// the movements of one account. Use TryAdd or Totals for anything else.
+func (m Money) Add(other Money) Money {
+	return Money{minor: m.minor + other.minor, currency: m.mustMatch(other)}
+}
+
+// Sub returns m - other, with the same currency rules as Add.
+func (m Money) Sub(other Money) Money {
+	return Money{minor: m.minor - other.minor, currency: m.mustMatch(other)}
+}
+
+// TryAdd returns m + other, or ErrCurrencyMismatch when both amounts carry
+// different currencies.
+func (m Money) TryAdd(other Money) (Money, error) {
+	if !m.SameCurrency(other) {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, other.currency)
+	}
+	return m.Add(other), nil
+}
+
+// TrySub returns m - other, or ErrCurrencyMismatch like TryAdd.
+func (m Money) TrySub(other Money) (Money, error) {
+	return m.TryAdd(other.Neg())
+}
+
+// SameCurrency reports whether m and other can be added or compared.
+func (m Money) SameCurrency(other Money) bool {
+	return m.currency == "" || other.currency == "" || m.currency == other.currency
+}
+
+func (m Money) mustMatch(other Money) string {
+	if !m.SameCurrency(other) {
+		panic(fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, other.currency))
+	}
+	if m.currency == "" {
+		return other.currency
+	}
+	return m.currency
+}
+
+// Cmp compares two amounts of the same currency and returns -1, 0 or +1.
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.minor < other.minor:
+		return -1
+	case m.minor > other.minor:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) Equal(other Money) bool {
+	return m.SameCurrency(other) && m.minor == other.minor
+}
+
+func (m Money) LessThan(other Money) bool    { return m.Cmp(other) < 0 }
+func (m Money) GreaterThan(other Money) bool { return m.Cmp(other) > 0 }
+
+// MultiplyRat returns m * num / den, rounded with mode.
+func (m Money) MultiplyRat(num, den int64, mode RoundingMode) Money {
+	if den == 0 {
+		panic("money: division by zero")
+	}
+	product := new(big.Int).Mul(big.NewInt(m.minor), big.NewInt(num))
+	return Money{minor: divRound(product, big.NewInt(den), mode), currency: m.currency}
+}
+
+// Multiply returns m * factor, rounded with mode. The factor is taken with
+// nine decimals, which is plenty for rates and percentages.
+func (m Money) Multiply(factor float64, mode RoundingMode) Money {
+	const scale = 1_000_000_000
+	return m.MultiplyRat(int64(math.Round(factor*scale)), scale, mode)
+}
+
+// Divide returns m / n, rounded with mode. Use Split to share an amount
+// without losing minor units.
+func (m Money) Divide(n int64, mode RoundingMode) Money {
+	return m.MultiplyRat(1, n, mode)
+}
+
+// Round rounds the amount to a multiple of unit minor units, e.g.
+// Round(100, RoundHalfUp) rounds euros to whole euros.
+func (m Money) Round(unit int64, mode RoundingMode) Money {
+	if unit <= 1 {
+		return m
+	}
+	return Money{minor: divRound(big.NewInt(m.minor), big.NewInt(unit), mode) * unit, currency: m.currency}
+}
+
+// Allocate splits the amount proportionally to ratios. Leftover minor units
+// go one at a time to the shares with the largest remainders, so the parts
+// always add up to m exactly.
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	var total int64
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, ErrInvalidRatios
+		}
+		total += ratio
+	}
+	if total == 0 {
+		return nil, ErrInvalidRatios
+	}
+
+	sign := int64(1)
+	amount := m.minor
+	if amount < 0 {
+		sign, amount = -1, -amount
+	}
+
+	parts := make([]Money, len(ratios))
+	remainders := make([]int64, len(ratios))
+	allocated := int64(0)
+	for i, ratio := range ratios {
+		share := new(big.Int).Mul(big.NewInt(amount), big.NewInt(ratio))
+		quotient, remainder := new(big.Int).QuoRem(share, big.NewInt(total), new(big.Int))
+		parts[i] = Money{minor: quotient.Int64(), currency: m.currency}
+		remainders[i] = remainder.Int64()
+		allocated += quotient.Int64()
+	}
+
+	for left := amount - allocated; left > 0; left-- {
+		best := -1
+		for i := range parts {
+			if ratios[i] > 0 && (best < 0 || remainders[i] > remainders[best]) {
+				best = i
+			}
+		}
+		parts[best].minor++
+		remainders[best] = -1
+	}
+
+	for i := range parts {
+		parts[i].minor *= sign
+	}
+	return parts, nil
+}
+
+// Split shares the amount in n parts that differ by at most one minor unit.
+func (m Money) Split(n int) ([]Money, error) {
+	ratios := make([]int64, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return m.Allocate(ratios...)
+}
+
+// Decimal returns the machine form of the amount, e.g. "-1234.50".
+func (m Money) Decimal() string {
+	exponent := Exponent(m.currency)
+	minor := m.minor
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	if exponent == 0 {
+		return sign + strconv.FormatInt(minor, 10)
+	}
+	unit := int64(math.Pow10(exponent))
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/unit, exponent, minor%unit)
+}
+
+// Format writes the amount for a locale, using the locale symbol when the
+// currency is the locale's own and the currency symbol otherwise.
+func (m Money) Format(locale Locale) string {
+	symbol := Symbol(m.currency)
+	if m.currency == "" || m.currency == locale.Currency {
+		symbol = locale.Symbol
+	}
+	return formatMinor(m.minor, Exponent(m.currency), locale, symbol)
+}
+
+func (m Money) String() string {
+	return strings.TrimSpace(m.Decimal() + " " + m.currency)
+}
+
+// parseDecimal converts "123.456" into minor units with exponent decimals.
+// A negative mode rejects any non-zero digit beyond the exponent.
+func parseDecimal(s string, exponent int, mode RoundingMode) (int64, error) {
+	negative := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("money: invalid decimal %q", s)
+	}
+	for _, r := range whole + fraction {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("money: invalid decimal %q", s)
+		}
+	}
+
+	extra := ""
+	if len(fraction) > exponent {
+		fraction, extra = fraction[:exponent], fraction[exponent:]
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	digits, ok := new(big.Int).SetString("0"+whole+fraction, 10)
+	if !ok {
+		return 0, fmt.Errorf("money: invalid decimal %q", s)
+	}
+
+	if strings.Trim(extra, "0") != "" {
+		if mode < 0 {
+			return 0, fmt.Errorf("money: more than %d decimals in %q", exponent, s)
+		}
+		// Round using the extra digits as a fraction of one minor unit
+		numerator, _ := new(big.Int).SetString(digits.String()+extra, 10)
+		if negative {
+			numerator.Neg(numerator)
+		}
+		denominator := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(len(extra))), nil)
+		return divRound(numerator, denominator, mode), nil
+	}
+
+	if !digits.IsInt64() {
+		return 0, fmt.Errorf("money: amount %q out of range", s)
+	}
+	if negative {
+		return -digits.Int64(), nil
+	}
+	return digits.Int64(), nil
+}
+
+// divRound returns num / den rounded with mode.
+func divRound(num, den *big.Int, mode RoundingMode) int64 {
+	if den.Sign() < 0 {
+		num = new(big.Int).Neg(num)
+		den = new(big.Int).Neg(den)
+	}
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient.Int64()
+	}
+
+	negative := num.Sign() < 0
+	// Compare twice the remainder with the denominator to find the half
+	half := new(big.Int).Abs(remainder)
+	half.Mul(half, big.NewInt(2))
+	cmpHalf := half.Cmp(den)
+
+	awayFromZero := false
+	switch mode {
+	case RoundHalfUp:
+		awayFromZero = cmpHalf >= 0
+	case RoundHalfDown:
+		awayFromZero = cmpHalf > 0
+	case RoundHalfEven:
+		awayFromZero = cmpHalf > 0 || (cmpHalf == 0 && quotient.Bit(0) == 1)
+	case RoundUp:
+		awayFromZero = true
+	case RoundDown:
+		awayFromZero = false
+	case RoundFloor:
+		awayFromZero = negative
+	case RoundCeiling:
+		awayFromZero = !negative
+	}
+
+	result := quotient.Int64()
+	if awayFromZero {
+		if negative {
+			result--
+		} else {
+			result++
+		}
+	}
+	return result
+}
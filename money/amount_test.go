@@ -0,0 +1,227 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		minor    int64
+		fails    bool
+	}{
+		{"-1234.5", "EUR", -123450, false},
+		{"12", "eur", 1200, false},
+		{"0.10", "EUR", 10, false},
+		{"1.500", "EUR", 150, false}, // Extra zero decimals are fine
+		{"1.505", "EUR", 0, true},
+		{"1500", "JPY", 1500, false},
+		{"1.5", "JPY", 0, true},
+		{"1.234", "KWD", 1234, false},
+		{"", "EUR", 0, true},
+		{"1,5", "EUR", 0, true},
+		{"99999999999999999999", "EUR", 0, true},
+	}
+	for _, test := range tests {
+		got, err := ParseDecimal(test.input, test.currency)
+		if test.fails {
+			if err == nil {
+				t.Errorf("ParseDecimal(%q, %s) = %s, want an error", test.input, test.currency, got)
+			}
+			continue
+		}
+		if err != nil || got.Minor() != test.minor {
+			t.Errorf("ParseDecimal(%q, %s) = %s, %v, want %d minor units", test.input, test.currency, got, err, test.minor)
+		}
+	}
+}
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		value    float64
+		currency string
+		minor    int64
+	}{
+		{0.1 + 0.2, "EUR", 30},
+		{1.005, "EUR", 101}, // Rounded from the shortest decimal form, not the binary value
+		{-2.675, "EUR", -268},
+		{12.5, "JPY", 13},
+	}
+	for _, test := range tests {
+		if got := FromFloat(test.value, test.currency); got.Minor() != test.minor {
+			t.Errorf("FromFloat(%v, %s) = %s, want %d minor units", test.value, test.currency, got, test.minor)
+		}
+	}
+}
+
+func TestRoundingModes(t *testing.T) {
+	// 1050 / 100 and -1050 / 100 are exactly halfway; 1051 / 100 is not
+	tests := []struct {
+		mode         RoundingMode
+		half         int64
+		negHalf      int64
+		aboveHalf    int64
+		belowHalf    int64
+		negBelowHalf int64
+	}{
+		{RoundHalfUp, 11, -11, 11, 10, -10},
+		{RoundHalfEven, 10, -10, 11, 10, -10},
+		{RoundHalfDown, 10, -10, 11, 10, -10},
+		{RoundDown, 10, -10, 10, 10, -10},
+		{RoundUp, 11, -11, 11, 11, -11},
+		{RoundFloor, 10, -11, 10, 10, -11},
+		{RoundCeiling, 11, -10, 11, 11, -10},
+	}
+	for _, test := range tests {
+		got := []int64{
+			New(1050, "EUR").Divide(100, test.mode).Minor(),
+			New(-1050, "EUR").Divide(100, test.mode).Minor(),
+			New(1051, "EUR").Divide(100, test.mode).Minor(),
+			New(1049, "EUR").Divide(100, test.mode).Minor(),
+			New(-1049, "EUR").Divide(100, test.mode).Minor(),
+		}
+		want := []int64{test.half, test.negHalf, test.aboveHalf, test.belowHalf, test.negBelowHalf}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("mode %d: case %d = %d, want %d", test.mode, i, got[i], want[i])
+			}
+		}
+	}
+
+	if got := New(1150, "EUR").Divide(100, RoundHalfEven).Minor(); got != 12 {
+		t.Errorf("11.5 half even = %d, want 12", got)
+	}
+	if got := New(12345, "EUR").Round(100, RoundHalfUp); got.Minor() != 12300 {
+		t.Errorf("Round(100) = %s, want 123.00 EUR", got)
+	}
+	if got := New(1000, "EUR").Multiply(0.21, RoundHalfUp); got.Minor() != 210 {
+		t.Errorf("Multiply(0.21) = %s, want 2.10 EUR", got)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		minor  int64
+		ratios []int64
+		want   []int64
+	}{
+		{100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{-100, []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{5, []int64{3, 7}, []int64{2, 3}}, // 1.5 and 3.5: equal remainders go to the first share
+		{1000, []int64{70, 20, 10}, []int64{700, 200, 100}},
+		{1, []int64{0, 1, 0}, []int64{0, 1, 0}},
+		{2, []int64{1, 0, 1}, []int64{1, 0, 1}},
+		{0, []int64{1, 2}, []int64{0, 0}},
+	}
+	for _, test := range tests {
+		parts, err := New(test.minor, "EUR").Allocate(test.ratios...)
+		if err != nil {
+			t.Errorf("Allocate(%d, %v): %v", test.minor, test.ratios, err)
+			continue
+		}
+		total := int64(0)
+		for i, part := range parts {
+			total += part.Minor()
+			if part.Minor() != test.want[i] || part.Currency() != "EUR" {
+				t.Errorf("Allocate(%d, %v)[%d] = %s, want %d", test.minor, test.ratios, i, part, test.want[i])
+			}
+		}
+		if total != test.minor {
+			t.Errorf("Allocate(%d, %v) adds up to %d", test.minor, test.ratios, total)
+		}
+	}
+}
+
+func TestAllocateInvalidRatios(t *testing.T) {
+	for _, ratios := range [][]int64{nil, {0, 0}, {1, -1}} {
+		if _, err := New(100, "EUR").Allocate(ratios...); !errors.Is(err, ErrInvalidRatios) {
+			t.Errorf("Allocate(%v) error = %v, want ErrInvalidRatios", ratios, err)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	parts, err := New(1000, "EUR").Split(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{334, 333, 333}
+	for i := range want {
+		if parts[i].Minor() != want[i] {
+			t.Errorf("Split(3)[%d] = %s, want %d", i, parts[i], want[i])
+		}
+	}
+}
+
+func TestCurrencyMismatch(t *testing.T) {
+	eur, usd := New(100, "EUR"), New(100, "USD")
+
+	if _, err := eur.TryAdd(usd); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("TryAdd error = %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := eur.TrySub(usd); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("TrySub error = %v, want ErrCurrencyMismatch", err)
+	}
+	if got, err := (Money{}).TryAdd(eur); err != nil || !got.Equal(eur) || got.Currency() != "EUR" {
+		t.Errorf("zero TryAdd = %s, %v, want the EUR amount", got, err)
+	}
+	if eur.Equal(usd) {
+		t.Errorf("%s equals %s", eur, usd)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Add of different currencies did not panic")
+		}
+	}()
+	eur.Add(usd)
+}
+
+func TestTotals(t *testing.T) {
+	totals := Totals{}
+	totals.Add(New(100, "EUR"))
+	totals.Add(New(250, "EUR"))
+	totals.Add(Money{})
+
+	if got, err := totals.Single(); err != nil || got.Minor() != 350 || got.Currency() != "EUR" {
+		t.Errorf("Single = %s, %v, want 3.50 EUR", got, err)
+	}
+
+	totals.Add(New(-40, "USD"))
+	if _, err := totals.Single(); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Single error = %v, want ErrCurrencyMismatch", err)
+	}
+	if got := totals.In("USD"); got.Minor() != -40 {
+		t.Errorf("In(USD) = %s, want -0.40 USD", got)
+	}
+	if got := totals.In("GBP"); !got.IsZero() || got.Currency() != "GBP" {
+		t.Errorf("In(GBP) = %s, want 0.00 GBP", got)
+	}
+
+	more := Totals{}
+	more.AddTotals(totals)
+	more.AddTotals(totals)
+	if got := more.In("EUR"); got.Minor() != 700 {
+		t.Errorf("AddTotals EUR = %s, want 7.00 EUR", got)
+	}
+}
+
+func TestDecimalAndString(t *testing.T) {
+	tests := []struct {
+		amount Money
+		want   string
+	}{
+		{New(-123450, "EUR"), "-1234.50 EUR"},
+		{New(5, "EUR"), "0.05 EUR"},
+		{New(1500, "JPY"), "1500 JPY"},
+		{New(1234, "KWD"), "1.234 KWD"},
+		{Money{}, "0.00"},
+	}
+	for _, test := range tests {
+		if got := test.amount.String(); got != test.want {
+			t.Errorf("String() = %q, want %q", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Locale describes how amounts are written in a given language and region
+type Locale struct {
+	Tag         string // BCP 47 tag, e.g. "es-ES"
+	Decimal     string
+	Group       string
+	Currency    string // Default ISO 4217 code
+	Symbol      string // Default currency symbol
+	SymbolFirst bool   // "$1.00" instead of "1,00 €"
+	SymbolSpace string // Separator between the number and the symbol
+}
+
+var (
+	EsES = Locale{Tag: "es-ES", Decimal: ",", Group: ".", Currency: "EUR", Symbol: "€", SymbolSpace: "\u00a0"}
+	EnUS = Locale{Tag: "en-US", Decimal: ".", Group: ",", Currency: "USD", Symbol: "$", SymbolFirst: true}
+	DeDE = Locale{Tag: "de-DE", Decimal: ",", Group: ".", Currency: "EUR", Symbol: "€", SymbolSpace: "\u00a0"}
+	FrFR = Locale{Tag: "fr-FR", Decimal: ",", Group: "\u202f", Currency: "EUR", Symbol: "€", SymbolSpace: "\u00a0"}
+)
+
+var locales = map[string]Locale{
+	"es-es": EsES,
+	"en-us": EnUS,
+	"de-de": DeDE,
+	"fr-fr": FrFR,
+}
+
+// LookupLocale returns the Locale for a tag such as "es-ES" or "fr_FR".
+func LookupLocale(tag string) (Locale, error) {
+	key := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	locale, ok := locales[key]
+	if !ok {
+		return Locale{}, fmt.Errorf("unsupported locale %q", tag)
+	}
+	return locale, nil
+}
@@ -0,0 +1,256 @@
+// Package money parses and formats monetary amounts as banks and users write
+// them in different locales.
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Currency symbols recognised before or after the number. "$" is left for
+// the locale to resolve.
+var symbols = []struct{ symbol, currency string }{
+	{"€", "EUR"}, {"US$", "USD"}, {"$", "$"}, {"£", "GBP"}, {"¥", "JPY"}, {"Fr.", "CHF"},
+}
+
+// Parse reads an amount written for the given locale. It accepts thousands
+// separators, a currency symbol or ISO code before or after the number, a
+// leading or trailing sign, parenthesised negatives ("(12,50 €)") and
+// non-breaking spaces. A single group separator that is not followed by
+// exactly three digits is read as the decimal separator, so "12.5" is still
+// twelve and a half in es-ES.
+func Parse(s string, locale Locale) (float64, error) {
+	number, negative, _, err := clean(s)
+	if err != nil {
+		return 0, err
+	}
+
+	normalized, err := normalizeNumber(number, locale)
+	if err != nil {
+		return 0, fmt.Errorf("money: invalid amount %q: %w", s, err)
+	}
+
+	value, err := strconv.ParseFloat(normalized, 64)
+	if err != nil {
+		return 0, fmt.Errorf("money: invalid amount %q", s)
+	}
+	if negative {
+		value = -value
+	}
+	return value, nil
+}
+
+// Format writes an amount with two decimals, grouping and the default
+// currency symbol of the locale, e.g. "-1.234,56 €" or "-$1,234.56".
+func Format(value float64, locale Locale) string {
+	return FormatWithSymbol(value, locale, locale.Symbol)
+}
+
+// FormatWithSymbol is Format with an explicit symbol; an empty symbol gives the
+// bare number.
+func FormatWithSymbol(value float64, locale Locale, symbol string) string {
+	return formatMinor(int64(math.Round(value*100)), 2, locale, symbol)
+}
+
+// ParseMoney reads an amount like Parse, but exactly. The currency is taken
+// from the symbol or ISO code in the text, or from the locale when there is
+// none.
+func ParseMoney(s string, locale Locale) (Money, error) {
+	number, negative, currency, err := clean(s)
+	if err != nil {
+		return Money{}, err
+	}
+	switch {
+	case currency == "" || (currency == "$" && locale.Symbol == "$"):
+		currency = locale.Currency
+	case currency == "$":
+		currency = "USD"
+	}
+
+	normalized, err := normalizeNumber(number, locale)
+	if err != nil {
+		return Money{}, fmt.Errorf("money: invalid amount %q: %w", s, err)
+	}
+
+	result, err := ParseDecimal(normalized, currency)
+	if err != nil {
+		return Money{}, err
+	}
+	if negative {
+		result = result.Neg()
+	}
+	return result, nil
+}
+
+func formatMinor(minor int64, exponent int, locale Locale, symbol string) string {
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+
+	unit := int64(math.Pow10(exponent))
+	number := groupDigits(strconv.FormatInt(minor/unit, 10), locale.Group)
+	if exponent > 0 {
+		number += locale.Decimal + fmt.Sprintf("%0*d", exponent, minor%unit)
+	}
+
+	switch {
+	case symbol == "":
+		return sign + number
+	case locale.SymbolFirst:
+		return sign + symbol + locale.SymbolSpace + number
+	default:
+		return sign + number + locale.SymbolSpace + symbol
+	}
+}
+
+// clean removes spaces, currency markers and the sign, returning the bare
+// number, whether it was negative and the currency found (an ISO code, or "$"
+// when only a dollar sign was seen).
+func clean(s string) (string, bool, string, error) {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '\u00a0', '\u202f', '\u2009':
+			return ' '
+		case '\u2212':
+			return '-'
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", false, "", fmt.Errorf("money: empty amount")
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	// Sign and currency may come in either order: "-€12", "€-12", "12 €-"
+	currency := ""
+	for changed := true; changed; {
+		changed = false
+		before := s
+		s = trimSign(s, &negative)
+		s = trimCurrency(s, &currency)
+		s = strings.TrimSpace(s)
+		if s != before {
+			changed = true
+		}
+	}
+
+	if s == "" {
+		return "", false, "", fmt.Errorf("money: no digits in amount")
+	}
+	return s, negative, currency, nil
+}
+
+func trimSign(s string, negative *bool) string {
+	switch {
+	case strings.HasPrefix(s, "-"):
+		*negative = !*negative
+		return s[1:]
+	case strings.HasSuffix(s, "-"):
+		*negative = !*negative
+		return s[:len(s)-1]
+	case strings.HasPrefix(s, "+"):
+		return s[1:]
+	case strings.HasSuffix(s, "+"):
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+func trimCurrency(s string, currency *string) string {
+	for _, known := range symbols {
+		if strings.HasPrefix(s, known.symbol) || strings.HasSuffix(s, known.symbol) {
+			s = strings.TrimSuffix(strings.TrimPrefix(s, known.symbol), known.symbol)
+			*currency = known.currency
+		}
+	}
+	if len(s) > 3 && isISOCode(s[:3]) {
+		*currency = s[:3]
+		s = s[3:]
+	}
+	if len(s) > 3 && isISOCode(s[len(s)-3:]) {
+		*currency = s[len(s)-3:]
+		s = s[:len(s)-3]
+	}
+	return s
+}
+
+func isISOCode(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeNumber turns a localized number into the "1234.56" form
+// understood by strconv.
+func normalizeNumber(s string, locale Locale) (string, error) {
+	group, decimal := locale.Group, locale.Decimal
+	// Spaces are used for grouping in French and typed by users everywhere
+	s = strings.ReplaceAll(s, " ", "")
+	if strings.TrimSpace(group) == "" {
+		group = ""
+	}
+
+	integer, fraction, hasDecimal := strings.Cut(s, decimal)
+	if hasDecimal && strings.Contains(fraction, decimal) {
+		return "", fmt.Errorf("more than one decimal separator")
+	}
+
+	if group != "" && strings.Contains(integer, group) {
+		parts := strings.Split(integer, group)
+		if !hasDecimal && len(parts) == 2 && len(parts[1]) != 3 {
+			// "12.5" in es-ES: the group separator is really a decimal point
+			integer, fraction, hasDecimal = parts[0], parts[1], true
+		} else {
+			for i, part := range parts {
+				if (i > 0 && len(part) != 3) || part == "" {
+					return "", fmt.Errorf("misplaced thousands separator")
+				}
+			}
+			integer = strings.Join(parts, "")
+		}
+	}
+
+	for _, r := range integer + fraction {
+		if !unicode.IsDigit(r) {
+			return "", fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	if integer == "" {
+		integer = "0"
+	}
+	if hasDecimal {
+		return integer + "." + fraction, nil
+	}
+	return integer, nil
+}
+
+func groupDigits(digits string, group string) string {
+	if group == "" || len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(group)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
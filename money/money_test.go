@@ -0,0 +1,127 @@
+package money
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input  string
+		locale Locale
+		minor  int64
+		code   string
+	}{
+		{"1.234,56 €", EsES, 123456, "EUR"},
+		{"-1.234,56 €", EsES, -123456, "EUR"},
+		{"1.234,56\u00a0€", EsES, 123456, "EUR"},
+		{"(12,50 €)", EsES, -1250, "EUR"},
+		{"12,5", EsES, 1250, "EUR"},
+		{"12.5", EsES, 1250, "EUR"}, // A lone group separator before one digit is a decimal point
+		{"1.234", EsES, 123400, "EUR"},
+		{"1.234.567", EsES, 123456700, "EUR"},
+		{"12 €-", EsES, -1200, "EUR"},
+		{"-€12", EsES, -1200, "EUR"},
+		{"$12", EsES, 1200, "USD"},
+		{"12 USD", EsES, 1200, "USD"},
+		{"$1,234.56", EnUS, 123456, "USD"},
+		{"-$1,234.56", EnUS, -123456, "USD"},
+		{"1,234", EnUS, 123400, "USD"},
+		{"US$ 3.10", EnUS, 310, "USD"},
+		{"1.234,56 €", DeDE, 123456, "EUR"},
+		{"1\u202f234,56\u00a0€", FrFR, 123456, "EUR"},
+		{"1 234,56 €", FrFR, 123456, "EUR"},
+		{"£7.20", EnUS, 720, "GBP"},
+		{"1500 JPY", EnUS, 1500, "JPY"},
+		{"\u221212,00 €", EsES, -1200, "EUR"},
+	}
+	for _, test := range tests {
+		got, err := ParseMoney(test.input, test.locale)
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %s): %v", test.input, test.locale.Tag, err)
+			continue
+		}
+		if got.Minor() != test.minor || got.Currency() != test.code {
+			t.Errorf("ParseMoney(%q, %s) = %s, want %d %s", test.input, test.locale.Tag, got, test.minor, test.code)
+		}
+	}
+}
+
+func TestParseMoneyErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		locale Locale
+	}{
+		{"", EsES},
+		{"€", EsES},
+		{"12,34,56", EsES},
+		{"1.23.456", EsES},
+		{"12a", EsES},
+		{"1,234,56", EnUS},
+		{"12,345", EsES}, // Three decimals do not fit in EUR
+	}
+	for _, test := range tests {
+		if got, err := ParseMoney(test.input, test.locale); err == nil {
+			t.Errorf("ParseMoney(%q, %s) = %s, want an error", test.input, test.locale.Tag, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input  string
+		locale Locale
+		want   float64
+	}{
+		{"1.234,56 €", EsES, 1234.56},
+		{"12,345", EsES, 12.345},
+		{"(3.50)", EnUS, -3.5},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.input, test.locale)
+		if err != nil || got != test.want {
+			t.Errorf("Parse(%q, %s) = %v, %v, want %v", test.input, test.locale.Tag, got, err, test.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount Money
+		locale Locale
+		want   string
+	}{
+		{New(-123456, "EUR"), EsES, "-1.234,56\u00a0€"},
+		{New(5, "EUR"), EsES, "0,05\u00a0€"},
+		{New(-123456, "USD"), EnUS, "-$1,234.56"},
+		{New(123456, "EUR"), FrFR, "1\u202f234,56\u00a0€"},
+		{New(123456, "USD"), EsES, "1.234,56\u00a0$"},
+		{New(1500, "JPY"), EnUS, "¥1,500"},
+		{New(1234567, "KWD"), EnUS, "KWD1,234.567"},
+	}
+	for _, test := range tests {
+		if got := test.amount.Format(test.locale); got != test.want {
+			t.Errorf("%s.Format(%s) = %q, want %q", test.amount, test.locale.Tag, got, test.want)
+		}
+	}
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	for _, locale := range []Locale{EsES, EnUS, DeDE, FrFR} {
+		for _, minor := range []int64{0, 1, -99, 100000, -123456789} {
+			amount := New(minor, locale.Currency)
+			got, err := ParseMoney(amount.Format(locale), locale)
+			if err != nil || !got.Equal(amount) {
+				t.Errorf("%s: ParseMoney(%q) = %s, %v, want %s", locale.Tag, amount.Format(locale), got, err, amount)
+			}
+		}
+	}
+}
+
+func TestLookupLocale(t *testing.T) {
+	for _, tag := range []string{"es-ES", "es_es", " fr-FR "} {
+		if _, err := LookupLocale(tag); err != nil {
+			t.Errorf("LookupLocale(%q): %v", tag, err)
+		}
+	}
+	if _, err := LookupLocale("xx-XX"); err == nil {
+		t.Errorf("LookupLocale(%q) did not fail", "xx-XX")
+	}
+}
@@ -0,0 +1,55 @@
+package money
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Totals sums amounts of any currency, keeping one total per currency. It is
+// meant for views over several accounts, where adding the amounts with Add
+// would panic.
+type Totals map[string]Money
+
+// Add adds m to the total of its currency. Amounts without a currency (the
+// zero value) are kept under the empty code.
+func (t Totals) Add(m Money) {
+	t[m.currency] = t[m.currency].Add(m)
+}
+
+// AddTotals adds every total of other
+func (t Totals) AddTotals(other Totals) {
+	for _, m := range other {
+		t.Add(m)
+	}
+}
+
+// Currencies returns the currencies with a total, sorted
+func (t Totals) Currencies() []string {
+	currencies := make([]string, 0, len(t))
+	for currency := range t {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// In returns the total of a currency, zero when there is none
+func (t Totals) In(currency string) Money {
+	if total, ok := t[currency]; ok {
+		return total
+	}
+	return New(0, currency)
+}
+
+// Single returns the total when every amount shares a currency, or
+// ErrCurrencyMismatch
+func (t Totals) Single() (Money, error) {
+	var total Money
+	for _, currency := range t.Currencies() {
+		var err error
+		if total, err = total.TryAdd(t[currency]); err != nil {
+			return Money{}, fmt.Errorf("%w: %v", ErrCurrencyMismatch, t.Currencies())
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,166 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var ErrSyntax = errors.New("query syntax error")
+
+// SyntaxError is a parse error at a byte offset of the query
+type SyntaxError struct {
+	Query    string
+	Position int // Byte offset, 0 for the first character
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d: %s", ErrSyntax, e.Position+1, e.Message)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return ErrSyntax
+}
+
+// Caret returns the query with a caret under the offending position, for
+// monospaced error messages
+func (e *SyntaxError) Caret() string {
+	return e.Query + "\n" + strings.Repeat(" ", len([]rune(e.Query[:min(e.Position, len(e.Query))]))) + "^"
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString // Quoted text
+	tokenRegex  // /pattern/flags
+	tokenOperator
+	tokenOpen
+	tokenClose
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type token struct {
+	kind     tokenKind
+	text     string // Without quotes or slashes
+	flags    string // Regex flags
+	position int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return fmt.Sprintf("%q", t.text)
+	case tokenRegex:
+		return "/" + t.text + "/" + t.flags
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// Characters that end a word
+const operatorChars = ":=!<>~"
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	fail := func(position int, format string, args ...interface{}) ([]token, error) {
+		return nil, &SyntaxError{Query: source, Position: position, Message: fmt.Sprintf(format, args...)}
+	}
+	afterOperator := func() bool {
+		return len(tokens) > 0 && tokens[len(tokens)-1].kind == tokenOperator
+	}
+
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", position: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", position: i})
+			i++
+
+		case strings.IndexByte(operatorChars, c) >= 0:
+			operator := string(c)
+			if i+1 < len(source) && source[i+1] == '=' && strings.IndexByte("!<>", c) >= 0 {
+				operator += "="
+			}
+			if operator == "!" {
+				return fail(i, "unexpected \"!\", use \"not\" or \"!=\"")
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, position: i})
+			i += len(operator)
+
+		case c == '"':
+			var text strings.Builder
+			j := i + 1
+			for ; j < len(source) && source[j] != '"'; j++ {
+				if source[j] == '\\' && j+1 < len(source) {
+					j++
+				}
+				text.WriteByte(source[j])
+			}
+			if j == len(source) {
+				return fail(i, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), position: i})
+			i = j + 1
+
+		case c == '/' && afterOperator():
+			var pattern strings.Builder
+			j := i + 1
+			for ; j < len(source) && source[j] != '/'; j++ {
+				// Keep escapes for the regexp package, except for the slash itself
+				if source[j] == '\\' && j+1 < len(source) && source[j+1] == '/' {
+					j++
+				}
+				pattern.WriteByte(source[j])
+			}
+			if j == len(source) {
+				return fail(i, "unterminated regular expression")
+			}
+			k := j + 1
+			for k < len(source) && unicode.IsLetter(rune(source[k])) {
+				k++
+			}
+			tokens = append(tokens, token{kind: tokenRegex, text: pattern.String(), flags: source[j+1 : k], position: i})
+			i = k
+
+		case c == '-' && !afterOperator() && i+1 < len(source) && !strings.ContainsRune(" \t\n\r)", rune(source[i+1])):
+			// -term is short for not term
+			tokens = append(tokens, token{kind: tokenNot, text: "-", position: i})
+			i++
+
+		default:
+			j := i
+			for j < len(source) && !strings.ContainsRune(" \t\n\r()\""+operatorChars, rune(source[j])) {
+				j++
+			}
+			word := source[i:j]
+			kind := tokenWord
+			if !afterOperator() {
+				switch strings.ToLower(word) {
+				case "and":
+					kind = tokenAnd
+				case "or":
+					kind = tokenOr
+				case "not":
+					kind = tokenNot
+				}
+			}
+			tokens = append(tokens, token{kind: kind, text: word, position: i})
+			i = j
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, position: len(source)}), nil
+}
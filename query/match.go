@@ -0,0 +1,130 @@
+package query
+
+import (
+	"strings"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+// WithCategories returns a copy of the query that resolves categories and
+// tags against the stored categories: category:X then also matches the
+// descendants of X, and tag:X the tags of the stored category and concept.
+func (q *Query) WithCategories(categories models.Categories) *Query {
+	resolved := *q
+	resolved.categories = categories
+	return &resolved
+}
+
+// Match reports whether the block meets the query
+func (q *Query) Match(b models.Block) bool {
+	if q == nil || q.Expr == nil {
+		return true
+	}
+	return q.match(q.Expr, b)
+}
+
+// Filter returns the blocks that meet the query, in their order
+func (q *Query) Filter(blocks models.Blocks) models.Blocks {
+	var matched models.Blocks
+	for i := range blocks {
+		if q.Match(blocks[i]) {
+			matched = append(matched, blocks[i])
+		}
+	}
+	return matched
+}
+
+func (q *Query) match(expr Expr, b models.Block) bool {
+	switch e := expr.(type) {
+	case And:
+		return q.match(e.Left, b) && q.match(e.Right, b)
+	case Or:
+		return q.match(e.Left, b) || q.match(e.Right, b)
+	case Not:
+		return !q.match(e.Expr, b)
+	case Term:
+		return q.matchTerm(e, b)
+	}
+	return false
+}
+
+func (q *Query) matchTerm(t Term, b models.Block) bool {
+	switch t.Field {
+	case FieldText:
+		return t.matchText(b.Concept.Name) || t.matchText(b.Concept2) || t.matchText(b.Notes)
+	case FieldConcept:
+		return t.matchText(b.Concept.Name)
+	case FieldNotes:
+		return t.matchText(b.Notes)
+	case FieldAccount:
+		return t.matchText(b.Account)
+	case FieldAmount:
+		return t.Bounds.Contains(Thousandths(b.Amount))
+	case FieldDate:
+		return t.Bounds.Contains(DayNumber(b.Date))
+	}
+
+	// Category fields hold for the block when they hold for any split line
+	for _, part := range b.Parts() {
+		switch t.Field {
+		case FieldCategory:
+			if t.matchText(part.Category.ShortName) {
+				return true
+			}
+			for _, ancestor := range q.categories.Ancestors(part.Category.ShortName) {
+				if t.matchText(ancestor) {
+					return true
+				}
+			}
+		case FieldKind:
+			if t.matchText(string(part.Category.Semantics())) {
+				return true
+			}
+		case FieldTag:
+			for _, tag := range q.tags(b, part.Category) {
+				if t.matchText(tag.Slug) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// tags returns the tags of the concept and the category, including the
+// stored ones when the query has categories
+func (q *Query) tags(b models.Block, category models.Category) models.Tags {
+	tags := append(append(models.Tags{}, b.Concept.Tags...), category.Tags...)
+	if stored, ok := q.categories.Find(category.ShortName); ok {
+		tags = append(tags, stored.Tags...)
+	}
+	for _, stored := range q.categories {
+		for _, concept := range stored.Concepts {
+			if concept.Name == b.Concept.Name {
+				tags = append(tags, concept.Tags...)
+			}
+		}
+	}
+	return tags
+}
+
+func (t Term) matchText(s string) bool {
+	switch t.Match {
+	case models.MatchRegex:
+		return t.Regex.MatchString(s)
+	case models.MatchExact:
+		return strings.EqualFold(s, t.Value)
+	default:
+		return strings.Contains(strings.ToLower(s), strings.ToLower(t.Value))
+	}
+}
+
+// Thousandths returns an amount in thousandths of its currency unit, the
+// unit of amount Bounds
+func Thousandths(m money.Money) int64 {
+	thousandths := m.Minor()
+	for i := money.Exponent(m.Currency()); i < 3; i++ {
+		thousandths *= 10
+	}
+	return thousandths
+}
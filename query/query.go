@@ -0,0 +1,429 @@
+// Package query implements a small filter language over blocks, such as
+//
+//	category:food and amount>50 and date>=2025-01 and tag:work
+//
+// Terms are field:value pairs joined with and, or, not (or a leading -) and
+// parentheses; and can be omitted. A bare word searches the concept, Concept2
+// and notes. Query evaluates expressions over models.Blocks and the
+// repository compiles them to SQL.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"txeo-gui-library/models"
+)
+
+// Field is what a term looks at
+type Field string
+
+const (
+	FieldText     Field = "text"     // Bare words: concept, Concept2 or notes
+	FieldConcept  Field = "concept"  // Concept as given by the bank
+	FieldNotes    Field = "notes"    // Notes written by the user
+	FieldAccount  Field = "account"  // Block.Account
+	FieldCategory Field = "category" // Short name of the category, or of one of its ancestors
+	FieldTag      Field = "tag"      // Tag slug of the concept or the category
+	FieldKind     Field = "kind"     // Kind of the category, e.g. income
+	FieldAmount   Field = "amount"   // Signed like Block.Amount: positive for expenses
+	FieldDate     Field = "date"     // 2025, 2025-01 or 2025-01-31
+)
+
+var fieldNames = map[string]Field{
+	"concept":  FieldConcept,
+	"notes":    FieldNotes,
+	"note":     FieldNotes,
+	"account":  FieldAccount,
+	"category": FieldCategory,
+	"cat":      FieldCategory,
+	"tag":      FieldTag,
+	"kind":     FieldKind,
+	"amount":   FieldAmount,
+	"date":     FieldDate,
+}
+
+var categoryKinds = map[string]models.CategoryKind{
+	string(models.CategoryExpense):    models.CategoryExpense,
+	string(models.CategoryIncome):     models.CategoryIncome,
+	string(models.CategoryTransfer):   models.CategoryTransfer,
+	string(models.CategorySavings):    models.CategorySavings,
+	string(models.CategoryWithdrawal): models.CategoryWithdrawal,
+}
+
+// Expr is a node of a parsed query: And, Or, Not or Term
+type Expr interface {
+	Position() int // Byte offset in the query
+	String() string
+}
+
+type And struct{ Left, Right Expr }
+type Or struct{ Left, Right Expr }
+type Not struct {
+	At   int
+	Expr Expr
+}
+
+// Term is a single condition. Text fields use Match and Value (or Regex);
+// amount and date use Bounds.
+type Term struct {
+	At     int
+	Field  Field
+	Op     string // As written, ":" for bare words
+	Value  string
+	Match  models.MatchType // MatchContains, MatchExact or MatchRegex
+	Regex  *regexp.Regexp
+	Bounds Bounds
+}
+
+// Bounds is the half-open range From <= x < To of an amount, in thousandths
+// of the currency unit, or of a date, in days since 1970-01-01. A missing
+// side is unbounded.
+type Bounds struct {
+	From, To       int64
+	HasFrom, HasTo bool
+}
+
+func (b Bounds) Contains(x int64) bool {
+	return (!b.HasFrom || x >= b.From) && (!b.HasTo || x < b.To)
+}
+
+func (e And) Position() int  { return e.Left.Position() }
+func (e Or) Position() int   { return e.Left.Position() }
+func (e Not) Position() int  { return e.At }
+func (e Term) Position() int { return e.At }
+
+func (e And) String() string { return "(" + e.Left.String() + " and " + e.Right.String() + ")" }
+func (e Or) String() string  { return "(" + e.Left.String() + " or " + e.Right.String() + ")" }
+func (e Not) String() string { return "not " + e.Expr.String() }
+func (e Term) String() string {
+	value := e.Value
+	if e.Match == models.MatchRegex {
+		value = "/" + value + "/"
+	} else if value == "" || strings.ContainsAny(value, " ()\""+operatorChars) {
+		value = strconv.Quote(value)
+	}
+	if e.Field == FieldText {
+		return value
+	}
+	return string(e.Field) + e.Op + value
+}
+
+// Query is a parsed filter. The zero value matches every block.
+type Query struct {
+	Source string
+	Expr   Expr // Nil for an empty query
+
+	categories models.Categories
+}
+
+// Parse parses a query. Errors are *SyntaxError with the offending position.
+func Parse(source string) (*Query, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{source: source, tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return &Query{Source: source}, nil
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, p.errorAt(next, "unexpected %s", next)
+	}
+	return &Query{Source: source, Expr: expr}, nil
+}
+
+// MustParse is Parse for queries known to be valid, such as constants
+func MustParse(source string) *Query {
+	q, err := Parse(source)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+type parser struct {
+	source string
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) errorAt(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Query: p.source, Position: t.position, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.advance()
+		case tokenWord, tokenString, tokenNot, tokenOpen:
+			// Implicit and
+		default:
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if t := p.peek(); t.kind == tokenNot {
+		p.advance()
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{At: t.position, Expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokenOpen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenClose {
+			return nil, p.errorAt(closing, "expected \")\" to close the \"(\" at position %d, found %s", t.position+1, closing)
+		}
+		return expr, nil
+	case tokenString:
+		return Term{At: t.position, Field: FieldText, Op: ":", Value: t.text, Match: models.MatchContains}, nil
+	case tokenWord:
+		if p.peek().kind != tokenOperator {
+			return Term{At: t.position, Field: FieldText, Op: ":", Value: t.text, Match: models.MatchContains}, nil
+		}
+		return p.parseTerm(t)
+	case tokenEOF:
+		return nil, p.errorAt(t, "unexpected end of query, expected a term")
+	default:
+		return nil, p.errorAt(t, "unexpected %s, expected a term", t)
+	}
+}
+
+// parseTerm parses the operator and value after a field name
+func (p *parser) parseTerm(name token) (Expr, error) {
+	field, ok := fieldNames[strings.ToLower(name.text)]
+	if !ok {
+		return nil, p.errorAt(name, "unknown field %q", name.text)
+	}
+	operator := p.advance()
+	value := p.advance()
+	switch value.kind {
+	case tokenWord, tokenString:
+	case tokenRegex:
+		if operator.text != ":" && operator.text != "~" {
+			return nil, p.errorAt(value, "a regular expression needs \":\" or \"~\", not %q", operator.text)
+		}
+	default:
+		return nil, p.errorAt(value, "expected a value after %s%s, found %s", name.text, operator.text, value)
+	}
+
+	term := Term{At: name.position, Field: field, Op: operator.text, Value: value.text}
+	var err error
+	switch field {
+	case FieldAmount, FieldDate:
+		err = p.parseBounds(&term, operator, value)
+	default:
+		err = p.parseMatch(&term, operator, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// field!=value is not field=value
+	if operator.text == "!=" {
+		term.Op = "="
+		return Not{At: name.position, Expr: term}, nil
+	}
+	return term, nil
+}
+
+func (p *parser) parseMatch(term *Term, operator token, value token) error {
+	switch {
+	case value.kind == tokenRegex || operator.text == "~":
+		pattern := value.text
+		for _, flag := range value.flags {
+			if flag != 'i' {
+				return p.errorAt(value, "unknown regular expression flag %q", flag)
+			}
+			pattern = "(?i)" + pattern
+		}
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return p.errorAt(value, "invalid regular expression: %v", err)
+		}
+		term.Match, term.Regex, term.Value = models.MatchRegex, regex, pattern
+		return nil
+	case operator.text == "=" || operator.text == "!=":
+		term.Match = models.MatchExact
+	case operator.text == ":":
+		// Names are compared whole, free text is searched
+		term.Match = models.MatchContains
+		if term.Field == FieldCategory || term.Field == FieldTag || term.Field == FieldKind || term.Field == FieldAccount {
+			term.Match = models.MatchExact
+		}
+	default:
+		return p.errorAt(operator, "operator %q is only valid for amount and date", operator.text)
+	}
+
+	if term.Field == FieldKind {
+		if _, ok := categoryKinds[strings.ToLower(term.Value)]; !ok {
+			return p.errorAt(value, "unknown kind %q, expected expense, income, transfer, savings or withdrawal", term.Value)
+		}
+		term.Value = strings.ToLower(term.Value)
+	}
+	return nil
+}
+
+// parseBounds turns the operator and value of an amount or date term into
+// Bounds
+func (p *parser) parseBounds(term *Term, operator token, value token) error {
+	parse := parseAmount
+	if term.Field == FieldDate {
+		parse = parseDate
+	}
+
+	if from, to, ok := strings.Cut(value.text, ".."); ok {
+		if operator.text != ":" && operator.text != "=" && operator.text != "!=" {
+			return p.errorAt(operator, "a range needs \":\", not %q", operator.text)
+		}
+		if from == "" && to == "" {
+			return p.errorAt(value, "empty range")
+		}
+		if from != "" {
+			low, _, err := parse(from)
+			if err != nil {
+				return p.errorAt(value, "%v", err)
+			}
+			term.Bounds.From, term.Bounds.HasFrom = low, true
+		}
+		if to != "" {
+			_, high, err := parse(to)
+			if err != nil {
+				return p.errorAt(token{position: value.position + len(from) + 2}, "%v", err)
+			}
+			term.Bounds.To, term.Bounds.HasTo = high, true
+		}
+		if term.Bounds.HasFrom && term.Bounds.HasTo && term.Bounds.From >= term.Bounds.To {
+			return p.errorAt(value, "empty range %s", value.text)
+		}
+		return nil
+	}
+
+	low, high, err := parse(value.text)
+	if err != nil {
+		return p.errorAt(value, "%v", err)
+	}
+	switch operator.text {
+	case ":", "=", "!=":
+		term.Bounds = Bounds{From: low, To: high, HasFrom: true, HasTo: true}
+	case ">":
+		term.Bounds = Bounds{From: high, HasFrom: true}
+	case ">=":
+		term.Bounds = Bounds{From: low, HasFrom: true}
+	case "<":
+		term.Bounds = Bounds{To: low, HasTo: true}
+	case "<=":
+		term.Bounds = Bounds{To: high, HasTo: true}
+	default:
+		return p.errorAt(operator, "operator %q is not valid for %s", operator.text, term.Field)
+	}
+	return nil
+}
+
+var amountPattern = regexp.MustCompile(`^([+-]?)(\d*)(?:\.(\d{1,3}))?$`)
+
+// parseAmount reads a decimal such as -12.5 and returns [value, value+1)
+// in thousandths
+func parseAmount(s string) (int64, int64, error) {
+	parts := amountPattern.FindStringSubmatch(s)
+	if parts == nil || parts[2]+parts[3] == "" {
+		return 0, 0, fmt.Errorf("invalid amount %q, expected a number with up to three decimals", s)
+	}
+	units, _ := strconv.ParseInt("0"+parts[2], 10, 64)
+	thousandths, _ := strconv.ParseInt(parts[3]+strings.Repeat("0", 3-len(parts[3])), 10, 64)
+
+	value := units*1000 + thousandths
+	if parts[1] == "-" {
+		value = -value
+	}
+	return value, value + 1, nil
+}
+
+// parseDate reads a year, month or day and returns its first day and the
+// first day after it, in days since 1970-01-01
+func parseDate(s string) (int64, int64, error) {
+	for _, layout := range []struct {
+		layout string
+		years  int
+		months int
+		days   int
+	}{
+		{models.DateLayout, 0, 0, 1},
+		{"2006-01", 0, 1, 0},
+		{"2006", 1, 0, 0},
+	} {
+		if from, err := time.Parse(layout.layout, s); err == nil {
+			return DayNumber(from), DayNumber(from.AddDate(layout.years, layout.months, layout.days)), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid date %q, expected 2025, 2025-01 or 2025-01-31", s)
+}
+
+// DayNumber returns the days between 1970-01-01 and the day of t, the unit
+// of date Bounds
+func DayNumber(t time.Time) int64 {
+	return models.Day(t).Unix() / (24 * 60 * 60)
+}
+
+// DayOf is the inverse of DayNumber
+func DayOf(day int64) time.Time {
+	return time.Unix(day*24*60*60, 0).UTC()
+}
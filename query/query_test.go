@@ -0,0 +1,158 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"category:food amount>50", "(category:food and amount>50)"},
+		{"a or b c", "(a or (b and c))"},
+		{"(a or b) and c", "((a or b) and c)"},
+		{"-tag:work", "not tag:work"},
+		{"not not a", "not not a"},
+		{"concept!=rent", "not concept=rent"},
+		{"\"two words\"", "\"two words\""},
+		{"KIND:Income", "kind:income"},
+		{"cat:food", "category:food"},
+		{"date:2025-01..2025-03", "date:2025-01..2025-03"},
+		{"amount:-5", "amount:-5"},     // A minus after an operator is a sign
+		{"a - b", "((a and -) and b)"}, // A lone minus is a word
+	}
+	for _, test := range tests {
+		q, err := Parse(test.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.input, err)
+			continue
+		}
+		if got := q.Expr.String(); got != test.want {
+			t.Errorf("Parse(%q) = %s, want %s", test.input, got, test.want)
+		}
+	}
+
+	if q, err := Parse("  "); err != nil || q.Expr != nil {
+		t.Errorf("Parse of a blank query = %v, %v, want no expression", q, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		position int
+	}{
+		{"foo:bar", 0},
+		{"food and", 8},
+		{"and", 0},
+		{"a)", 1},
+		{"(a or b", 7},
+		{"a ! b", 2},
+		{"\"open", 0},
+		{"concept:/open", 8},
+		{"notes:/x/g", 6},
+		{"notes:/(/", 6},
+		{"amount>", 7},
+		{"amount>abc", 7},
+		{"amount>1..2", 6},
+		{"amount:..", 7},
+		{"date:2025..x", 11},
+		{"date:2025-03..2025-01", 5},
+		{"date~2025", 4},
+		{"concept>x", 7},
+		{"kind:food", 5},
+		{"a (b or)", 7},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.input)
+		var syntaxError *SyntaxError
+		if !errors.As(err, &syntaxError) || !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) error = %v, want a *SyntaxError", test.input, err)
+			continue
+		}
+		if syntaxError.Position != test.position || syntaxError.Query != test.input {
+			t.Errorf("Parse(%q) error at %d, want %d: %v", test.input, syntaxError.Position, test.position, err)
+		}
+	}
+}
+
+func TestSyntaxErrorCaret(t *testing.T) {
+	_, err := Parse("café foo:1")
+	var syntaxError *SyntaxError
+	if !errors.As(err, &syntaxError) {
+		t.Fatalf("error = %v, want a *SyntaxError", err)
+	}
+	// Positions are bytes, the caret is placed in characters
+	if want := "café foo:1\n     ^"; syntaxError.Caret() != want {
+		t.Errorf("Caret() = %q, want %q", syntaxError.Caret(), want)
+	}
+	if want := "query syntax error at position 7: unknown field \"foo\""; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	day := func(s string) time.Time {
+		date, _ := time.Parse(models.DateLayout, s)
+		return date
+	}
+	food := models.Category{ShortName: "food", ParentShortName: "home"}
+	salary := models.Category{ShortName: "salary", Kind: models.CategoryIncome}
+	blocks := models.Blocks{
+		{Concept: models.Concept{Name: "MERCADONA"}, Date: day("2025-01-10"), Amount: money.New(5000, "EUR"), Category: food},
+		{Concept: models.Concept{Name: "NOMINA"}, Date: day("2025-01-31"), Amount: money.New(-150000, "EUR"), Category: salary},
+		{Concept: models.Concept{Name: "Café Central"}, Date: day("2025-02-01"), Amount: money.New(250, "EUR"), Category: food, Notes: "with Ana"},
+	}
+	categories := models.Categories{{ShortName: "home"}, food, salary}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", []int{0, 1, 2}},
+		{"mercadona", []int{0}},
+		{"ana", []int{2}},
+		{"concept:ana", nil},
+		{"category:food", []int{0, 2}},
+		{"category:home", []int{0, 2}}, // Ancestors through WithCategories
+		{"kind:income", []int{1}},
+		{"kind:expense", []int{0, 2}},
+		{"amount>50", nil}, // Above 50, so 50.00 is out
+		{"amount>=50", []int{0}},
+		{"amount<0", []int{1}},
+		{"amount:2.5", []int{2}},
+		{"amount:0..100", []int{0, 2}},
+		{"date:2025-01", []int{0, 1}},
+		{"date>2025-01", []int{2}},
+		{"date<=2025-01-10", []int{0}},
+		{"date:2025-01-11..", []int{1, 2}},
+		{"concept~/^caf[eé]/i", []int{2}},
+		{"concept=nomina", []int{1}},
+		{"-category:food", []int{1}},
+		{"category!=food", []int{1}},
+		{"mercadona or nomina", []int{0, 1}},
+		{"(mercadona or nomina) amount<0", []int{1}},
+	}
+	for _, test := range tests {
+		q, err := Parse(test.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.query, err)
+			continue
+		}
+		got := q.WithCategories(categories).Filter(blocks)
+		if len(got) != len(test.want) {
+			t.Errorf("%q matches %d blocks, want %v", test.query, len(got), test.want)
+			continue
+		}
+		for i, index := range test.want {
+			if got[i].Concept.Name != blocks[index].Concept.Name {
+				t.Errorf("%q match %d is %q, want %q", test.query, i, got[i].Concept.Name, blocks[index].Concept.Name)
+			}
+		}
+	}
+}
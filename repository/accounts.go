@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+var ErrAccountNotFound = errors.New("account not found")
+
+// LoadAccounts returns every account sorted by name
+func (r *Repository) LoadAccounts() (models.Accounts, error) {
+	rows, err := r.db.Query(`SELECT id, name, iban, type, currency, opening_minor, opening_date FROM accounts ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts models.Accounts
+	for rows.Next() {
+		var account models.Account
+		var openingMinor int64
+		var openingDate string
+		if err := rows.Scan(&account.ID, &account.Name, &account.IBAN, &account.Type, &account.Currency, &openingMinor, &openingDate); err != nil {
+			return nil, err
+		}
+		account.OpeningBalance = money.New(openingMinor, account.Currency)
+		if openingDate != "" {
+			if account.OpeningDate, err = time.Parse(models.DateLayout, openingDate); err != nil {
+				return nil, err
+			}
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// SaveAccount inserts or updates an account by ID
+func (r *Repository) SaveAccount(account models.Account) error {
+	_, err := r.db.Exec(`INSERT INTO accounts (id, name, iban, type, currency, opening_minor, opening_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			iban = excluded.iban,
+			type = excluded.type,
+			currency = excluded.currency,
+			opening_minor = excluded.opening_minor,
+			opening_date = excluded.opening_date`,
+		account.ID, account.Name, account.IBAN, account.Type, account.Currency,
+		account.OpeningBalance.Minor(), formatOptionalDate(account.OpeningDate))
+	return err
+}
+
+// DeleteAccount removes an account that has no blocks
+func (r *Repository) DeleteAccount(id string) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		var blocks int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM blocks WHERE account = ?`, id).Scan(&blocks); err != nil {
+			return err
+		}
+		if blocks > 0 {
+			return fmt.Errorf("account %s still has %d blocks", id, blocks)
+		}
+
+		result, err := tx.Exec(`DELETE FROM accounts WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+		}
+		return nil
+	})
+}
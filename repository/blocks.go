@@ -0,0 +1,322 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+var ErrBlockNotFound = errors.New("block not found")
+
+const blockColumns = `b.id, b.account, b.date, b.value_date, b.concept, b.concept2, b.amount_minor, b.currency,
+	b.balance_minor, b.balance_currency, b.external_id, b.notes, b.transfer_id,
+	EXISTS(SELECT 1 FROM block_splits s WHERE s.block_id = b.id),
+	c.id, c.name, c.short_name, c.icon, c.color, c.kind, (SELECT p.short_name FROM categories p WHERE p.id = c.parent_id)`
+
+const blockFrom = ` FROM blocks b LEFT JOIN categories c ON c.id = b.category_id`
+
+// SaveBlocks stores the movements of an account. Blocks without an account
+// are tied to the given one, and blocks without an ID get their fingerprint
+// first (see models.Blocks.AssignIDs), so saving a re-imported statement
+// updates the bank data of the existing rows instead of duplicating them,
+// while keeping the category, notes and splits chosen by the user. Splits
+// are only replaced when the given block has them. It returns how many
+// blocks were new.
+func (r *Repository) SaveBlocks(account string, blocks models.Blocks) (int, error) {
+	blocks.SetAccount(account)
+	blocks.AssignIDs()
+
+	inserted := 0
+	err := r.withTx(func(tx *sql.Tx) error {
+		for _, block := range blocks {
+			categoryID, err := categoryIDFor(tx, block.Category)
+			if err != nil {
+				return err
+			}
+
+			var exists bool
+			if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM blocks WHERE id = ?)`, block.ID).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				inserted++
+			}
+
+			balanceMinor, balanceCurrency := nullableMoney(block)
+			_, err = tx.Exec(`INSERT INTO blocks (id, account, date, value_date, concept, concept2, amount_minor, currency, balance_minor, balance_currency, category_id, external_id, notes, transfer_id)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(id) DO UPDATE SET
+					value_date = excluded.value_date,
+					concept2 = excluded.concept2,
+					balance_minor = excluded.balance_minor,
+					balance_currency = excluded.balance_currency,
+					external_id = excluded.external_id,
+					category_id = COALESCE(blocks.category_id, excluded.category_id),
+					transfer_id = CASE WHEN excluded.transfer_id = '' THEN blocks.transfer_id ELSE excluded.transfer_id END`,
+				block.ID, block.Account, block.FormatDate(), formatOptionalDate(block.ValueDate), block.Concept.Name, block.Concept2,
+				block.Amount.Minor(), block.Amount.Currency(), balanceMinor, balanceCurrency, categoryID, block.ExternalID, block.Notes, block.TransferID)
+			if err != nil {
+				return err
+			}
+			if block.IsSplit() {
+				if err := saveSplits(tx, block); err != nil {
+					return err
+				}
+			}
+		}
+		return refreshCounts(tx, nil)
+	})
+	return inserted, err
+}
+
+// UpdateBlock saves the user editable fields of a stored block: category,
+// splits, Concept2, notes and the transfer pairing.
+func (r *Repository) UpdateBlock(block models.Block) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		categoryID, err := categoryIDFor(tx, block.Category)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(`UPDATE blocks SET category_id = ?, concept2 = ?, notes = ?, transfer_id = ? WHERE id = ?`,
+			categoryID, block.Concept2, block.Notes, block.TransferID, block.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("%w: %s", ErrBlockNotFound, block.ID)
+		}
+		if err := saveSplits(tx, block); err != nil {
+			return err
+		}
+		return refreshCounts(tx, nil)
+	})
+}
+
+// saveSplits replaces the stored split lines of a block after validating
+// them; a block that is not split has none.
+func saveSplits(tx *sql.Tx, block models.Block) error {
+	if block.IsSplit() {
+		if err := block.Splits.Validate(block.Amount); err != nil {
+			return fmt.Errorf("block %s: %w", block.ID, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM block_splits WHERE block_id = ?`, block.ID); err != nil {
+		return err
+	}
+	for i, split := range block.Splits {
+		categoryID, err := categoryIDFor(tx, split.Category)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO block_splits (block_id, position, category_id, amount_minor, currency, note) VALUES (?, ?, ?, ?, ?, ?)`,
+			block.ID, i, categoryID, split.Amount.Minor(), split.Amount.Currency(), split.Note)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) GetBlock(id string) (models.Block, error) {
+	blocks, err := r.queryBlocks(`SELECT `+blockColumns+blockFrom+` WHERE b.id = ?`, id)
+	if err != nil {
+		return models.Block{}, err
+	}
+	if len(blocks) == 0 {
+		return models.Block{}, fmt.Errorf("%w: %s", ErrBlockNotFound, id)
+	}
+	return blocks[0], nil
+}
+
+// BlocksBetween returns the blocks with from <= date < to, in date order,
+// using the date index.
+func (r *Repository) BlocksBetween(from time.Time, to time.Time) (models.Blocks, error) {
+	return r.queryBlocks(`SELECT `+blockColumns+blockFrom+` WHERE b.date >= ? AND b.date < ? ORDER BY b.date, b.rowid`,
+		from.Format(models.DateLayout), to.Format(models.DateLayout))
+}
+
+// BlocksForAccount returns the blocks of one account in date order
+func (r *Repository) BlocksForAccount(account string) (models.Blocks, error) {
+	return r.queryBlocks(`SELECT `+blockColumns+blockFrom+` WHERE b.account = ? ORDER BY b.date, b.rowid`, account)
+}
+
+// BlocksByCategory returns the blocks of the category with the given short
+// name, including split blocks with a line in it. An empty short name
+// returns the uncategorized blocks.
+func (r *Repository) BlocksByCategory(shortName string) (models.Blocks, error) {
+	if shortName == "" {
+		return r.queryBlocks(`SELECT ` + blockColumns + blockFrom + ` WHERE b.category_id IS NULL ORDER BY b.date, b.rowid`)
+	}
+	return r.queryBlocks(`SELECT `+blockColumns+blockFrom+`
+		WHERE b.category_id = (SELECT id FROM categories WHERE short_name = ?)
+			OR b.id IN (SELECT s.block_id FROM block_splits s JOIN categories sc ON sc.id = s.category_id WHERE sc.short_name = ?)
+		ORDER BY b.date, b.rowid`, shortName, shortName)
+}
+
+// SearchBlocks returns the blocks whose concept, Concept2 or notes contain
+// text, ignoring case.
+func (r *Repository) SearchBlocks(text string) (models.Blocks, error) {
+	pattern := "%" + escapeLike(text) + "%"
+	return r.queryBlocks(`SELECT `+blockColumns+blockFrom+`
+		WHERE b.concept LIKE ? ESCAPE '\' OR b.concept2 LIKE ? ESCAPE '\' OR b.notes LIKE ? ESCAPE '\'
+		ORDER BY b.date, b.rowid`, pattern, pattern, pattern)
+}
+
+func (r *Repository) queryBlocks(query string, args ...interface{}) (models.Blocks, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks models.Blocks
+	var split []int
+	for rows.Next() {
+		block, hasSplits, err := scanBlock(rows)
+		if err != nil {
+			return nil, err
+		}
+		if hasSplits {
+			split = append(split, len(blocks))
+		}
+		blocks = append(blocks, block)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, i := range split {
+		if blocks[i].Splits, err = r.loadSplits(blocks[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return blocks, nil
+}
+
+func (r *Repository) loadSplits(blockID string) (models.Splits, error) {
+	rows, err := r.db.Query(`SELECT s.amount_minor, s.currency, s.note, c.id, c.name, c.short_name, c.icon, c.color, c.kind,
+			(SELECT p.short_name FROM categories p WHERE p.id = c.parent_id)
+		FROM block_splits s LEFT JOIN categories c ON c.id = s.category_id
+		WHERE s.block_id = ? ORDER BY s.position`, blockID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var splits models.Splits
+	for rows.Next() {
+		var split models.Split
+		var amountMinor int64
+		var currency string
+		var category nullableCategory
+		if err := rows.Scan(&amountMinor, &currency, &split.Note, &category.id, &category.name, &category.shortName, &category.icon, &category.color, &category.kind, &category.parent); err != nil {
+			return nil, err
+		}
+		split.Amount = money.New(amountMinor, currency)
+		split.Category = category.toCategory()
+		splits = append(splits, split)
+	}
+	return splits, rows.Err()
+}
+
+// scanBlock reads a row of blockColumns and reports whether the block has
+// split lines to load
+func scanBlock(rows *sql.Rows) (models.Block, bool, error) {
+	var block models.Block
+	var date, valueDate, concept, currency string
+	var amountMinor int64
+	var balanceMinor sql.NullInt64
+	var balanceCurrency sql.NullString
+	var hasSplits bool
+	var category nullableCategory
+
+	err := rows.Scan(&block.ID, &block.Account, &date, &valueDate, &concept, &block.Concept2, &amountMinor, &currency,
+		&balanceMinor, &balanceCurrency, &block.ExternalID, &block.Notes, &block.TransferID, &hasSplits,
+		&category.id, &category.name, &category.shortName, &category.icon, &category.color, &category.kind, &category.parent)
+	if err != nil {
+		return block, false, err
+	}
+
+	if block.Date, err = time.Parse(models.DateLayout, date); err != nil {
+		return block, false, err
+	}
+	if valueDate != "" {
+		if block.ValueDate, err = time.Parse(models.DateLayout, valueDate); err != nil {
+			return block, false, err
+		}
+	}
+
+	block.Concept = models.NewConceptFromString(concept)
+	block.Amount = money.New(amountMinor, currency)
+	if balanceMinor.Valid {
+		block.Balance = money.New(balanceMinor.Int64, balanceCurrency.String)
+	}
+	if category.id.Valid {
+		block.Category = category.toCategory()
+		block.Concept.CategoryShortName = block.Category.ShortName
+	}
+	return block, hasSplits, nil
+}
+
+// nullableCategory holds the columns of a LEFT JOIN on categories
+type nullableCategory struct {
+	id                                         sql.NullInt64
+	name, shortName, icon, color, kind, parent sql.NullString
+}
+
+func (c nullableCategory) toCategory() models.Category {
+	if !c.id.Valid {
+		return models.Category{}
+	}
+	return models.Category{
+		ID:              int(c.id.Int64),
+		Name:            c.name.String,
+		ShortName:       c.shortName.String,
+		Icon:            c.icon.String,
+		Color:           c.color.String,
+		Kind:            models.CategoryKind(c.kind.String),
+		ParentShortName: c.parent.String,
+	}
+}
+
+// categoryIDFor resolves the stored category of a block by ID or short name.
+// Uncategorized blocks, and categories that are not stored (such as the
+// unknown "?" category), give NULL.
+func categoryIDFor(tx *sql.Tx, category models.Category) (interface{}, error) {
+	if category.ID != 0 {
+		return category.ID, nil
+	}
+	if category.ShortName == "" {
+		return nil, nil
+	}
+
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM categories WHERE short_name = ?`, category.ShortName).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return id, err
+}
+
+func nullableMoney(block models.Block) (interface{}, interface{}) {
+	if !block.HasBalance() {
+		return nil, nil
+	}
+	return block.Balance.Minor(), block.Balance.Currency()
+}
+
+func formatOptionalDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(models.DateLayout)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+func TestSaveBlocksUpsert(t *testing.T) {
+	r := newTestRepository(t)
+	saveTestCategories(t, r, "food", "fun")
+
+	market := testRepositoryBlock("MERCADONA", "2025-03-02", 1234)
+	market.Concept2 = "COMPRA"
+	inserted, err := r.SaveBlocks("main", models.Blocks{market, testRepositoryBlock("BAR", "2025-03-03", 500)})
+	if err != nil || inserted != 2 {
+		t.Fatalf("first import = %d, %v, want 2 new blocks", inserted, err)
+	}
+
+	stored, err := r.BlocksForAccount("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored[0].Category = models.Category{ShortName: "food"}
+	stored[0].Notes = "weekly shopping"
+	if err := r.UpdateBlock(stored[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-importing the statement refreshes the bank data and keeps what the
+	// user chose, even when the importer guesses another category
+	market.Concept2 = "COMPRA TARJETA"
+	market.Balance = money.New(98766, "EUR")
+	market.Category = models.Category{ShortName: "fun"}
+	inserted, err = r.SaveBlocks("main", models.Blocks{market, testRepositoryBlock("BAR", "2025-03-03", 500), testRepositoryBlock("CINE", "2025-03-04", 900)})
+	if err != nil || inserted != 1 {
+		t.Fatalf("re-import = %d, %v, want only CINE new", inserted, err)
+	}
+
+	stored, err = r.BlocksForAccount("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 3 {
+		t.Fatalf("%d blocks stored, want 3", len(stored))
+	}
+	got := stored[0]
+	if got.Concept2 != "COMPRA TARJETA" || got.Balance.Minor() != 98766 || got.Category.ShortName != "food" || got.Notes != "weekly shopping" {
+		t.Errorf("re-imported block %q balance %s category %q notes %q", got.Concept2, got.Balance, got.Category.ShortName, got.Notes)
+	}
+	if got.Account != "main" || got.Amount.Minor() != 1234 || got.FormatDate() != "2025-03-02" {
+		t.Errorf("re-imported block %s %s %s", got.Account, got.Amount, got.FormatDate())
+	}
+
+	// The importer category is used for a block the user did not categorize
+	if stored[1].Category.ShortName != "" {
+		t.Errorf("BAR got category %q", stored[1].Category.ShortName)
+	}
+}
+
+func TestSaveBlocksFingerprintTwins(t *testing.T) {
+	r := newTestRepository(t)
+
+	// Two identical coffees on the same day are two movements
+	twins := models.Blocks{testRepositoryBlock("CAFE", "2025-03-02", 150), testRepositoryBlock("CAFE", "2025-03-02", 150)}
+	if inserted, err := r.SaveBlocks("main", twins); err != nil || inserted != 2 {
+		t.Fatalf("twins = %d, %v, want 2 new blocks", inserted, err)
+	}
+
+	// Importing them again, or a statement that overlaps them, adds nothing
+	again := models.Blocks{testRepositoryBlock("CAFE", "2025-03-02", 150), testRepositoryBlock("CAFE", "2025-03-02", 150)}
+	if inserted, err := r.SaveBlocks("main", again); err != nil || inserted != 0 {
+		t.Errorf("same twins again = %d, %v, want nothing new", inserted, err)
+	}
+	third := models.Blocks{testRepositoryBlock("cafe ", "2025-03-02", 150), testRepositoryBlock("CAFE", "2025-03-02", 150), testRepositoryBlock("CAFE", "2025-03-02", 150)}
+	if inserted, err := r.SaveBlocks("main", third); err != nil || inserted != 1 {
+		t.Errorf("a third coffee = %d, %v, want 1 new block", inserted, err)
+	}
+
+	// The same movement on another account is another block
+	if inserted, err := r.SaveBlocks("savings", models.Blocks{testRepositoryBlock("CAFE", "2025-03-02", 150)}); err != nil || inserted != 1 {
+		t.Errorf("other account = %d, %v, want 1 new block", inserted, err)
+	}
+
+	stored, err := r.BlocksForAccount("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 3 || stored[0].ID == stored[1].ID || stored[1].ID == stored[2].ID {
+		t.Errorf("%d blocks stored for main, want 3 with different IDs", len(stored))
+	}
+}
+
+func TestSaveBlocksSplits(t *testing.T) {
+	r := newTestRepository(t)
+	saveTestCategories(t, r, "food", "home")
+
+	market := testRepositoryBlock("MERCADONA", "2025-03-02", 9000)
+	market.Splits = models.Splits{
+		{Category: models.Category{ShortName: "food"}, Amount: money.New(6000, "EUR"), Note: "fruta"},
+		{Category: models.Category{ShortName: "home"}, Amount: money.New(3000, "EUR")},
+	}
+	if _, err := r.SaveBlocks("main", models.Blocks{market}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := r.BlocksForAccount("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	splits := stored[0].Splits
+	if len(splits) != 2 || splits[0].Category.ShortName != "food" || splits[0].Amount.Minor() != 6000 || splits[0].Note != "fruta" || splits[1].Category.ShortName != "home" {
+		t.Fatalf("stored splits %+v", splits)
+	}
+	if byCategory, err := r.BlocksByCategory("home"); err != nil || len(byCategory) != 1 {
+		t.Errorf("BlocksByCategory(home) = %d blocks, %v, want the split block", len(byCategory), err)
+	}
+
+	// A re-import without splits keeps them
+	if _, err := r.SaveBlocks("main", models.Blocks{testRepositoryBlock("MERCADONA", "2025-03-02", 9000)}); err != nil {
+		t.Fatal(err)
+	}
+	if block, err := r.GetBlock(stored[0].ID); err != nil || len(block.Splits) != 2 {
+		t.Errorf("re-import left %d splits, %v", len(block.Splits), err)
+	}
+
+	// Lines that do not add up are rejected and nothing is saved
+	bad := stored[0]
+	bad.Notes = "changed"
+	bad.Splits = models.Splits{
+		{Category: models.Category{ShortName: "food"}, Amount: money.New(6000, "EUR")},
+		{Category: models.Category{ShortName: "home"}, Amount: money.New(2000, "EUR")},
+	}
+	if err := r.UpdateBlock(bad); err == nil {
+		t.Errorf("UpdateBlock saved splits that do not add up")
+	}
+	if block, _ := r.GetBlock(stored[0].ID); block.Notes != "" || len(block.Splits) != 2 || block.Splits[1].Amount.Minor() != 3000 {
+		t.Errorf("the failed update was not rolled back: notes %q splits %+v", block.Notes, block.Splits)
+	}
+
+	// Updating without lines removes the split
+	unsplit := stored[0]
+	unsplit.Splits = nil
+	unsplit.Category = models.Category{ShortName: "food"}
+	if err := r.UpdateBlock(unsplit); err != nil {
+		t.Fatal(err)
+	}
+	if block, _ := r.GetBlock(stored[0].ID); len(block.Splits) != 0 || block.Category.ShortName != "food" {
+		t.Errorf("unsplit block has %d splits, category %q", len(block.Splits), block.Category.ShortName)
+	}
+
+	if err := r.UpdateBlock(models.Block{ID: "missing"}); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("UpdateBlock of a missing block error = %v, want ErrBlockNotFound", err)
+	}
+}
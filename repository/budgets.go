@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+var ErrBudgetNotFound = errors.New("budget not found")
+
+// LoadBudgets returns the budgets of the categories that are not deleted
+func (r *Repository) LoadBudgets() (models.Budgets, error) {
+	rows, err := r.db.Query(`SELECT b.id, c.short_name, b.period, b.limit_minor, b.currency, b.rollover, b.start_date
+		FROM budgets b JOIN categories c ON c.id = b.category_id
+		WHERE c.deleted = 0
+		ORDER BY c.short_name, b.period`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets models.Budgets
+	for rows.Next() {
+		var budget models.Budget
+		var limitMinor int64
+		var currency, start string
+		if err := rows.Scan(&budget.ID, &budget.CategoryShortName, &budget.Period, &limitMinor, &currency, &budget.Rollover, &start); err != nil {
+			return nil, err
+		}
+		budget.Limit = money.New(limitMinor, currency)
+		if start != "" {
+			if budget.Start, err = time.Parse(models.DateLayout, start); err != nil {
+				return nil, err
+			}
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+// SaveBudget inserts the budget (when ID is 0) or updates it. A category has
+// at most one budget per period. The ID is set on insert.
+func (r *Repository) SaveBudget(budget *models.Budget) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		var categoryID int64
+		var deleted bool
+		err := tx.QueryRow(`SELECT id, deleted FROM categories WHERE short_name = ?`, budget.CategoryShortName).Scan(&categoryID, &deleted)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %q", ErrCategoryNotFound, budget.CategoryShortName)
+		}
+		if err != nil {
+			return err
+		}
+		if deleted {
+			return fmt.Errorf("%w: %q", ErrCategoryDeleted, budget.CategoryShortName)
+		}
+
+		if budget.ID == 0 {
+			result, err := tx.Exec(`INSERT INTO budgets (category_id, period, limit_minor, currency, rollover, start_date) VALUES (?, ?, ?, ?, ?, ?)`,
+				categoryID, budget.Period, budget.Limit.Minor(), budget.Limit.Currency(), budget.Rollover, formatOptionalDate(budget.Start))
+			if err != nil {
+				return err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			budget.ID = int(id)
+			return nil
+		}
+
+		result, err := tx.Exec(`UPDATE budgets SET category_id = ?, period = ?, limit_minor = ?, currency = ?, rollover = ?, start_date = ? WHERE id = ?`,
+			categoryID, budget.Period, budget.Limit.Minor(), budget.Limit.Currency(), budget.Rollover, formatOptionalDate(budget.Start), budget.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("%w: id %d", ErrBudgetNotFound, budget.ID)
+		}
+		return nil
+	})
+}
+
+func (r *Repository) DeleteBudget(id int) error {
+	result, err := r.db.Exec(`DELETE FROM budgets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: id %d", ErrBudgetNotFound, id)
+	}
+	return nil
+}
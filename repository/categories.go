@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"txeo-gui-library/models"
+)
+
+// LoadCategories returns the categories that are not deleted, sorted by short
+// name, with their concepts and tags.
+func (r *Repository) LoadCategories() (models.Categories, error) {
+	return r.loadCategories(false)
+}
+
+// LoadAllCategories is LoadCategories including the deleted ones.
+func (r *Repository) LoadAllCategories() (models.Categories, error) {
+	return r.loadCategories(true)
+}
+
+func (r *Repository) loadCategories(includeDeleted bool) (models.Categories, error) {
+	query := `SELECT c.id, c.name, c.short_name, c.count, c.deleted, c.traduction, c.icon, c.color, c.kind, COALESCE(p.short_name, '')
+		FROM categories c LEFT JOIN categories p ON p.id = c.parent_id`
+	if !includeDeleted {
+		query += ` WHERE c.deleted = 0`
+	}
+	query += ` ORDER BY c.short_name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories models.Categories
+	for rows.Next() {
+		var c models.Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.ShortName, &c.Count, &c.Deleted, &c.Traduction, &c.Icon, &c.Color, &c.Kind, &c.ParentShortName); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range categories {
+		if categories[i].Tags, err = r.loadTags(`SELECT t.name, t.slug FROM tags t JOIN category_tags ct ON ct.tag_id = t.id WHERE ct.category_id = ? ORDER BY t.slug`, categories[i].ID); err != nil {
+			return nil, err
+		}
+		if categories[i].Concepts, err = r.loadConcepts(categories[i]); err != nil {
+			return nil, err
+		}
+	}
+	return categories, nil
+}
+
+func (r *Repository) loadConcepts(category models.Category) (models.Concepts, error) {
+	rows, err := r.db.Query(`SELECT id, name, short_name, icon FROM concepts WHERE category_id = ? ORDER BY name`, category.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var concepts models.Concepts
+	for rows.Next() {
+		var id int64
+		concept := models.Concept{CategoryShortName: category.ShortName}
+		if err := rows.Scan(&id, &concept.Name, &concept.ShortName, &concept.Icon); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		concepts = append(concepts, concept)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range concepts {
+		if concepts[i].Tags, err = r.loadTags(`SELECT t.name, t.slug FROM tags t JOIN concept_tags ct ON ct.tag_id = t.id WHERE ct.concept_id = ? ORDER BY t.slug`, ids[i]); err != nil {
+			return nil, err
+		}
+	}
+	return concepts, nil
+}
+
+func (r *Repository) loadTags(query string, id interface{}) (models.Tags, error) {
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags models.Tags
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.Slug); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// SaveCategory inserts the category (when ID is 0) or updates it, together
+// with its tags and concepts. The ID is set on insert. Count is maintained by
+// the repository and ignored. The parent must exist and not be the category
+// or one of its descendants; a category without Kind takes the one of its
+// parent, or expense at the top level.
+func (r *Repository) SaveCategory(category *models.Category) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		parentID, parentKind, err := parentFor(tx, *category)
+		if err != nil {
+			return err
+		}
+		if category.Kind == "" {
+			category.Kind = parentKind
+		}
+
+		if category.ID == 0 {
+			result, err := tx.Exec(`INSERT INTO categories (name, short_name, deleted, traduction, icon, color, kind, parent_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				category.Name, category.ShortName, category.Deleted, category.Traduction, category.Icon, category.Color, category.Kind, parentID)
+			if err != nil {
+				return err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			category.ID = int(id)
+		} else {
+			result, err := tx.Exec(`UPDATE categories SET name = ?, short_name = ?, deleted = ?, traduction = ?, icon = ?, color = ?, kind = ?, parent_id = ? WHERE id = ?`,
+				category.Name, category.ShortName, category.Deleted, category.Traduction, category.Icon, category.Color, category.Kind, parentID, category.ID)
+			if err != nil {
+				return err
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				return fmt.Errorf("%w: id %d", ErrCategoryNotFound, category.ID)
+			}
+		}
+
+		if _, err := tx.Exec(`DELETE FROM category_tags WHERE category_id = ?`, category.ID); err != nil {
+			return err
+		}
+		for _, tag := range category.Tags {
+			tagID, err := saveTag(tx, tag)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO category_tags (category_id, tag_id) VALUES (?, ?)`, category.ID, tagID); err != nil {
+				return err
+			}
+		}
+
+		for _, concept := range category.Concepts {
+			if err := saveConcept(tx, int64(category.ID), concept); err != nil {
+				return err
+			}
+		}
+
+		return refreshCounts(tx, category)
+	})
+}
+
+// parentFor resolves the parent of a category to its id and kind, checking
+// that the category would not become its own ancestor. Top level categories
+// give NULL and CategoryExpense.
+func parentFor(tx *sql.Tx, category models.Category) (interface{}, models.CategoryKind, error) {
+	if category.ParentShortName == "" {
+		return nil, models.CategoryExpense, nil
+	}
+
+	var parentID int64
+	var kind models.CategoryKind
+	err := tx.QueryRow(`SELECT id, kind FROM categories WHERE short_name = ?`, category.ParentShortName).Scan(&parentID, &kind)
+	if err == sql.ErrNoRows {
+		return nil, "", fmt.Errorf("%w: %q", models.ErrParentCategoryNotFound, category.ParentShortName)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Walk up from the parent; meeting the category itself means a cycle
+	if category.ID != 0 {
+		var cycle bool
+		err := tx.QueryRow(`WITH RECURSIVE ancestors(id) AS (
+				SELECT ?
+				UNION SELECT c.parent_id FROM categories c JOIN ancestors a ON c.id = a.id WHERE c.parent_id IS NOT NULL
+			)
+			SELECT EXISTS(SELECT 1 FROM ancestors WHERE id = ?)`, parentID, category.ID).Scan(&cycle)
+		if err != nil {
+			return nil, "", err
+		}
+		if cycle {
+			return nil, "", fmt.Errorf("%w: %q under %q", models.ErrCategoryCycle, category.ShortName, category.ParentShortName)
+		}
+	}
+	return parentID, kind, nil
+}
+
+// DeleteCategory marks a category as deleted. Its concepts are kept so the
+// category can be restored with RestoreCategory.
+func (r *Repository) DeleteCategory(id int) error {
+	return r.setDeleted(id, true)
+}
+
+func (r *Repository) RestoreCategory(id int) error {
+	return r.setDeleted(id, false)
+}
+
+func (r *Repository) setDeleted(id int, deleted bool) error {
+	result, err := r.db.Exec(`UPDATE categories SET deleted = ? WHERE id = ?`, deleted, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: id %d", ErrCategoryNotFound, id)
+	}
+	return nil
+}
+
+// SaveConcept persists a concept-to-category assignment, such as the Concept
+// returned by Categories.AssignCategoryToSelectedConcept. The category is
+// found by CategoryShortName; a concept already assigned elsewhere is moved.
+func (r *Repository) SaveConcept(concept models.Concept) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		var categoryID int64
+		var deleted bool
+		err := tx.QueryRow(`SELECT id, deleted FROM categories WHERE short_name = ?`, concept.CategoryShortName).Scan(&categoryID, &deleted)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %q", ErrCategoryNotFound, concept.CategoryShortName)
+		}
+		if err != nil {
+			return err
+		}
+		if deleted {
+			return fmt.Errorf("%w: %q", ErrCategoryDeleted, concept.CategoryShortName)
+		}
+
+		return saveConcept(tx, categoryID, concept)
+	})
+}
+
+// DeleteConcept removes a concept so it is no longer auto-categorized.
+func (r *Repository) DeleteConcept(name string) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM concepts WHERE name = ?`, name)
+		return err
+	})
+}
+
+func saveConcept(tx *sql.Tx, categoryID int64, concept models.Concept) error {
+	_, err := tx.Exec(`INSERT INTO concepts (category_id, name, short_name, icon) VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET category_id = excluded.category_id, short_name = excluded.short_name, icon = excluded.icon`,
+		categoryID, concept.Name, concept.ShortName, concept.Icon)
+	if err != nil {
+		return err
+	}
+
+	var conceptID int64
+	if err := tx.QueryRow(`SELECT id FROM concepts WHERE name = ?`, concept.Name).Scan(&conceptID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM concept_tags WHERE concept_id = ?`, conceptID); err != nil {
+		return err
+	}
+	for _, tag := range concept.Tags {
+		tagID, err := saveTag(tx, tag)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO concept_tags (concept_id, tag_id) VALUES (?, ?)`, conceptID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveTag upserts a tag by slug and returns its id.
+func saveTag(tx *sql.Tx, tag models.Tag) (int64, error) {
+	if _, err := tx.Exec(`INSERT INTO tags (name, slug) VALUES (?, ?) ON CONFLICT(slug) DO UPDATE SET name = excluded.name`, tag.Name, tag.Slug); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM tags WHERE slug = ?`, tag.Slug).Scan(&id)
+	return id, err
+}
+
+// refreshCounts recomputes Count as the number of blocks of every category,
+// updating category in memory when given. A split block is counted by its
+// lines, once for each, and not by its own category, as the queries match it.
+func refreshCounts(tx *sql.Tx, category *models.Category) error {
+	if _, err := tx.Exec(`UPDATE categories SET count =
+		(SELECT COUNT(*) FROM blocks b WHERE b.category_id = categories.id
+			AND NOT EXISTS(SELECT 1 FROM block_splits s WHERE s.block_id = b.id))
+		+ (SELECT COUNT(*) FROM block_splits s WHERE s.category_id = categories.id)`); err != nil {
+		return err
+	}
+	if category == nil {
+		return nil
+	}
+	return tx.QueryRow(`SELECT count FROM categories WHERE id = ?`, category.ID).Scan(&category.Count)
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	repository, err := New(openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repository
+}
+
+// saveTestCategories stores top level expense categories with the given
+// short names
+func saveTestCategories(t *testing.T, r *Repository, shortNames ...string) {
+	for _, shortName := range shortNames {
+		if err := r.SaveCategory(&models.Category{Name: shortName, ShortName: shortName}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testRepositoryBlock(concept string, date string, minor int64) models.Block {
+	day, err := time.Parse(models.DateLayout, date)
+	if err != nil {
+		panic(err)
+	}
+	return *models.NewBlockWithMoney(concept, day, "", money.New(minor, "EUR"), money.Money{})
+}
+
+func counts(t *testing.T, r *Repository) map[string]int {
+	categories, err := r.LoadCategories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for _, category := range categories {
+		counts[category.ShortName] = category.Count
+	}
+	return counts
+}
+
+func TestCategoryCountsBlocks(t *testing.T) {
+	r := newTestRepository(t)
+	saveTestCategories(t, r, "food", "home", "fun")
+	if err := r.SaveConcept(models.Concept{Name: "MERCADONA", CategoryShortName: "food"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := counts(t, r); got["food"] != 0 {
+		t.Errorf("concepts are counted: %v", got)
+	}
+
+	bread := testRepositoryBlock("PANADERIA", "2025-03-01", 300)
+	bread.Category = models.Category{ShortName: "food"}
+	market := testRepositoryBlock("MERCADONA", "2025-03-02", 9000)
+	market.Category = models.Category{ShortName: "food"}
+	market.Splits = models.Splits{
+		{Category: models.Category{ShortName: "food"}, Amount: money.New(5000, "EUR")},
+		{Category: models.Category{ShortName: "home"}, Amount: money.New(2500, "EUR")},
+		{Category: models.Category{ShortName: "home"}, Amount: money.New(1500, "EUR")},
+	}
+	if _, err := r.SaveBlocks("main", models.Blocks{bread, market}); err != nil {
+		t.Fatal(err)
+	}
+	// The split block counts by its lines, not by its own category
+	if got := counts(t, r); got["food"] != 2 || got["home"] != 2 || got["fun"] != 0 {
+		t.Errorf("counts %v, want food 2, home 2, fun 0", got)
+	}
+
+	stored, err := r.BlocksForAccount("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range stored {
+		if block.Concept.Name == "MERCADONA" {
+			block.Category = models.Category{ShortName: "fun"}
+			block.Splits = nil
+			if err := r.UpdateBlock(block); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got := counts(t, r); got["food"] != 1 || got["home"] != 0 || got["fun"] != 1 {
+		t.Errorf("counts after unsplitting %v, want food 1, home 0, fun 1", got)
+	}
+}
+
+func TestSaveCategoryTree(t *testing.T) {
+	r := newTestRepository(t)
+	salary := models.Category{Name: "Nómina", ShortName: "salary", Kind: models.CategoryIncome}
+	if err := r.SaveCategory(&salary); err != nil {
+		t.Fatal(err)
+	}
+	bonus := models.Category{Name: "Bonus", ShortName: "bonus", ParentShortName: "salary"}
+	if err := r.SaveCategory(&bonus); err != nil {
+		t.Fatal(err)
+	}
+	extra := models.Category{Name: "Extra", ShortName: "extra", ParentShortName: "bonus"}
+	if err := r.SaveCategory(&extra); err != nil {
+		t.Fatal(err)
+	}
+	home := models.Category{Name: "Casa", ShortName: "home"}
+	if err := r.SaveCategory(&home); err != nil {
+		t.Fatal(err)
+	}
+	// Without kind a category takes the one of its parent, or expense
+	if bonus.Kind != models.CategoryIncome || extra.Kind != models.CategoryIncome || home.Kind != models.CategoryExpense {
+		t.Errorf("kinds %s %s %s", bonus.Kind, extra.Kind, home.Kind)
+	}
+
+	salary.ParentShortName = "extra"
+	if err := r.SaveCategory(&salary); !errors.Is(err, models.ErrCategoryCycle) {
+		t.Errorf("salary under its grandchild: %v, want ErrCategoryCycle", err)
+	}
+	bonus.ParentShortName = "bonus"
+	if err := r.SaveCategory(&bonus); !errors.Is(err, models.ErrCategoryCycle) {
+		t.Errorf("bonus under itself: %v, want ErrCategoryCycle", err)
+	}
+	if err := r.SaveCategory(&models.Category{Name: "Nada", ShortName: "nothing", ParentShortName: "nope"}); !errors.Is(err, models.ErrParentCategoryNotFound) {
+		t.Errorf("unknown parent: %v, want ErrParentCategoryNotFound", err)
+	}
+
+	categories, err := r.LoadCategories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := categories.ValidateTree(); err != nil {
+		t.Fatal(err)
+	}
+	if path := categories.Path("extra"); path != "Nómina › Bonus › Extra" {
+		t.Errorf("stored path %q", path)
+	}
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	ErrDatabaseTooNew   = errors.New("database schema is newer than this library")
+	ErrUnknownMigration = errors.New("unknown migration version")
+)
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change. SQL migrations come from the
+// embedded migrations directory; Go migrations are added with
+// registerMigration for changes that need code, such as backfilling data.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+	Down    func(tx *sql.Tx) error
+}
+
+// MigrationStep is a migration to apply in a given direction
+type MigrationStep struct {
+	Version int
+	Name    string
+	Down    bool
+}
+
+func (s MigrationStep) String() string {
+	direction := "up"
+	if s.Down {
+		direction = "down"
+	}
+	return fmt.Sprintf("%04d %s (%s)", s.Version, s.Name, direction)
+}
+
+var goMigrations []Migration
+
+// registerMigration adds a Go migration; call it from an init function.
+func registerMigration(migration Migration) {
+	goMigrations = append(goMigrations, migration)
+}
+
+// Migrator applies migrations and records them in the schema_migrations table.
+// With DryRun set, MigrateTo only returns the steps it would run.
+type Migrator struct {
+	DryRun     bool
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func loadMigrations() ([]Migration, error) {
+	byVersion := map[int]*Migration{}
+
+	files, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		match := migrationFilePattern.FindStringSubmatch(file.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", file.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := migrationFiles.ReadFile("migrations/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		step := sqlMigration(string(content))
+		if match[3] == "up" {
+			migration.Up = step
+		} else {
+			migration.Down = step
+		}
+	}
+
+	for _, goMigration := range goMigrations {
+		if _, ok := byVersion[goMigration.Version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d", goMigration.Version)
+		}
+		migration := goMigration
+		byVersion[migration.Version] = &migration
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("migration %d has no up step", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func sqlMigration(statements string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(statements)
+		return err
+	}
+}
+
+// Latest returns the highest version known to this library.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Applied returns the versions recorded in the database, in order.
+func (m *Migrator) Applied() ([]int, error) {
+	rows, err := m.db.Query(`SELECT version FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// Version returns the highest applied version, 0 for an empty database.
+func (m *Migrator) Version() (int, error) {
+	applied, err := m.Applied()
+	if err != nil || len(applied) == 0 {
+		return 0, err
+	}
+	return applied[len(applied)-1], nil
+}
+
+// Migrate applies every pending migration.
+func (m *Migrator) Migrate() ([]MigrationStep, error) {
+	return m.MigrateTo(m.Latest())
+}
+
+// Plan returns the steps needed to reach target, without running them.
+func (m *Migrator) Plan(target int) ([]MigrationStep, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[int]bool{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+	done := map[int]bool{}
+	for _, version := range applied {
+		if version > m.Latest() {
+			return nil, fmt.Errorf("%w: database at version %d, library knows up to %d", ErrDatabaseTooNew, version, m.Latest())
+		}
+		if !known[version] {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownMigration, version)
+		}
+		done[version] = true
+	}
+	if target != 0 && !known[target] {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownMigration, target)
+	}
+
+	var steps []MigrationStep
+	for _, migration := range m.migrations {
+		if migration.Version <= target && !done[migration.Version] {
+			steps = append(steps, MigrationStep{Version: migration.Version, Name: migration.Name})
+		}
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version > target && done[migration.Version] {
+			if migration.Down == nil {
+				return nil, fmt.Errorf("migration %d cannot be reverted", migration.Version)
+			}
+			steps = append(steps, MigrationStep{Version: migration.Version, Name: migration.Name, Down: true})
+		}
+	}
+	return steps, nil
+}
+
+// MigrateTo moves the schema up or down to target. Every step runs in its own
+// transaction together with its schema_migrations record, so a failure leaves
+// the database at the last successful version. The steps run (or, with
+// DryRun, the steps that would run) are returned.
+func (m *Migrator) MigrateTo(target int) ([]MigrationStep, error) {
+	steps, err := m.Plan(target)
+	if err != nil || m.DryRun {
+		return steps, err
+	}
+
+	for i, step := range steps {
+		if err := m.apply(step); err != nil {
+			return steps[:i], fmt.Errorf("migration %s: %w", step, err)
+		}
+	}
+	return steps, nil
+}
+
+func (m *Migrator) apply(step MigrationStep) error {
+	var migration Migration
+	for _, candidate := range m.migrations {
+		if candidate.Version == step.Version {
+			migration = candidate
+		}
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if step.Down {
+		err = migration.Down(tx)
+		if err == nil {
+			_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, step.Version)
+		}
+	} else {
+		err = migration.Up(tx)
+		if err == nil {
+			_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				step.Version, step.Name, time.Now().UTC().Format(time.RFC3339))
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS concepts_category_id;
+DROP TABLE IF EXISTS concept_tags;
+DROP TABLE IF EXISTS category_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS concepts;
+DROP TABLE IF EXISTS categories;
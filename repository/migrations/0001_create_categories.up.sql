@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS categories (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	name        TEXT    NOT NULL,
+	short_name  TEXT    NOT NULL UNIQUE,
+	count       INTEGER NOT NULL DEFAULT 0,
+	deleted     INTEGER NOT NULL DEFAULT 0,
+	traduction  TEXT,
+	icon        TEXT    NOT NULL DEFAULT '',
+	color       TEXT    NOT NULL DEFAULT '',
+	subcategory TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS concepts (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	category_id INTEGER NOT NULL REFERENCES categories(id),
+	name        TEXT    NOT NULL UNIQUE,
+	short_name  TEXT    NOT NULL DEFAULT '',
+	icon        TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+	id   INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	slug TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS category_tags (
+	category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+	tag_id      INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (category_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS concept_tags (
+	concept_id INTEGER NOT NULL REFERENCES concepts(id) ON DELETE CASCADE,
+	tag_id     INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (concept_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS concepts_category_id ON concepts(category_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS blocks_concept;
+DROP INDEX IF EXISTS blocks_category_date;
+DROP INDEX IF EXISTS blocks_date;
+DROP TABLE IF EXISTS blocks;
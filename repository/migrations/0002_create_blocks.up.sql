@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS blocks (
+	id               TEXT    PRIMARY KEY,
+	account          TEXT    NOT NULL DEFAULT '',
+	date             TEXT    NOT NULL,
+	value_date       TEXT    NOT NULL DEFAULT '',
+	concept          TEXT    NOT NULL,
+	concept2         TEXT    NOT NULL DEFAULT '',
+	amount_minor     INTEGER NOT NULL,
+	currency         TEXT    NOT NULL,
+	balance_minor    INTEGER,
+	balance_currency TEXT,
+	category_id      INTEGER REFERENCES categories(id),
+	external_id      TEXT    NOT NULL DEFAULT '',
+	notes            TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS blocks_date ON blocks(date);
+CREATE INDEX IF NOT EXISTS blocks_category_date ON blocks(category_id, date);
+CREATE INDEX IF NOT EXISTS blocks_concept ON blocks(concept);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS blocks_account_date;
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS accounts (
+	id               TEXT    PRIMARY KEY,
+	name             TEXT    NOT NULL,
+	iban             TEXT    NOT NULL DEFAULT '',
+	type             TEXT    NOT NULL DEFAULT 'checking',
+	currency         TEXT    NOT NULL,
+	opening_minor    INTEGER NOT NULL DEFAULT 0,
+	opening_date     TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS blocks_account_date ON blocks(account, date);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS blocks_transfer_id;
+ALTER TABLE blocks DROP COLUMN transfer_id;
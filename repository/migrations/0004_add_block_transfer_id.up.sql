@@ -0,0 +1,3 @@
+ALTER TABLE blocks ADD COLUMN transfer_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS blocks_transfer_id ON blocks(transfer_id);
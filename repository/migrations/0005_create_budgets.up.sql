@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS budgets (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	category_id  INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+	period       TEXT    NOT NULL DEFAULT 'monthly',
+	limit_minor  INTEGER NOT NULL,
+	currency     TEXT    NOT NULL,
+	rollover     TEXT    NOT NULL DEFAULT 'none',
+	start_date   TEXT    NOT NULL DEFAULT '',
+	UNIQUE (category_id, period)
+);
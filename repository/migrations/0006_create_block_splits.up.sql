@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS block_splits (
+	block_id     TEXT    NOT NULL REFERENCES blocks(id) ON DELETE CASCADE,
+	position     INTEGER NOT NULL,
+	category_id  INTEGER REFERENCES categories(id),
+	amount_minor INTEGER NOT NULL,
+	currency     TEXT    NOT NULL,
+	note         TEXT    NOT NULL DEFAULT '',
+	PRIMARY KEY (block_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS block_splits_category ON block_splits(category_id);
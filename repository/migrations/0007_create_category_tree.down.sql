@@ -0,0 +1,7 @@
+ALTER TABLE categories ADD COLUMN subcategory TEXT NOT NULL DEFAULT '';
+
+UPDATE categories SET subcategory = CASE kind WHEN 'expense' THEN '' ELSE kind END;
+
+DROP INDEX IF EXISTS categories_parent_id;
+ALTER TABLE categories DROP COLUMN kind;
+ALTER TABLE categories DROP COLUMN parent_id;
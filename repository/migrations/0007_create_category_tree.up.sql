@@ -0,0 +1,16 @@
+ALTER TABLE categories ADD COLUMN parent_id INTEGER REFERENCES categories(id);
+ALTER TABLE categories ADD COLUMN kind TEXT NOT NULL DEFAULT '';
+
+UPDATE categories SET kind = CASE subcategory
+	WHEN 'income'     THEN 'income'
+	WHEN 'savings'    THEN 'savings'
+	WHEN 'HUCHA_SAVE' THEN 'savings'
+	WHEN 'withdrawal' THEN 'withdrawal'
+	WHEN 'HUCHA_TAKE' THEN 'withdrawal'
+	WHEN 'transfer'   THEN 'transfer'
+	ELSE 'expense'
+END;
+
+ALTER TABLE categories DROP COLUMN subcategory;
+
+CREATE INDEX IF NOT EXISTS categories_parent_id ON categories(parent_id);
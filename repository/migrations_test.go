@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open(driverName, filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB) *Migrator {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrator
+}
+
+// tables returns the user tables of the database, schema_migrations included
+func tables(t *testing.T, db *sql.DB) []string {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := openTestDB(t)
+	migrator := newTestMigrator(t, db)
+	latest := migrator.Latest()
+	if latest == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	migrator.DryRun = true
+	steps, err := migrator.Migrate()
+	if err != nil || len(steps) != latest {
+		t.Fatalf("dry run = %v, %v, want %d steps", steps, err, latest)
+	}
+	if version, _ := migrator.Version(); version != 0 {
+		t.Errorf("dry run applied up to %d", version)
+	}
+
+	migrator.DryRun = false
+	if _, err := migrator.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	schema := tables(t, db)
+	if steps, err := migrator.Migrate(); err != nil || len(steps) != 0 {
+		t.Errorf("second Migrate = %v, %v, want nothing to do", steps, err)
+	}
+
+	// Every down step must undo its up step, so going down to each version
+	// and back up leaves the same schema
+	for target := latest - 1; target >= 0; target-- {
+		steps, err := migrator.MigrateTo(target)
+		if err != nil {
+			t.Fatalf("down to %d: %v", target, err)
+		}
+		if len(steps) != latest-target || !steps[0].Down || steps[0].Version != latest || steps[len(steps)-1].Version != target+1 {
+			t.Errorf("down to %d ran %v", target, steps)
+		}
+		if version, _ := migrator.Version(); version != target {
+			t.Errorf("down to %d left version %d", target, version)
+		}
+		if _, err := migrator.Migrate(); err != nil {
+			t.Fatalf("up from %d: %v", target, err)
+		}
+		if got := tables(t, db); strings.Join(got, ",") != strings.Join(schema, ",") {
+			t.Errorf("up from %d gives tables %v, want %v", target, got, schema)
+		}
+	}
+
+	if _, err := migrator.MigrateTo(0); err != nil {
+		t.Fatal(err)
+	}
+	if got := tables(t, db); len(got) != 1 || got[0] != "schema_migrations" {
+		t.Errorf("tables after reverting everything: %v", got)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	db := openTestDB(t)
+	migrator := newTestMigrator(t, db)
+
+	if _, err := migrator.MigrateTo(migrator.Latest() + 1000); !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("unknown target error = %v, want ErrUnknownMigration", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'future', '')`, migrator.Latest()+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Migrate(); !errors.Is(err, ErrDatabaseTooNew) {
+		t.Errorf("newer database error = %v, want ErrDatabaseTooNew", err)
+	}
+	if _, err := New(db); !errors.Is(err, ErrDatabaseTooNew) {
+		t.Errorf("New on a newer database error = %v, want ErrDatabaseTooNew", err)
+	}
+}
+
+func TestMigrateRollsBackFailedStep(t *testing.T) {
+	db := openTestDB(t)
+	migrator := newTestMigrator(t, db)
+	latest := migrator.Latest()
+	failure := errors.New("backfill failed")
+	migrator.migrations = append(migrator.migrations, Migration{
+		Version: latest + 1,
+		Name:    "failing",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`CREATE TABLE half_done (id INTEGER)`); err != nil {
+				return err
+			}
+			return failure
+		},
+	})
+
+	steps, err := migrator.Migrate()
+	if !errors.Is(err, failure) || len(steps) != latest {
+		t.Errorf("Migrate = %d steps, %v, want %d steps and the failure", len(steps), err, latest)
+	}
+	if version, _ := migrator.Version(); version != latest {
+		t.Errorf("version %d, want the last successful %d", version, latest)
+	}
+	for _, table := range tables(t, db) {
+		if table == "half_done" {
+			t.Errorf("the failed step was not rolled back")
+		}
+	}
+}
+
+func TestMigrateKeepsBlocks(t *testing.T) {
+	db := openTestDB(t)
+	migrator := newTestMigrator(t, db)
+	if _, err := migrator.MigrateTo(3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO categories (name, short_name) VALUES ('Comida', 'food')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO blocks (id, account, date, concept, amount_minor, currency, category_id)
+		VALUES ('b1', 'main', '2025-03-02', 'MERCADONA', 4500, 'EUR', (SELECT id FROM categories WHERE short_name = 'food'))`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Blocks from before 0004 are not transfers
+	if _, err := migrator.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	var transferID, category string
+	var amount int64
+	row := db.QueryRow(`SELECT b.transfer_id, b.amount_minor, c.short_name FROM blocks b JOIN categories c ON c.id = b.category_id WHERE b.id = 'b1'`)
+	if err := row.Scan(&transferID, &amount, &category); err != nil {
+		t.Fatal(err)
+	}
+	if transferID != "" || amount != 4500 || category != "food" {
+		t.Errorf("after migrating up: transfer %q, amount %d, category %q", transferID, amount, category)
+	}
+
+	if _, err := db.Exec(`UPDATE blocks SET transfer_id = 't1' WHERE id = 'b1'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.MigrateTo(3); err != nil {
+		t.Fatal(err)
+	}
+	row = db.QueryRow(`SELECT b.amount_minor, c.short_name FROM blocks b JOIN categories c ON c.id = b.category_id WHERE b.id = 'b1'`)
+	if err := row.Scan(&amount, &category); err != nil {
+		t.Fatalf("block lost going down: %v", err)
+	}
+	if amount != 4500 || category != "food" {
+		t.Errorf("after migrating down: amount %d, category %q", amount, category)
+	}
+}
+
+func TestMigrateCategoryKinds(t *testing.T) {
+	db := openTestDB(t)
+	migrator := newTestMigrator(t, db)
+	if _, err := migrator.MigrateTo(6); err != nil {
+		t.Fatal(err)
+	}
+
+	subcategories := map[string]string{
+		"salary":   "income",
+		"hucha":    "HUCHA_SAVE",
+		"savings":  "savings",
+		"take":     "HUCHA_TAKE",
+		"withdraw": "withdrawal",
+		"moves":    "transfer",
+		"food":     "",
+		"other":    "whatever",
+	}
+	for shortName, subcategory := range subcategories {
+		if _, err := db.Exec(`INSERT INTO categories (name, short_name, subcategory) VALUES (?, ?, ?)`, shortName, shortName, subcategory); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := migrator.MigrateTo(7); err != nil {
+		t.Fatal(err)
+	}
+	wantKinds := map[string]string{
+		"salary":   "income",
+		"hucha":    "savings",
+		"savings":  "savings",
+		"take":     "withdrawal",
+		"withdraw": "withdrawal",
+		"moves":    "transfer",
+		"food":     "expense",
+		"other":    "expense",
+	}
+	if got := columnByShortName(t, db, "kind"); !sameStrings(got, wantKinds) {
+		t.Errorf("kinds after 0007 %v, want %v", got, wantKinds)
+	}
+
+	// Going down keeps the meaning; expense is the empty subcategory again
+	if _, err := migrator.MigrateTo(6); err != nil {
+		t.Fatal(err)
+	}
+	wantSubcategories := map[string]string{
+		"salary":   "income",
+		"hucha":    "savings",
+		"savings":  "savings",
+		"take":     "withdrawal",
+		"withdraw": "withdrawal",
+		"moves":    "transfer",
+		"food":     "",
+		"other":    "",
+	}
+	if got := columnByShortName(t, db, "subcategory"); !sameStrings(got, wantSubcategories) {
+		t.Errorf("subcategories after reverting 0007 %v, want %v", got, wantSubcategories)
+	}
+}
+
+// columnByShortName reads a text column of every category
+func columnByShortName(t *testing.T, db *sql.DB, column string) map[string]string {
+	rows, err := db.Query(`SELECT short_name, ` + column + ` FROM categories`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	values := map[string]string{}
+	for rows.Next() {
+		var shortName, value string
+		if err := rows.Scan(&shortName, &value); err != nil {
+			t.Fatal(err)
+		}
+		values[shortName] = value
+	}
+	return values
+}
+
+func sameStrings(got map[string]string, want map[string]string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for key, value := range want {
+		if got[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"txeo-gui-library/models"
+	"txeo-gui-library/money"
+	"txeo-gui-library/query"
+)
+
+// FindBlocks returns the blocks that meet a query, in date order. Category
+// terms include the descendants of the category and tag terms the tags of
+// the stored category and concept, like q.WithCategories with the stored
+// categories. Regular expressions need a database opened with Open.
+func (r *Repository) FindBlocks(q *query.Query) (models.Blocks, error) {
+	where, args := CompileQuery(q)
+	return r.queryBlocks(`SELECT `+blockColumns+blockFrom+` WHERE `+where+` ORDER BY b.date, b.rowid`, args...)
+}
+
+// CompileQuery translates a query to a condition over the blocks table
+// (aliased b) and its arguments
+func CompileQuery(q *query.Query) (string, []interface{}) {
+	if q == nil || q.Expr == nil {
+		return "1", nil
+	}
+	c := &queryCompiler{}
+	return c.compile(q.Expr), c.args
+}
+
+type queryCompiler struct {
+	args []interface{}
+}
+
+func (c *queryCompiler) compile(expr query.Expr) string {
+	switch e := expr.(type) {
+	case query.And:
+		return "(" + c.compile(e.Left) + " AND " + c.compile(e.Right) + ")"
+	case query.Or:
+		return "(" + c.compile(e.Left) + " OR " + c.compile(e.Right) + ")"
+	case query.Not:
+		return "(NOT " + c.compile(e.Expr) + ")"
+	case query.Term:
+		return c.compileTerm(e)
+	}
+	return "0"
+}
+
+func (c *queryCompiler) compileTerm(t query.Term) string {
+	switch t.Field {
+	case query.FieldText:
+		return "(" + c.match("b.concept", t) + " OR " + c.match("b.concept2", t) + " OR " + c.match("b.notes", t) + ")"
+	case query.FieldConcept:
+		return c.match("b.concept", t)
+	case query.FieldNotes:
+		return c.match("b.notes", t)
+	case query.FieldAccount:
+		return c.match("b.account", t)
+	case query.FieldAmount:
+		return c.bounds(amountThousandths, t.Bounds, func(x int64) interface{} { return x })
+	case query.FieldDate:
+		return c.bounds("b.date", t.Bounds, func(day int64) interface{} { return query.DayOf(day).Format(models.DateLayout) })
+	case query.FieldCategory:
+		return c.parts(func(column string) string {
+			if t.Match == models.MatchExact && t.Value == "" {
+				return column + " IS NULL"
+			}
+			return column + ` IS NOT NULL AND ` + column + ` IN (WITH RECURSIVE tree(id) AS (
+				SELECT id FROM categories WHERE ` + c.match("short_name", t) + `
+				UNION SELECT k.id FROM categories k JOIN tree ON k.parent_id = tree.id
+			) SELECT id FROM tree)`
+		})
+	case query.FieldKind:
+		return c.parts(func(column string) string {
+			return c.match(`COALESCE(NULLIF((SELECT kind FROM categories WHERE id = `+column+`), ''), '`+string(models.CategoryExpense)+`')`, t)
+		})
+	case query.FieldTag:
+		concept := `EXISTS(SELECT 1 FROM concepts co JOIN concept_tags cot ON cot.concept_id = co.id JOIN tags t ON t.id = cot.tag_id
+			WHERE co.name = b.concept AND ` + c.match("t.slug", t) + `)`
+		return "(" + concept + " OR " + c.parts(func(column string) string {
+			return column + ` IS NOT NULL AND ` + column + ` IN (SELECT cat.category_id FROM category_tags cat JOIN tags t ON t.id = cat.tag_id WHERE ` + c.match("t.slug", t) + `)`
+		}) + ")"
+	}
+	return "0"
+}
+
+// parts applies a condition on a category id column to the parts of a
+// block: its own category when it is not split, or any of its split lines.
+// The condition must be false, not NULL, for uncategorized parts so that it
+// can be negated.
+func (c *queryCompiler) parts(condition func(column string) string) string {
+	return `((NOT EXISTS(SELECT 1 FROM block_splits s WHERE s.block_id = b.id) AND ` + condition("b.category_id") + `)
+		OR EXISTS(SELECT 1 FROM block_splits s WHERE s.block_id = b.id AND ` + condition("s.category_id") + `))`
+}
+
+func (c *queryCompiler) match(column string, t query.Term) string {
+	switch t.Match {
+	case models.MatchRegex:
+		c.args = append(c.args, t.Regex.String())
+		return column + " REGEXP ?"
+	case models.MatchExact:
+		c.args = append(c.args, t.Value)
+		return column + " = ? COLLATE NOCASE"
+	default:
+		c.args = append(c.args, "%"+escapeLike(t.Value)+"%")
+		return column + ` LIKE ? ESCAPE '\'`
+	}
+}
+
+func (c *queryCompiler) bounds(column string, bounds query.Bounds, value func(int64) interface{}) string {
+	var conditions []string
+	if bounds.HasFrom {
+		conditions = append(conditions, column+" >= ?")
+		c.args = append(c.args, value(bounds.From))
+	}
+	if bounds.HasTo {
+		conditions = append(conditions, column+" < ?")
+		c.args = append(c.args, value(bounds.To))
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
+
+// amountThousandths is query.Thousandths in SQL
+var amountThousandths = func() string {
+	exponents := money.CurrencyExponents()
+	currencies := make([]string, 0, len(exponents))
+	for currency := range exponents {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var scale strings.Builder
+	scale.WriteString("b.amount_minor * CASE b.currency")
+	for _, currency := range currencies {
+		factor := 1
+		for i := exponents[currency]; i < 3; i++ {
+			factor *= 10
+		}
+		fmt.Fprintf(&scale, " WHEN '%s' THEN %d", currency, factor)
+	}
+	scale.WriteString(" ELSE 10 END")
+	return scale.String()
+}()
@@ -0,0 +1,99 @@
+// Package repository persists categories, concepts and tags in a local SQLite
+// database.
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryDeleted  = errors.New("category is deleted")
+)
+
+// Driver registered by this package: go-sqlite3 with the REGEXP function
+// used by the regular expressions of queries
+const driverName = "sqlite3_txeo"
+
+var compiledPatterns sync.Map
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", sqlRegexp, true)
+		},
+	})
+}
+
+// sqlRegexp implements "text REGEXP pattern", caching the compiled patterns
+func sqlRegexp(pattern string, text string) (bool, error) {
+	if cached, ok := compiledPatterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp).MatchString(text), nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	compiledPatterns.Store(pattern, compiled)
+	return compiled.MatchString(text), nil
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+// Open opens (or creates) the SQLite database at path and migrates it to the
+// latest schema.
+func Open(path string) (*Repository, error) {
+	db, err := sql.Open(driverName, path+"?_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+
+	repository, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return repository, nil
+}
+
+// New wraps an already opened database and applies the pending migrations.
+// It fails with ErrDatabaseTooNew when the database was written by a newer
+// version of the library.
+func New(db *sql.DB) (*Repository, error) {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrator.Migrate(); err != nil {
+		return nil, err
+	}
+	return &Repository{db: db}, nil
+}
+
+func (r *Repository) DB() *sql.DB {
+	return r.db
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// withTx runs fn inside a transaction, rolling back when it fails.
+func (r *Repository) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
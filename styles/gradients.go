@@ -3,6 +3,7 @@ package styles
 import (
 	"image/color"
 	"math"
+	"txeo-gui-library/money"
 
 	"fyne.io/fyne/v2/widget"
 )
@@ -24,19 +25,28 @@ var (
 )
 
 func GetStyleForAmount(amount float64) *widget.CustomTextGridStyle {
+	return GetStyleForMoney(money.FromFloat(amount, ""))
+}
+
+func GetStyleForMoney(amount money.Money) *widget.CustomTextGridStyle {
+	// Trabajar en unidades menores (céntimos) para no arrastrar errores de float
+	unit := int64(math.Pow10(money.Exponent(amount.Currency())))
+	minMinor := int64(minVal) * unit
+	maxMinor := int64(maxVal) * unit
+
 	// Ajustar el amount al rango [minVal, maxVal]
-	if amount < minVal {
-		amount = minVal
+	if amount.Minor() < minMinor {
+		amount = amount.WithMinor(minMinor)
 	}
-	if amount > maxVal {
-		amount = maxVal
+	if amount.Minor() > maxMinor {
+		amount = amount.WithMinor(maxMinor)
 	}
 
 	// Redondear a múltiplos de 5
-	snapVal := 5.0 * math.Round(amount/5.0)
+	snapVal := amount.Round(5*unit, money.RoundHalfUp).Minor()
 
 	// Calcular la fracción en el rango
-	fraction := (snapVal - minVal) / (maxVal - minVal)
+	fraction := float64(snapVal-minMinor) / float64(maxMinor-minMinor)
 	if fraction < 0 {
 		fraction = 0
 	} else if fraction > 1 {
@@ -72,19 +82,24 @@ func GetStyleForAmount(amount float64) *widget.CustomTextGridStyle {
 }
 
 func GetStyleForBalance(balance float64) *widget.CustomTextGridStyle {
+	return GetStyleForBalanceMoney(money.FromFloat(balance, ""))
+}
+
+func GetStyleForBalanceMoney(balance money.Money) *widget.CustomTextGridStyle {
 	var bgColor color.NRGBA
 
-	if balance < 0 {
+	if balance.IsNegative() {
 		// Caso negativo: fondo rojo fijo
 		bgColor = negativeRedColor
 	} else {
 		// Caso positivo o cero: interpolar entre startGreenColor y endGreenColor
-		val := balance
-		if val > maxIncomeVal {
-			val = maxIncomeVal // Limitar por arriba
+		unit := int64(math.Pow10(money.Exponent(balance.Currency())))
+		val := balance.Minor()
+		if val > int64(maxIncomeVal)*unit {
+			val = int64(maxIncomeVal) * unit // Limitar por arriba
 		}
 
-		fraction := (val - minIncomeVal) / (maxIncomeVal - minIncomeVal)
+		fraction := float64(val-int64(minIncomeVal)*unit) / float64(int64(maxIncomeVal-minIncomeVal)*unit)
 		if fraction < 0 {
 			fraction = 0
 		} else if fraction > 1 {